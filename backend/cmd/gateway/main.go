@@ -1,21 +1,28 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"embed"
+	"fmt"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
 	"os"
 	"strings"
-	"time"
 
+	"Zero-TrustAPIGateWayServer/internal/admin"
 	"Zero-TrustAPIGateWayServer/internal/audit"
 	"Zero-TrustAPIGateWayServer/internal/auth"
+	"Zero-TrustAPIGateWayServer/internal/certs"
+	"Zero-TrustAPIGateWayServer/internal/config"
 	"Zero-TrustAPIGateWayServer/internal/dashboard"
+	"Zero-TrustAPIGateWayServer/internal/health"
 	"Zero-TrustAPIGateWayServer/internal/middleware"
 	"Zero-TrustAPIGateWayServer/internal/policy"
+	"Zero-TrustAPIGateWayServer/internal/proxy"
 	"Zero-TrustAPIGateWayServer/internal/ratelimit"
 	"Zero-TrustAPIGateWayServer/internal/rbac"
 )
@@ -36,12 +43,13 @@ SECURITY PRINCIPLES ENFORCED HERE:
 MIDDLEWARE ORDER (TOP to BOTTOM):
 
 1. Request validation    :   reject malformed traffic early
+   Public routes         :   policy file's public: section (skip 2 and 3)
 2. Authentication        :   establish identity
 3. RBAC authorization    :   role-based access
 4. Policy evaluation     :   route/method allow-list
 5. Rate limiting         :   abuse prevention
 6. Audit logging         :   tamper-evident decision record
-7. Reverse proxy         :   upstream forwarding
+7. Routing + proxy       :   per-route upstream forwarding (unmatched => deny)
 */
 
 func main() {
@@ -49,21 +57,29 @@ func main() {
 	log.SetFlags(log.LstdFlags | log.LUTC)
 
 	/*
-		Upstream configuration (static, allow listed)
+		Gateway configuration (file, env, flags; fail closed on any error)
 	*/
 
-	upstream, err := url.Parse("http://localhost:9000")
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
 	if err != nil {
-		log.Fatalf("invalid upstream URL: %v", err)
+		log.Fatalf("invalid gateway configuration: %v", err)
 	}
 
-	proxy := httputil.NewSingleHostReverseProxy(upstream)
+	/*
+		Upstream routing (static, allow listed; unmatched routes denied)
+	*/
+
+	router, err := buildRouter(cfg)
+	if err != nil {
+		log.Fatalf("invalid routing table: %v", err)
+	}
+	router.StartHealthChecks(context.Background())
 
 	/*
 		Audit logger (append only, fail open for logging)
 	*/
 
-	auditLogger, err := audit.NewLogger("./audit.log")
+	auditLogger, err := audit.NewLogger(cfg.Audit.Path)
 	if err != nil {
 		log.Fatalf("failed to initialize audit logger: %v", err)
 	}
@@ -74,31 +90,59 @@ func main() {
 	*/
 
 	policyEngine := policy.NewEngine()
-	if err := policyEngine.LoadFromFile("./policies/policies.yaml"); err != nil {
+	if err := policyEngine.LoadFromFile(cfg.Policy.Path); err != nil {
 		log.Printf("policy load failed, gateway running in deny-all mode: %v", err)
 	}
-	policyEngine.Watch("./policies/policies.yaml", 5*time.Second)
-
-	rbacMiddleware := rbac.RBACMiddleware(
-		rbac.PolicySet{Policies: convertPolicyRulesToRBACPolicies(policyEngine.GetPolicies())},
-	)
+	policyEngine.Watch(cfg.Policy.Path, cfg.Policy.ReloadInterval)
+
+	// Optional candidate set in shadow mode: evaluated on every request,
+	// never enforced. A broken candidate is simply not compared.
+	var shadowEngine *policy.Engine
+	if cfg.Policy.ShadowPath != "" {
+		shadowEngine = policy.NewEngine()
+		if err := shadowEngine.LoadFromFile(cfg.Policy.ShadowPath); err != nil {
+			log.Printf("shadow policy load failed, candidate not compared: %v", err)
+		}
+		shadowEngine.Watch(cfg.Policy.ShadowPath, cfg.Policy.ReloadInterval)
+	}
 
 	/*
 		Rate limiter (in-memory)
 	*/
 
-	limiter := ratelimit.NewLimiter()
+	limiter := ratelimit.NewLimiterWithConfig(ratelimit.Config{
+		IPCapacity:   cfg.RateLimit.IPCapacity,
+		IPRefillPS:   cfg.RateLimit.IPRefillPS,
+		UserCapacity: cfg.RateLimit.UserCapacity,
+		UserRefillPS: cfg.RateLimit.UserRefillPS,
+	})
+
+	/*
+		Request validation (guardrails)
+	*/
+
+	validateMiddleware := middleware.NewValidateRequestMiddleware(middleware.Config{
+		MaxBodyBytes:        cfg.Validation.MaxBodyBytes,
+		AllowedContentTypes: cfg.Validation.AllowedContentTypes,
+		RequiredHeaders:     cfg.Validation.RequiredHeaders,
+		LowercasePaths:      cfg.Validation.LowercasePaths,
+	})
 
 	/*
-		Authentication middleware (API key, demo store)
+		Authentication middleware (configured schemes, tried in order)
 
 		note:
 		 This assumes auth middleware already enforces default-deny
 		 Composition only, no logic changes
 	*/
 
-	demoStore := auth.NewDemoStore()
-	authMiddleware := auth.APIKeyMiddleware(demoStore)
+	keyStore := buildAPIKeyStore(cfg.Auth)
+
+	authenticators, err := buildAuthenticators(cfg.Auth, keyStore)
+	if err != nil {
+		log.Fatalf("failed to initialize authentication: %v", err)
+	}
+	authMiddleware := auth.Middleware(authenticators...)
 
 	/*
 		Stats collector for dashboard
@@ -106,6 +150,24 @@ func main() {
 
 	stats := dashboard.NewStatsCollector()
 
+	/*
+		RBAC (the engine is the live policy source: reloads and deny-all
+		invalidation apply to the very next request)
+	*/
+
+	var shadow *rbac.Shadow
+	if shadowEngine != nil {
+		shadow = &rbac.Shadow{
+			Candidate: shadowEngine,
+			OnDivergence: func(r *http.Request, d rbac.Divergence) {
+				stats.IncrementDivergence()
+				auditLogger.Log(r.Method, audit.RequestPath(r), "SHADOW", d.String())
+			},
+		}
+	}
+	rbacMiddleware := rbac.ShadowRBACMiddleware(policyEngine, shadow)
+	publicMiddleware := rbac.PublicMiddleware(policyEngine)
+
 	/*
 		Audit middleware composit
 	*/
@@ -115,6 +177,12 @@ func main() {
 
 			rr := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
 
+			// The path as sent; validation rewrites it to canonical form
+			path := audit.RequestPath(r)
+
+			// Layers that deny record their reason here (audit only)
+			r = r.WithContext(audit.WithReason(r.Context()))
+
 			next.ServeHTTP(rr, r)
 
 			decision := "ALLOW"
@@ -124,6 +192,9 @@ func main() {
 				decision = "DENY"
 				reason = http.StatusText(rr.status)
 			}
+			if recorded, ok := audit.Reason(r.Context()); ok {
+				reason = recorded
+			}
 
 			if decision == "ALLOW" {
 				stats.IncrementAllow()
@@ -133,24 +204,35 @@ func main() {
 
 			auditLogger.Log(
 				r.Method,
-				r.URL.Path,
+				path,
 				decision,
 				reason,
 			)
 		})
 	}
 
+	/*
+		Gateway's own health endpoint: ahead of authentication, so it is
+		anonymous and still answers 503 in deny-all mode
+	*/
+
+	healthHandler := &health.Handler{Policies: policyEngine}
+
 	/*
 		Final handler chain (exact required order)
 		Audit must wrap the entire chain so it sees ALL requests (incl. denies).
 	*/
 
 	securedChain :=
-		middleware.ValidateRequestMiddleware(
-			authMiddleware(
-				rbacMiddleware(
-					limiter.Middleware(
-						proxy,
+		validateMiddleware(
+			healthHandler.Middleware(
+				publicMiddleware(
+					authMiddleware(
+						rbacMiddleware(
+							limiter.Middleware(
+								router,
+							),
+						),
 					),
 				),
 			),
@@ -159,14 +241,16 @@ func main() {
 	finalHandler := auditMiddleware(securedChain)
 
 	/*
-		Dashboard (unauthenticated for demo)
+		Dashboard (authenticated, dashboard role, CSRF protected)
 	*/
 
 	dashboardHandlers := &dashboard.Handlers{
 		Stats:        stats,
-		AuditPath:    "./audit.log",
+		AuditPath:    cfg.Audit.Path,
 		PolicyEngine: policyEngine,
+		Shadow:       shadowEngine,
 		Limiter:      limiter,
+		Upstreams:    router,
 	}
 
 	subFS, err := fs.Sub(dashboardFS, "web/dashboard")
@@ -174,17 +258,28 @@ func main() {
 		log.Fatalf("dashboard embed: %v", err)
 	}
 
-	dashboardFiles := http.StripPrefix("/dashboard", http.FileServer(http.FS(subFS)))
+	// Sessions from an API key end as soon as the key is revoked,
+	// expires or disappears from the key file
+	sessions := auth.NewSessionStore(cfg.Dashboard.SessionTTL)
+	for _, a := range authenticators {
+		if keys, ok := a.(auth.APIKeyAuthenticator); ok {
+			sessions.Revalidate = keys.Recheck
+		}
+	}
+
+	dashboardHandler := dashboard.NewHandler(dashboardHandlers, subFS, dashboard.Access{
+		Role:           cfg.Dashboard.Role,
+		Authenticators: authenticators,
+		Sessions:       sessions,
+		Audit:          auditLogger.Log,
+	})
 
 	rootHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		p := r.URL.Path
 		switch {
-		case p == "/dashboard":
-			http.Redirect(w, r, "/dashboard/", http.StatusFound)
-		case strings.HasPrefix(p, "/dashboard/"):
-			dashboardFiles.ServeHTTP(w, r)
-		case strings.HasPrefix(p, "/api/dashboard"):
-			dashboardHandlers.ServeAPI(w, r)
+		case p == "/dashboard" || strings.HasPrefix(p, "/dashboard/"),
+			p == "/api/dashboard" || strings.HasPrefix(p, "/api/dashboard/"):
+			dashboardHandler.ServeHTTP(w, r)
 		default:
 			finalHandler.ServeHTTP(w, r)
 		}
@@ -194,33 +289,321 @@ func main() {
 		HTTP server
 	*/
 
+	tlsConfig, err := buildServerTLS(cfg.Server.TLS)
+	if err != nil {
+		log.Fatalf("failed to initialize TLS: %v", err)
+	}
+
 	server := &http.Server{
-		Addr:         ":8080",
+		Addr:         cfg.Server.Listen,
 		Handler:      rootHandler,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		TLSConfig:    tlsConfig,
 	}
 
-	log.Println("Zero-Trust API Gateway listening on :8080")
+	/*
+		Admin API (own listener; authenticated, admin role, audited)
+	*/
+
+	if cfg.Admin.Listen != "" {
+		adminHandlers := &admin.Handlers{
+			Role:         cfg.Admin.Role,
+			KeyFile:      adminKeyFile(cfg.Auth),
+			Keys:         keyStore,
+			PolicyPath:   cfg.Policy.Path,
+			PolicyEngine: policyEngine,
+
+			LowercasePaths: cfg.Validation.LowercasePaths,
+		}
+
+		// Same certificates and client auth as the public listener, so
+		// admin credentials are never sent in cleartext and mtls works.
+		// Plain HTTP only with admin.insecure (checked by config).
+		adminServer := &http.Server{
+			Addr:         cfg.Admin.Listen,
+			Handler:      auditMiddleware(authMiddleware(adminHandlers.Routes())),
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			IdleTimeout:  cfg.Server.IdleTimeout,
+			TLSConfig:    tlsConfig,
+		}
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		go func() {
+			var err error
+			if tlsConfig != nil {
+				log.Printf("Admin API listening on %s (TLS)", cfg.Admin.Listen)
+				err = adminServer.ListenAndServeTLS("", "")
+			} else {
+				log.Printf("Admin API listening on %s (insecure, plain HTTP)", cfg.Admin.Listen)
+				err = adminServer.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				log.Fatalf("admin server error: %v", err)
+			}
+		}()
+	}
+
+	/*
+		HTTP -> HTTPS redirect (own listener; never serves content)
+	*/
+
+	if tlsConfig != nil && cfg.Server.TLS.RedirectListen != "" {
+		_, httpsPort, _ := net.SplitHostPort(cfg.Server.Listen)
+
+		redirectServer := &http.Server{
+			Addr:         cfg.Server.TLS.RedirectListen,
+			Handler:      certs.RedirectHandler(httpsPort),
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			IdleTimeout:  cfg.Server.IdleTimeout,
+		}
+
+		go func() {
+			log.Printf("HTTP redirect listening on %s", cfg.Server.TLS.RedirectListen)
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("redirect server error: %v", err)
+			}
+		}()
+	}
+
+	if tlsConfig != nil {
+		log.Printf("Zero-Trust API Gateway listening on %s (TLS)", cfg.Server.Listen)
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		log.Printf("Zero-Trust API Gateway listening on %s", cfg.Server.Listen)
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatalf("server error: %v", err)
 	}
 }
 
 /*
-Response recorder (standard pattern)
+TLS termination (config -> certs)
+*/
+
+// buildServerTLS returns nil when TLS is not configured. Certificates
+// and the client CA bundle are watched: a broken certificate update keeps
+// the previous one, a broken bundle rejects every client certificate.
+func buildServerTLS(cfg config.TLSConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" {
+		return nil, nil
+	}
+
+	pairs := []certs.KeyPair{{CertFile: cfg.CertFile, KeyFile: cfg.KeyFile}}
+	for _, c := range cfg.Certificates {
+		pairs = append(pairs, certs.KeyPair{CertFile: c.CertFile, KeyFile: c.KeyFile})
+	}
+	store, err := certs.NewCertStore(pairs)
+	if err != nil {
+		return nil, err
+	}
+	store.Watch(cfg.ReloadInterval, func(err error) {
+		log.Printf("server certificate reload failed, keeping current certificates: %v", err)
+	})
+
+	// Both already checked by config validation
+	minVersion, _ := certs.ParseVersion(cfg.MinVersion)
+	cipherSuites, _ := certs.ParseCipherSuites(cfg.CipherSuites)
+
+	opts := certs.ServerOptions{
+		Certificates: store,
+		MinVersion:   minVersion,
+		CipherSuites: cipherSuites,
+		ClientAuth:   certs.ClientAuth(cfg.ClientAuth),
+	}
+
+	if cfg.ClientCAFile != "" && opts.ClientAuth != certs.ClientAuthNone {
+		pool, err := certs.NewCAPool(cfg.ClientCAFile)
+		if err != nil {
+			log.Printf("client CA bundle load failed, rejecting all client certificates: %v", err)
+		}
+		pool.Watch(cfg.ClientCAFile, cfg.ReloadInterval)
+		opts.ClientCAs = pool
+	}
+
+	return certs.ServerTLSConfig(opts)
+}
+
+/*
+Authentication schemes (config -> auth)
+*/
+
+func buildAuthenticators(cfg config.AuthConfig, keyStore *auth.FileStore) ([]auth.Authenticator, error) {
+	var authenticators []auth.Authenticator
+
+	for _, scheme := range cfg.Schemes {
+		switch scheme {
+		case "jwt":
+			var issuers []auth.IssuerConfig
+			for _, ic := range cfg.JWT.Issuers {
+				keys, err := buildJWTKeySource(ic)
+				if err != nil {
+					return nil, fmt.Errorf("issuer %q: %w", ic.Issuer, err)
+				}
+				issuers = append(issuers, auth.IssuerConfig{
+					Issuer:     ic.Issuer,
+					Audiences:  ic.Audiences,
+					Keys:       keys,
+					Algorithms: ic.Algorithms,
+					Leeway:     ic.Leeway,
+					RolesClaim: ic.RolesClaim,
+				})
+			}
+			authenticators = append(authenticators, auth.JWTAuthenticator{Config: auth.JWTConfig{Issuers: issuers}})
+		case "api_key":
+			// Config validation allows the demo keys only when asked for
+			// explicitly, and never with TLS
+			var store auth.APIKeyStore = keyStore
+			if cfg.APIKeys.Demo {
+				log.Printf("auth.api_keys.demo is set: accepting the well-known demo API keys (local development only)")
+				store = auth.NewDemoStore()
+			}
+			authenticators = append(authenticators, auth.APIKeyAuthenticator{
+				Store: store,
+				Usage: buildUsageTracker(cfg.APIKeys),
+			})
+		case "mtls":
+			roles := make([]auth.CertRoleMapping, len(cfg.MTLS.Roles))
+			for i, m := range cfg.MTLS.Roles {
+				roles[i] = auth.CertRoleMapping(m)
+			}
+			authenticators = append(authenticators, auth.MTLSAuthenticator{Roles: roles})
+		}
+	}
+
+	return authenticators, nil
+}
+
+func buildJWTKeySource(cfg config.JWTIssuerConfig) (auth.KeySource, error) {
+	if cfg.PublicKeyFile != "" {
+		key, err := auth.LoadPublicKey(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return auth.StaticKey{PublicKey: key}, nil
+	}
+
+	return auth.NewJWKS(auth.JWKSConfig{
+		URL:                cfg.JWKSURL,
+		File:               cfg.JWKSFile,
+		TTL:                cfg.JWKSTTL,
+		MinRefreshInterval: cfg.JWKSMinRefreshInterval,
+	})
+}
+
+// buildAPIKeyStore loads the hashed key file and watches it.
+// A load failure leaves the store empty: no API key is accepted.
+// It returns nil when no key file is used: the api_key scheme is off,
+// or the demo keys are.
+func buildAPIKeyStore(cfg config.AuthConfig) *auth.FileStore {
+	file := adminKeyFile(cfg)
+	if file == "" {
+		return nil
+	}
+
+	store, err := auth.NewFileStore(file)
+	if err != nil {
+		log.Printf("API key file load failed, rejecting all API keys: %v", err)
+	}
+	store.Watch(file, cfg.APIKeys.ReloadInterval)
+	return store
+}
+
+// adminKeyFile is the key file in use, which the admin API edits; none
+// when the api_key scheme is off or uses the demo keys.
+func adminKeyFile(cfg config.AuthConfig) string {
+	if !cfg.HasScheme("api_key") || cfg.APIKeys.Demo {
+		return ""
+	}
+	return cfg.APIKeys.File
+}
+
+// buildUsageTracker records API key last-used times, persisted to
+// usage_file when configured. Tracking never affects authentication.
+func buildUsageTracker(cfg config.APIKeyConfig) *auth.UsageTracker {
+	usage, err := auth.NewUsageTracker(cfg.UsageFile)
+	if err != nil {
+		log.Printf("API key usage file unreadable, starting empty: %v", err)
+		usage, _ = auth.NewUsageTracker("")
+	}
+	usage.Start(cfg.UsageFlushInterval, func(err error) {
+		log.Printf("API key usage flush failed: %v", err)
+	})
+	return usage
+}
+
+/*
+Routing table (config -> proxy)
 */
 
-func convertPolicyRulesToRBACPolicies(rules []policy.Rule) []rbac.Policy {
-	policies := make([]rbac.Policy, len(rules))
-	for i, rule := range rules {
-		policies[i] = rbac.Policy(rule)
+func buildRouter(cfg config.Config) (*proxy.Router, error) {
+	upstreams := make([]proxy.Upstream, len(cfg.Upstreams))
+	for i, u := range cfg.Upstreams {
+		targets := make([]*url.URL, len(u.Targets))
+		for j, raw := range u.Targets {
+			parsed, err := url.Parse(raw)
+			if err != nil {
+				return nil, err
+			}
+			targets[j] = parsed
+		}
+
+		var tlsConfig *tls.Config
+		if u.TLS.IsSet() {
+			var err error
+			tlsConfig, err = certs.UpstreamTLSConfig(certs.UpstreamOptions{
+				CAFile:     u.TLS.CAFile,
+				CertFile:   u.TLS.CertFile,
+				KeyFile:    u.TLS.KeyFile,
+				ServerName: u.TLS.ServerName,
+				SPIFFEIDs:  u.TLS.SPIFFEIDs,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("upstream %q: %w", u.Name, err)
+			}
+		}
+
+		upstreams[i] = proxy.Upstream{
+			Name:     u.Name,
+			Targets:  targets,
+			Strategy: proxy.Strategy(u.Strategy),
+			HealthCheck: proxy.HealthCheck{
+				Path:     u.HealthCheck.Path,
+				Interval: u.HealthCheck.Interval,
+				Timeout:  u.HealthCheck.Timeout,
+			},
+			Passive: proxy.PassiveCheck{
+				MaxFailures:  u.Passive.MaxFailures,
+				EjectionTime: u.Passive.EjectionTime,
+			},
+			TLS: tlsConfig,
+		}
 	}
-	return policies
+
+	routes := make([]proxy.Route, len(cfg.Routes))
+	for i, r := range cfg.Routes {
+		routes[i] = proxy.Route{
+			Name:          r.Name,
+			PathPrefix:    r.PathPrefix,
+			Hosts:         r.Hosts,
+			Methods:       r.Methods,
+			Upstream:      r.Upstream,
+			StripPrefix:   r.StripPrefix,
+			ReplacePrefix: r.ReplacePrefix,
+			Timeout:       r.Timeout,
+		}
+	}
+
+	return proxy.NewRouter(upstreams, routes)
 }
 
+/*
+Response recorder (standard pattern)
+*/
+
 type responseRecorder struct {
 	http.ResponseWriter
 	status int
@@ -1,5 +1,23 @@
 const API_BASE = '/api/dashboard';
 
+// Session expired or missing: back to the login form
+async function apiFetch(path) {
+  const res = await fetch(API_BASE + path, { credentials: 'same-origin' });
+  if (res.status === 401) {
+    window.location.href = '/dashboard/login';
+    throw new Error('Not logged in');
+  }
+  return res;
+}
+
+async function loadSession() {
+  const res = await apiFetch('/session');
+  if (!res.ok) throw new Error('Session fetch failed');
+  const data = await res.json();
+  document.getElementById('session-user').textContent = data.type + ':' + data.subject;
+  document.getElementById('csrf-token').value = data.csrf_token || '';
+}
+
 function formatUptime(seconds) {
   const h = Math.floor(seconds / 3600);
   const m = Math.floor((seconds % 3600) / 60);
@@ -12,26 +30,36 @@ function formatUptime(seconds) {
 }
 
 async function fetchStats() {
-  const res = await fetch(API_BASE + '/stats');
+  const res = await apiFetch('/stats');
   if (!res.ok) throw new Error('Stats fetch failed');
   return res.json();
 }
 
 async function fetchAudit() {
-  const res = await fetch(API_BASE + '/audit?limit=50');
+  const res = await apiFetch('/audit?limit=50');
   if (!res.ok) throw new Error('Audit fetch failed');
   return res.json();
 }
 
 async function fetchPolicies() {
-  const res = await fetch(API_BASE + '/policies');
+  const res = await apiFetch('/policies');
   if (!res.ok) throw new Error('Policies fetch failed');
   return res.json();
 }
 
+async function fetchUpstreams() {
+  const res = await apiFetch('/upstreams');
+  if (!res.ok) throw new Error('Upstreams fetch failed');
+  return res.json();
+}
+
 function renderStats(data) {
   document.getElementById('allow-count').textContent = data.allow;
   document.getElementById('deny-count').textContent = data.deny;
+  const shadow = data.shadow || { status: 'off', divergences: 0 };
+  document.getElementById('shadow-divergences').textContent =
+    shadow.status === 'off' ? '-' : shadow.divergences;
+  document.getElementById('shadow-status').textContent = 'candidate ' + shadow.status;
   document.getElementById('uptime').textContent = formatUptime(data.uptime_seconds);
 }
 
@@ -67,6 +95,37 @@ function renderPolicies(data) {
   `).join('');
 }
 
+function instanceState(i) {
+  if (!i.healthy) return 'down';
+  if (i.ejected) return 'ejected';
+  return 'up';
+}
+
+function renderUpstreams(data) {
+  const tbody = document.getElementById('upstreams-body');
+  const rows = [];
+  (data.upstreams || []).forEach(u => {
+    (u.instances || []).forEach(i => {
+      const state = instanceState(i);
+      rows.push(`
+    <tr>
+      <td>${escapeHtml(u.name)}</td>
+      <td>${escapeHtml(u.strategy)}</td>
+      <td>${escapeHtml(i.url)}</td>
+      <td class="state-${state}">${state}</td>
+      <td>${i.active_connections}</td>
+      <td>${escapeHtml(i.last_error || '')}</td>
+    </tr>
+  `);
+    });
+  });
+  if (rows.length === 0) {
+    tbody.innerHTML = '<tr><td colspan="6" class="empty">No upstreams configured</td></tr>';
+    return;
+  }
+  tbody.innerHTML = rows.join('');
+}
+
 function escapeHtml(s) {
   const div = document.createElement('div');
   div.textContent = s;
@@ -75,18 +134,21 @@ function escapeHtml(s) {
 
 async function refresh() {
   try {
-    const [stats, audit, policies] = await Promise.all([
+    const [stats, audit, policies, upstreams] = await Promise.all([
       fetchStats(),
       fetchAudit(),
-      fetchPolicies()
+      fetchPolicies(),
+      fetchUpstreams()
     ]);
     renderStats(stats);
     renderAudit(audit);
     renderPolicies(policies);
+    renderUpstreams(upstreams);
   } catch (err) {
     console.error('Dashboard refresh failed:', err);
   }
 }
 
+loadSession().catch(err => console.error('Session load failed:', err));
 refresh();
 setInterval(refresh, 3000);
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+	"Zero-TrustAPIGateWayServer/internal/config"
+)
+
+/*
+API KEY MANAGEMENT
+
+ The raw key is printed exactly once (create, rotate) and never stored
+ Only the prefix and SHA-256 hash are written to the key file
+ Every write is validated and atomic, so the watching gateway never
+  loads a half-written or invalid file
+ Revocation keeps the record, so the audit trail still resolves its ID
+*/
+
+var keyCommands = map[string]command{
+	"create": keysCreate,
+	"list":   keysList,
+	"show":   keysShow,
+	"rotate": keysRotate,
+	"revoke": keysRevoke,
+}
+
+// keyFlags are shared by every keys subcommand.
+type keyFlags struct {
+	configPath string
+	file       string
+	usageFile  string
+}
+
+func newKeyFlagSet(env *cliEnv, name string) (*flag.FlagSet, *keyFlags) {
+	fset := flag.NewFlagSet("gatewayctl keys "+name, flag.ContinueOnError)
+	fset.SetOutput(env.stderr)
+
+	kf := &keyFlags{}
+	fset.StringVar(&kf.configPath, "config", "", "gateway config file (default "+config.DefaultPath+")")
+	fset.StringVar(&kf.file, "file", "", "API key file (default: auth.api_keys.file from the config)")
+	fset.StringVar(&kf.usageFile, "usage-file", "", "last-used file (default: auth.api_keys.usage_file from the config)")
+	return fset, kf
+}
+
+// resolve fills in file locations from the gateway config.
+func (kf *keyFlags) resolve(env *cliEnv) error {
+	if kf.file != "" && kf.usageFile != "" {
+		return nil
+	}
+
+	var args []string
+	if kf.configPath != "" {
+		args = []string{"-config", kf.configPath}
+	}
+	cfg, err := config.Load(args, env.getenv)
+	if err != nil {
+		return err
+	}
+
+	if kf.file == "" && !cfg.Auth.APIKeys.Demo {
+		kf.file = cfg.Auth.APIKeys.File
+	}
+	if kf.usageFile == "" {
+		kf.usageFile = cfg.Auth.APIKeys.UsageFile
+	}
+	if kf.file == "" {
+		return errors.New("no key file: set auth.api_keys.file (without auth.api_keys.demo) in the gateway config or pass -file")
+	}
+	return nil
+}
+
+func keysCreate(env *cliEnv, args []string) error {
+	fset, kf := newKeyFlagSet(env, "create")
+	id := fset.String("id", "", "key ID (required, unique)")
+	roles := fset.String("roles", "", "comma-separated roles (required)")
+	notBefore := fset.String("not-before", "", "valid from: RFC 3339 time or duration from now")
+	expires := fset.String("expires", "", "expires at: RFC 3339 time or duration from now, e.g. 2160h")
+	owner := fset.String("owner", "", "owning team or person")
+	description := fset.String("description", "", "what the key is for")
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+	if err := kf.resolve(env); err != nil {
+		return err
+	}
+
+	if *id == "" {
+		return errors.New("-id is required")
+	}
+	roleList := splitList(*roles)
+	if len(roleList) == 0 {
+		return errors.New("-roles is required")
+	}
+
+	file, err := auth.LoadKeyFile(kf.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		// First key: the file is created
+		file, err = auth.KeyFile{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	if file.Find(*id) >= 0 {
+		return fmt.Errorf("key %q already exists", *id)
+	}
+
+	raw, record, err := auth.GenerateAPIKey(*id, roleList)
+	if err != nil {
+		return err
+	}
+	if record.NotBefore, err = parseWhen(*notBefore, env.now()); err != nil {
+		return fmt.Errorf("-not-before: %w", err)
+	}
+	if record.ExpiresAt, err = parseWhen(*expires, env.now()); err != nil {
+		return fmt.Errorf("-expires: %w", err)
+	}
+	record.Owner = *owner
+	record.Description = *description
+
+	file.Keys = append(file.Keys, record)
+	if err := auth.SaveKeyFile(kf.file, file); err != nil {
+		return err
+	}
+
+	printRawKey(env, record, raw)
+	return nil
+}
+
+func keysList(env *cliEnv, args []string) error {
+	fset, kf := newKeyFlagSet(env, "list")
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+	if err := kf.resolve(env); err != nil {
+		return err
+	}
+
+	file, err := auth.LoadKeyFile(kf.file)
+	if err != nil {
+		return err
+	}
+	usage := loadUsage(env, kf.usageFile)
+	now := env.now()
+
+	tw := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPREFIX\tROLES\tSTATUS\tEXPIRES\tLAST USED\tOWNER")
+	for _, k := range file.Keys {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			k.ID, k.Prefix, strings.Join(k.Roles, ","), k.Status(now),
+			formatTime(k.ExpiresAt), formatLastUsed(usage, k.ID), k.Owner)
+	}
+	return tw.Flush()
+}
+
+func keysShow(env *cliEnv, args []string) error {
+	fset, kf := newKeyFlagSet(env, "show")
+	id := fset.String("id", "", "key ID (required)")
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+	if err := kf.resolve(env); err != nil {
+		return err
+	}
+
+	file, i, err := loadKey(kf.file, *id)
+	if err != nil {
+		return err
+	}
+	k := file.Keys[i]
+	usage := loadUsage(env, kf.usageFile)
+
+	tw := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "id:\t%s\n", k.ID)
+	fmt.Fprintf(tw, "prefix:\t%s\n", k.Prefix)
+	fmt.Fprintf(tw, "roles:\t%s\n", strings.Join(k.Roles, ", "))
+	fmt.Fprintf(tw, "status:\t%s\n", k.Status(env.now()))
+	fmt.Fprintf(tw, "not before:\t%s\n", formatTime(k.NotBefore))
+	fmt.Fprintf(tw, "expires:\t%s\n", formatTime(k.ExpiresAt))
+	fmt.Fprintf(tw, "last used:\t%s\n", formatLastUsed(usage, k.ID))
+	fmt.Fprintf(tw, "owner:\t%s\n", k.Owner)
+	fmt.Fprintf(tw, "description:\t%s\n", k.Description)
+	return tw.Flush()
+}
+
+func keysRotate(env *cliEnv, args []string) error {
+	fset, kf := newKeyFlagSet(env, "rotate")
+	id := fset.String("id", "", "key ID (required)")
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+	if err := kf.resolve(env); err != nil {
+		return err
+	}
+
+	file, i, err := loadKey(kf.file, *id)
+	if err != nil {
+		return err
+	}
+	if file.Keys[i].Revoked {
+		// Rotating would silently bring a revoked identity back
+		return fmt.Errorf("key %q is revoked; create a new key instead", *id)
+	}
+
+	raw, fresh, err := auth.GenerateAPIKey(*id, file.Keys[i].Roles)
+	if err != nil {
+		return err
+	}
+
+	// Same identity and lifecycle, new secret
+	file.Keys[i].Prefix = fresh.Prefix
+	file.Keys[i].Hash = fresh.Hash
+	if err := auth.SaveKeyFile(kf.file, file); err != nil {
+		return err
+	}
+
+	printRawKey(env, file.Keys[i], raw)
+	fmt.Fprintln(env.stdout, "The previous key stops working when the gateway reloads the key file.")
+	return nil
+}
+
+func keysRevoke(env *cliEnv, args []string) error {
+	fset, kf := newKeyFlagSet(env, "revoke")
+	id := fset.String("id", "", "key ID (required)")
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+	if err := kf.resolve(env); err != nil {
+		return err
+	}
+
+	file, i, err := loadKey(kf.file, *id)
+	if err != nil {
+		return err
+	}
+	if file.Keys[i].Revoked {
+		fmt.Fprintf(env.stdout, "key %q is already revoked\n", *id)
+		return nil
+	}
+
+	file.Keys[i].Revoked = true
+	if err := auth.SaveKeyFile(kf.file, file); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.stdout, "key %q revoked\n", *id)
+	return nil
+}
+
+/*
+helpers
+*/
+
+func parseFlags(fset *flag.FlagSet, args []string) error {
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fset.Args())
+	}
+	return nil
+}
+
+func loadKey(path, id string) (auth.KeyFile, int, error) {
+	if id == "" {
+		return auth.KeyFile{}, -1, errors.New("-id is required")
+	}
+
+	file, err := auth.LoadKeyFile(path)
+	if err != nil {
+		return auth.KeyFile{}, -1, err
+	}
+
+	i := file.Find(id)
+	if i < 0 {
+		return auth.KeyFile{}, -1, fmt.Errorf("key %q not found", id)
+	}
+	return file, i, nil
+}
+
+// loadUsage is best effort: last-used times are informational.
+func loadUsage(env *cliEnv, path string) map[string]time.Time {
+	if path == "" {
+		return nil
+	}
+
+	usage, err := auth.LoadUsage(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(env.stderr, "warning: %v\n", err)
+			return nil
+		}
+		// Not flushed yet: nothing has been used
+		return map[string]time.Time{}
+	}
+	return usage
+}
+
+func printRawKey(env *cliEnv, record auth.KeyRecord, raw string) {
+	fmt.Fprintf(env.stdout, "id:     %s\n", record.ID)
+	fmt.Fprintf(env.stdout, "prefix: %s\n", record.Prefix)
+	fmt.Fprintf(env.stdout, "key:    %s\n", raw)
+	fmt.Fprintln(env.stdout, "Store this key now: it is not saved and cannot be shown again.")
+}
+
+// parseWhen accepts an RFC 3339 time or a duration relative to now.
+func parseWhen(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a positive duration", value)
+	}
+	return now.Add(d).UTC().Truncate(time.Second), nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatLastUsed shows "-" when usage is not tracked on disk.
+func formatLastUsed(usage map[string]time.Time, id string) string {
+	if usage == nil {
+		return "-"
+	}
+	t, ok := usage[id]
+	if !ok {
+		return "never"
+	}
+	return formatTime(t)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
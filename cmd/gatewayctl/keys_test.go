@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+var rawKeyLine = regexp.MustCompile(`(?m)^key:\s+(\S+)$`)
+
+type testCLI struct {
+	t       *testing.T
+	keyFile string
+	now     time.Time
+}
+
+func newTestCLI(t *testing.T) *testCLI {
+	return &testCLI{
+		t:       t,
+		keyFile: filepath.Join(t.TempDir(), "api_keys.yaml"),
+		now:     time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// run executes "gatewayctl keys <args> -file <keyFile>" and returns stdout.
+func (c *testCLI) run(wantCode int, args ...string) string {
+	c.t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	env := &cliEnv{
+		stdout: &stdout,
+		stderr: &stderr,
+		getenv: func(string) string { return "" },
+		now:    func() time.Time { return c.now },
+	}
+
+	args = append(append([]string{"keys"}, args...), "-file", c.keyFile, "-usage-file", c.keyFile+".usage")
+	if code := run(env, args); code != wantCode {
+		c.t.Fatalf("gatewayctl %v: expected exit %d, got %d (stderr: %s)", args, wantCode, code, stderr.String())
+	}
+	return stdout.String()
+}
+
+func (c *testCLI) authenticate(raw string) error {
+	store, err := auth.NewFileStore(c.keyFile)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	a := auth.APIKeyAuthenticator{Store: store, Now: func() time.Time { return c.now }}
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("X-API-Key", raw)
+	_, err = a.Authenticate(req)
+	return err
+}
+
+func rawKey(t *testing.T, out string) string {
+	t.Helper()
+
+	m := rawKeyLine.FindStringSubmatch(out)
+	if m == nil {
+		t.Fatalf("no raw key in output:\n%s", out)
+	}
+	return m[1]
+}
+
+func TestKeysCreateStoresOnlyHash(t *testing.T) {
+	c := newTestCLI(t)
+
+	out := c.run(0, "create", "-id", "ci-bot", "-roles", "deployer, user", "-expires", "720h", "-owner", "platform")
+	raw := rawKey(t, out)
+
+	data, err := os.ReadFile(c.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), raw) || strings.Contains(string(data), strings.SplitN(raw, ".", 2)[1]) {
+		t.Fatal("raw key written to the key file")
+	}
+
+	if err := c.authenticate(raw); err != nil {
+		t.Fatalf("created key rejected: %v", err)
+	}
+
+	file, err := auth.LoadKeyFile(c.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := file.Keys[0]
+	if len(k.Roles) != 2 || k.Owner != "platform" || !k.ExpiresAt.Equal(c.now.Add(720*time.Hour)) {
+		t.Fatalf("unexpected record: %+v", k)
+	}
+
+	// IDs are unique
+	c.run(1, "create", "-id", "ci-bot", "-roles", "user")
+}
+
+func TestKeysRotateReplacesSecret(t *testing.T) {
+	c := newTestCLI(t)
+
+	oldRaw := rawKey(t, c.run(0, "create", "-id", "ci-bot", "-roles", "deployer"))
+	newRaw := rawKey(t, c.run(0, "rotate", "-id", "ci-bot"))
+
+	if oldRaw == newRaw {
+		t.Fatal("rotate must issue a new key")
+	}
+	if err := c.authenticate(oldRaw); err == nil {
+		t.Fatal("old key must stop working after rotation")
+	}
+	if err := c.authenticate(newRaw); err != nil {
+		t.Fatalf("rotated key rejected: %v", err)
+	}
+}
+
+func TestKeysRevoke(t *testing.T) {
+	c := newTestCLI(t)
+
+	raw := rawKey(t, c.run(0, "create", "-id", "ci-bot", "-roles", "deployer"))
+	c.run(0, "revoke", "-id", "ci-bot")
+
+	if err := c.authenticate(raw); err != auth.ErrKeyRevoked {
+		t.Fatalf("expected ErrKeyRevoked, got %v", err)
+	}
+
+	// A revoked identity is never brought back by rotation
+	c.run(1, "rotate", "-id", "ci-bot")
+	c.run(1, "revoke", "-id", "missing")
+}
+
+func TestKeysListAndShowNeverPrintSecrets(t *testing.T) {
+	c := newTestCLI(t)
+
+	raw := rawKey(t, c.run(0, "create", "-id", "ci-bot", "-roles", "deployer", "-expires", "2026-01-01T00:00:00Z"))
+	c.run(0, "create", "-id", "reporter", "-roles", "user", "-description", "weekly report")
+
+	list := c.run(0, "list")
+	show := c.run(0, "show", "-id", "ci-bot")
+
+	for _, out := range []string{list, show} {
+		if strings.Contains(out, raw) || strings.Contains(out, "sha256:") {
+			t.Fatalf("secret material printed:\n%s", out)
+		}
+	}
+	if !strings.Contains(list, "expired") || !strings.Contains(list, "active") || !strings.Contains(list, "never") {
+		t.Fatalf("unexpected list output:\n%s", list)
+	}
+	if !strings.Contains(show, "deployer") {
+		t.Fatalf("unexpected show output:\n%s", show)
+	}
+}
+
+func TestKeysRequiresKeyFile(t *testing.T) {
+	var stderr bytes.Buffer
+	env := &cliEnv{
+		stdout: &bytes.Buffer{},
+		stderr: &stderr,
+		getenv: func(string) string { return "" },
+		now:    time.Now,
+	}
+
+	// A gateway on the demo keys uses no key file
+	empty := filepath.Join(t.TempDir(), "gateway.yaml")
+	if err := os.WriteFile(empty, []byte("auth:\n  api_keys:\n    demo: true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := run(env, []string{"keys", "list", "-config", empty}); code != 1 {
+		t.Fatalf("expected exit 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "no key file") {
+		t.Fatalf("unexpected error: %s", stderr.String())
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/config"
+	"Zero-TrustAPIGateWayServer/internal/policy"
+	"Zero-TrustAPIGateWayServer/internal/rbac"
+)
+
+/*
+POLICY TOOLING
+
+ Read-only: nothing here changes the policy file or sends traffic
+ Decisions come from the same evaluation the gateway runs, so an
+  explanation cannot disagree with enforcement
+ -file evaluates a candidate file; the default is the gateway's
+  configured policy.path
+ "policy test" exits non-zero on any failing case, so it can gate
+  policy changes in CI
+*/
+
+var policyCommands = map[string]command{
+	"explain": policyExplain,
+	"test":    policyTest,
+}
+
+// policyFlags are shared by every policy subcommand.
+type policyFlags struct {
+	configPath string
+	file       string
+}
+
+func newPolicyFlagSet(env *cliEnv, name string) (*flag.FlagSet, *policyFlags) {
+	fset := flag.NewFlagSet("gatewayctl policy "+name, flag.ContinueOnError)
+	fset.SetOutput(env.stderr)
+
+	pf := &policyFlags{}
+	fset.StringVar(&pf.configPath, "config", "", "gateway config file (default "+config.DefaultPath+")")
+	fset.StringVar(&pf.file, "file", "", "policy file (default: policy.path from the config)")
+	return fset, pf
+}
+
+// load validates and compiles the policy file through policy.Engine,
+// exactly as the gateway loads it. The config is always read, so
+// evaluation uses the gateway's own settings (validation.lowercase_paths)
+// even for a -file candidate.
+func (pf *policyFlags) load(env *cliEnv) (rbac.PolicySet, policy.Options, error) {
+	var args []string
+	if pf.configPath != "" {
+		args = []string{"-config", pf.configPath}
+	}
+	cfg, err := config.Load(args, env.getenv)
+	if err != nil {
+		return rbac.PolicySet{}, policy.Options{}, err
+	}
+	if pf.file == "" {
+		pf.file = cfg.Policy.Path
+	}
+	opts := policy.Options{Now: env.now(), LowercasePaths: cfg.Validation.LowercasePaths}
+
+	engine := policy.NewEngine()
+	if err := engine.LoadFromFile(pf.file); err != nil {
+		return rbac.PolicySet{}, opts, fmt.Errorf("%s: %w", pf.file, err)
+	}
+	return engine.Current(), opts, nil
+}
+
+func policyExplain(env *cliEnv, args []string) error {
+	fset, pf := newPolicyFlagSet(env, "explain")
+	method := fset.String("method", "GET", "request method")
+	path := fset.String("path", "", "request path (required)")
+	anonymous := fset.Bool("anonymous", false, "evaluate without an identity")
+	roles := fset.String("roles", "", "comma-separated caller roles")
+	authType := fset.String("auth-type", "", "caller auth scheme: jwt, api_key or mtls")
+	subject := fset.String("subject", "", "caller subject")
+	sourceIP := fset.String("ip", "", "client IP")
+	at := fset.String("time", "", "request time, RFC 3339 (default now)")
+	var claims, headers pairList
+	fset.Var(&claims, "claim", "JWT claim name=value (repeatable; repeats form an array)")
+	fset.Var(&headers, "header", "request header Name=value (repeatable)")
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("-path is required")
+	}
+
+	set, opts, err := pf.load(env)
+	if err != nil {
+		return err
+	}
+
+	h := policy.Hypothetical{
+		Method:   *method,
+		Path:     *path,
+		SourceIP: *sourceIP,
+		Headers:  headers.strings(),
+	}
+	if *at != "" {
+		if h.Time, err = time.Parse(time.RFC3339, *at); err != nil {
+			return fmt.Errorf("-time: %q is not an RFC 3339 time", *at)
+		}
+	}
+	if !*anonymous {
+		h.Identity = &policy.HypotheticalIdentity{
+			AuthType: *authType,
+			Subject:  *subject,
+			Roles:    splitList(*roles),
+			Claims:   claims.claims(),
+		}
+	}
+
+	d, err := policy.Explain(set, h, opts)
+	if err != nil {
+		return err
+	}
+	printDecision(env, d)
+	return nil
+}
+
+func policyTest(env *cliEnv, args []string) error {
+	fset, pf := newPolicyFlagSet(env, "test")
+	testsFile := fset.String("tests", "", "test suite (default: <policy file>_test.yaml)")
+	verbose := fset.Bool("v", false, "also list passing tests")
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+
+	set, opts, err := pf.load(env)
+	if err != nil {
+		return err
+	}
+
+	if *testsFile == "" {
+		ext := filepath.Ext(pf.file)
+		*testsFile = strings.TrimSuffix(pf.file, ext) + "_test" + ext
+	}
+	data, err := os.ReadFile(*testsFile)
+	if err != nil {
+		return err
+	}
+	suite, err := policy.ParseTestSuite(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *testsFile, err)
+	}
+
+	failed := 0
+	for _, r := range policy.RunTests(set, suite, opts) {
+		if !r.Passed {
+			failed++
+			fmt.Fprintf(env.stdout, "FAIL  %s: %s\n", r.Case.Name, r.Failure)
+		} else if *verbose {
+			fmt.Fprintf(env.stdout, "ok    %s\n", r.Case.Name)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tests failed", failed, len(suite.Tests))
+	}
+	fmt.Fprintf(env.stdout, "PASS  %d tests (%s)\n", len(suite.Tests), *testsFile)
+	return nil
+}
+
+func printDecision(env *cliEnv, d rbac.Decision) {
+	verdict := "DENY"
+	if d.Allowed {
+		verdict = "ALLOW"
+	}
+	rule := "none"
+	if d.Rule >= 0 {
+		rule = fmt.Sprintf("policy[%d]", d.Rule)
+	}
+
+	fmt.Fprintf(env.stdout, "decision: %s\n", verdict)
+	fmt.Fprintf(env.stdout, "rule:     %s\n", rule)
+	fmt.Fprintf(env.stdout, "reason:   %s\n", d.Reason)
+}
+
+// pairList collects repeated name=value flags, in order.
+type pairList [][2]string
+
+func (p *pairList) String() string { return "" }
+
+func (p *pairList) Set(value string) error {
+	name, v, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("%q is not name=value", value)
+	}
+	*p = append(*p, [2]string{name, v})
+	return nil
+}
+
+func (p pairList) strings() map[string]string {
+	if len(p) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(p))
+	for _, kv := range p {
+		out[kv[0]] = kv[1]
+	}
+	return out
+}
+
+// claims builds a claim set; a repeated name becomes an array claim.
+// Dotted names become nested objects, as in a real token.
+func (p pairList) claims() map[string]interface{} {
+	if len(p) == 0 {
+		return nil
+	}
+	out := map[string]interface{}{}
+	for _, kv := range p {
+		obj := out
+		parts := strings.Split(kv[0], ".")
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := obj[part].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				obj[part] = next
+			}
+			obj = next
+		}
+
+		name := parts[len(parts)-1]
+		switch existing := obj[name].(type) {
+		case nil:
+			obj[name] = kv[1]
+		case string:
+			obj[name] = []interface{}{existing, kv[1]}
+		case []interface{}:
+			obj[name] = append(existing, kv[1])
+		}
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const explainPolicy = `
+policies:
+  - method: "*"
+    path: /api
+    roles: [admin]
+  - method: DELETE
+    path: /api/billing
+    roles: [admin]
+    effect: deny
+  - method: GET
+    path: /api/tenants/{tenant}
+    match: template
+    roles: [user]
+    when:
+      claims:
+        org.tenants: acme
+public:
+  - path: /health
+    match: exact
+    method: [GET]
+`
+
+// runPolicy executes "gatewayctl policy <args> -file <file>".
+func runPolicy(t *testing.T, file string, wantCode int, args ...string) string {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	env := &cliEnv{
+		stdout: &stdout,
+		stderr: &stderr,
+		getenv: func(string) string { return "" },
+		now:    func() time.Time { return time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC) },
+	}
+
+	args = append(append([]string{"policy"}, args...), "-file", file)
+	if code := run(env, args); code != wantCode {
+		t.Fatalf("gatewayctl %v: expected exit %d, got %d (stderr: %s)", args, wantCode, code, stderr.String())
+	}
+	return stdout.String()
+}
+
+func writePolicyFile(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPolicyExplain(t *testing.T) {
+	file := writePolicyFile(t, explainPolicy)
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-method", "GET", "-path", "/api/billing", "-roles", "admin"},
+			[]string{"decision: ALLOW", "rule:     policy[0]"}},
+		{[]string{"-method", "DELETE", "-path", "/api//billing/", "-roles", "admin"},
+			[]string{"decision: DENY", "rule:     policy[1]", "denied by policy[1] (DELETE /api/billing)"}},
+		{[]string{"-method", "POST", "-path", "/api/users", "-roles", "user"},
+			[]string{"decision: DENY", "rule:     none", "closest policy[0] (* /api): caller has none of the roles admin"}},
+		{[]string{"-path", "/api/tenants/acme", "-roles", "user", "-auth-type", "jwt", "-claim", "org.tenants=globex", "-claim", "org.tenants=acme"},
+			[]string{"decision: ALLOW", "rule:     policy[2]"}},
+		{[]string{"-path", "/api/tenants/acme", "-roles", "user", "-auth-type", "api_key", "-claim", "org.tenants=acme"},
+			[]string{"decision: DENY", "condition not met: claim org.tenants does not match"}},
+		{[]string{"-path", "/health", "-anonymous"},
+			[]string{"decision: ALLOW", "reason:   public route"}},
+	}
+	for _, tt := range tests {
+		out := runPolicy(t, file, 0, append([]string{"explain"}, tt.args...)...)
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("explain %v: output missing %q:\n%s", tt.args, want, out)
+			}
+		}
+	}
+}
+
+func TestPolicyExplainRejectsBadInput(t *testing.T) {
+	file := writePolicyFile(t, explainPolicy)
+
+	runPolicy(t, file, 1, "explain", "-roles", "admin")
+	runPolicy(t, file, 1, "explain", "-path", "/api%2Fbilling", "-roles", "admin")
+	runPolicy(t, file, 1, "explain", "-path", "/api", "-auth-type", "basic")
+	runPolicy(t, writePolicyFile(t, "policies: []\n"), 1, "explain", "-path", "/api")
+}
+
+func TestPolicyTestSuite(t *testing.T) {
+	file := writePolicyFile(t, explainPolicy)
+	tests := strings.TrimSuffix(file, ".yaml") + "_test.yaml"
+
+	suite := `
+tests:
+  - name: admins read billing
+    request: {method: GET, path: /api/billing}
+    identity: {roles: [admin]}
+    expect: allow
+    rule: 0
+  - name: billing cannot be deleted
+    request: {method: DELETE, path: /api/billing, time: 2026-06-01T09:00:00Z}
+    identity: {roles: [admin]}
+    expect: deny
+    rule: 1
+  - name: tenant claim
+    request: {method: GET, path: /api/tenants/acme}
+    identity: {auth_type: jwt, roles: [user], claims: {org: {tenants: [acme]}}}
+    expect: allow
+`
+	if err := os.WriteFile(tests, []byte(suite), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := runPolicy(t, file, 0, "test", "-v")
+	if !strings.Contains(out, "ok    tenant claim") || !strings.Contains(out, "PASS  3 tests") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	// A wrong expectation fails the run and names the case
+	broken := strings.Replace(suite, "expect: deny\n    rule: 1", "expect: allow", 1)
+	if err := os.WriteFile(tests, []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out = runPolicy(t, file, 1, "test")
+	if !strings.Contains(out, "FAIL  billing cannot be deleted: expected ALLOW, got DENY (denied by policy[1]") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestPolicyTestRejectsInvalidSuites(t *testing.T) {
+	file := writePolicyFile(t, explainPolicy)
+	tests := filepath.Join(t.TempDir(), "suite.yaml")
+
+	for name, suite := range map[string]string{
+		"empty":          "tests: []\n",
+		"unknown key":    "tests:\n  - name: a\n    request: {method: GET, path: /}\n    expect: allow\n    expected: deny\n",
+		"bad expect":     "tests:\n  - name: a\n    request: {method: GET, path: /}\n    expect: maybe\n",
+		"no path":        "tests:\n  - name: a\n    request: {method: GET}\n    expect: allow\n",
+		"duplicate name": "tests:\n  - name: a\n    request: {method: GET, path: /}\n    expect: allow\n  - name: a\n    request: {method: GET, path: /}\n    expect: allow\n",
+	} {
+		if err := os.WriteFile(tests, []byte(suite), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var stdout, stderr bytes.Buffer
+		env := &cliEnv{stdout: &stdout, stderr: &stderr, getenv: func(string) string { return "" }, now: time.Now}
+		if code := run(env, []string{"policy", "test", "-file", file, "-tests", tests}); code != 1 {
+			t.Errorf("%s: expected exit 1, got %d", name, code)
+		}
+	}
+}
+
+// The shipped suite must keep passing against the shipped policies.
+func TestShippedPolicySuitePasses(t *testing.T) {
+	runPolicy(t, filepath.Join("..", "..", "policies", "policies.yaml"), 0, "test")
+}
@@ -1,22 +1,40 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 )
 
+// Run several copies on different ports to exercise load balancing:
+//
+//	go run ./cmd/upstream -addr :9000 -name a
+//	go run ./cmd/upstream -addr :9001 -name b
 func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	name := flag.String("name", "", "instance name echoed in responses (default: addr)")
+	flag.Parse()
+
+	if *name == "" {
+		*name = *addr
+	}
+
+	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":"ok"}`)
+	})
+
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		body, _ := io.ReadAll(r.Body)
 		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{"status":"ok","path":"%s","method":"%s","body_size":%d}`,
-			r.URL.Path, r.Method, len(body))
+		fmt.Fprintf(w, `{"status":"ok","instance":%q,"path":"%s","method":"%s","body_size":%d}`,
+			*name, r.URL.Path, r.Method, len(body))
 	})
 
-	log.Println("Demo upstream listening on :9000")
-	if err := http.ListenAndServe(":9000", nil); err != nil {
+	log.Printf("Demo upstream %q listening on %s", *name, *addr)
+	if err := http.ListenAndServe(*addr, nil); err != nil {
 		log.Fatalf("upstream error: %v", err)
 	}
 }
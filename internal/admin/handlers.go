@@ -0,0 +1,444 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/audit"
+	"Zero-TrustAPIGateWayServer/internal/auth"
+	"Zero-TrustAPIGateWayServer/internal/policy"
+)
+
+/*
+ADMIN API
+
+Runtime management of API keys and policies, served on its own
+listener so it can be bound to a private interface.
+
+SECURITY DESIGN:
+- Callers authenticate with the gateway's normal schemes (mounted by
+  the caller), then must hold the admin role. The role is set in the
+  gateway config, NOT in policies.yaml, so a policy upload can never
+  grant or remove admin access.
+- Every mutation is recorded as the request's audit reason, including
+  who did it; failed mutations are audited as denies.
+- Uploaded policies are validated BEFORE anything is written; the live
+  policy set is never replaced by an invalid one.
+- Raw API keys appear exactly once, in the create response.
+
+Endpoints:
+	GET  /admin/keys                   list keys (no secrets)
+	POST /admin/keys                   create a key
+	POST /admin/keys/{id}/revoke       revoke a key
+	POST /admin/policies/validate      validate a policy file (no changes)
+	POST /admin/policies/explain       evaluate a hypothetical request (no traffic)
+	PUT  /admin/policies               replace the policy file
+	POST /admin/reload                 reload policies and keys from disk
+*/
+
+// DefaultRole is required when Handlers.Role is empty.
+const DefaultRole = "gateway-admin"
+
+// MaxBodyBytes caps uploaded policies and key requests.
+const MaxBodyBytes = 1 << 20
+
+// Handlers holds dependencies for admin API endpoints.
+type Handlers struct {
+	Role string
+
+	// API keys; key endpoints answer 409 when no key file is configured
+	KeyFile string
+	Keys    *auth.FileStore
+
+	PolicyPath   string
+	PolicyEngine *policy.Engine
+
+	// validation.lowercase_paths, so explain canonicalizes like enforcement
+	LowercasePaths bool
+
+	// Now is used for key status; nil = time.Now (tests only)
+	Now func() time.Time
+
+	// Serializes read-modify-write of the key file
+	mu sync.Mutex
+}
+
+// Routes returns the admin API. The caller must put authentication in
+// front of it; the admin role is enforced here.
+func (h *Handlers) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/keys", h.listKeys)
+	mux.HandleFunc("POST /admin/keys", h.createKey)
+	mux.HandleFunc("POST /admin/keys/{id}/revoke", h.revokeKey)
+	mux.HandleFunc("POST /admin/policies/validate", h.validatePolicies)
+	mux.HandleFunc("POST /admin/policies/explain", h.explainPolicies)
+	mux.HandleFunc("PUT /admin/policies", h.replacePolicies)
+	mux.HandleFunc("POST /admin/reload", h.reload)
+
+	return h.requireRole(mux)
+}
+
+func (h *Handlers) requireRole(next http.Handler) http.Handler {
+	role := h.Role
+	if role == "" {
+		role = DefaultRole
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := auth.FromContext(r.Context())
+		if !ok || !hasRole(id.Roles, role) {
+			audit.SetReason(r.Context(), "admin: "+role+" role required")
+			http.Error(w, "access denied", http.StatusForbidden)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+/*
+API keys
+*/
+
+type keyDTO struct {
+	ID          string   `json:"id"`
+	Prefix      string   `json:"prefix"`
+	Roles       []string `json:"roles"`
+	Status      string   `json:"status"`
+	NotBefore   string   `json:"not_before,omitempty"`
+	ExpiresAt   string   `json:"expires_at,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+type createKeyRequest struct {
+	ID          string    `json:"id"`
+	Roles       []string  `json:"roles"`
+	NotBefore   time.Time `json:"not_before"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Owner       string    `json:"owner"`
+	Description string    `json:"description"`
+}
+
+func (h *Handlers) listKeys(w http.ResponseWriter, r *http.Request) {
+	if !h.keysConfigured(w, r) {
+		return
+	}
+
+	file, err := auth.LoadKeyFile(h.KeyFile)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed to read key file", err)
+		return
+	}
+
+	now := h.now()
+	out := make([]keyDTO, len(file.Keys))
+	for i, k := range file.Keys {
+		out[i] = keyDTO{
+			ID:          k.ID,
+			Prefix:      k.Prefix,
+			Roles:       k.Roles,
+			Status:      k.Status(now),
+			NotBefore:   formatTime(k.NotBefore),
+			ExpiresAt:   formatTime(k.ExpiresAt),
+			Owner:       k.Owner,
+			Description: k.Description,
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handlers) createKey(w http.ResponseWriter, r *http.Request) {
+	if !h.keysConfigured(w, r) {
+		return
+	}
+
+	var req createKeyRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+	if strings.TrimSpace(req.ID) == "" || len(req.Roles) == 0 {
+		h.fail(w, r, http.StatusBadRequest, "id and roles are required", nil)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := auth.LoadKeyFile(h.KeyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		file, err = auth.KeyFile{}, nil
+	}
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed to read key file", err)
+		return
+	}
+	if file.Find(req.ID) >= 0 {
+		h.fail(w, r, http.StatusConflict, "key "+req.ID+" already exists", nil)
+		return
+	}
+
+	raw, record, err := auth.GenerateAPIKey(req.ID, req.Roles)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed to generate key", err)
+		return
+	}
+	record.NotBefore = req.NotBefore.UTC()
+	record.ExpiresAt = req.ExpiresAt.UTC()
+	record.Owner = req.Owner
+	record.Description = req.Description
+
+	file.Keys = append(file.Keys, record)
+	if err := h.saveKeys(file); err != nil {
+		status, msg := http.StatusUnprocessableEntity, "key rejected"
+		if errors.Is(err, errKeysNotApplied) {
+			status, msg = http.StatusInternalServerError, "key file written but reload failed"
+		}
+		h.fail(w, r, status, msg, err)
+		return
+	}
+
+	audit.SetReason(r.Context(), "admin: key "+record.ID+" created by "+actor(r))
+
+	// The only time the raw key is ever returned
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, map[string]string{
+		"id":     record.ID,
+		"prefix": record.Prefix,
+		"key":    raw,
+	})
+}
+
+func (h *Handlers) revokeKey(w http.ResponseWriter, r *http.Request) {
+	if !h.keysConfigured(w, r) {
+		return
+	}
+	id := r.PathValue("id")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := auth.LoadKeyFile(h.KeyFile)
+	if err != nil {
+		h.fail(w, r, http.StatusInternalServerError, "failed to read key file", err)
+		return
+	}
+
+	i := file.Find(id)
+	if i < 0 {
+		h.fail(w, r, http.StatusNotFound, "key "+id+" not found", nil)
+		return
+	}
+
+	file.Keys[i].Revoked = true
+	if err := h.saveKeys(file); err != nil {
+		msg := "failed to write key file"
+		if errors.Is(err, errKeysNotApplied) {
+			msg = "key file written but reload failed"
+		}
+		h.fail(w, r, http.StatusInternalServerError, msg, err)
+		return
+	}
+
+	audit.SetReason(r.Context(), "admin: key "+id+" revoked by "+actor(r))
+	writeJSON(w, http.StatusOK, map[string]string{"id": id, "status": "revoked"})
+}
+
+// errKeysNotApplied means the key file was written but reloading it
+// failed: the change is on disk and the store is left in deny-all.
+var errKeysNotApplied = errors.New("key file not applied")
+
+// saveKeys writes the key file and applies it immediately rather than
+// waiting for the watcher, so a revocation takes effect on return.
+func (h *Handlers) saveKeys(file auth.KeyFile) error {
+	if err := auth.SaveKeyFile(h.KeyFile, file); err != nil {
+		return err
+	}
+	if err := h.Keys.LoadFromFile(h.KeyFile); err != nil {
+		return fmt.Errorf("%w: %v", errKeysNotApplied, err)
+	}
+	return nil
+}
+
+func (h *Handlers) keysConfigured(w http.ResponseWriter, r *http.Request) bool {
+	if h.Keys == nil || h.KeyFile == "" {
+		h.fail(w, r, http.StatusConflict, "no API key file configured", nil)
+		return false
+	}
+	return true
+}
+
+/*
+Policies
+*/
+
+func (h *Handlers) validatePolicies(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.fail(w, r, http.StatusRequestEntityTooLarge, "policy file too large", err)
+		return
+	}
+
+	pf, err := policy.Parse(data)
+	if err != nil {
+		// Validation is read-only; an invalid file is a normal answer
+		writeJSON(w, http.StatusOK, map[string]interface{}{"valid": false, "error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"valid": true, "policies": len(pf.Policies)})
+}
+
+// explainRequest is a hypothetical request, evaluated against the live
+// policies or, when given, a candidate policy file.
+type explainRequest struct {
+	policy.Hypothetical
+	Candidate string `json:"candidate,omitempty"` // policies.yaml content
+}
+
+func (h *Handlers) explainPolicies(w http.ResponseWriter, r *http.Request) {
+	var req explainRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	source := "current"
+	set := h.PolicyEngine.Current()
+	if req.Candidate != "" {
+		pf, err := policy.Parse([]byte(req.Candidate))
+		if err == nil {
+			set, err = policy.Compile(pf)
+		}
+		if err != nil {
+			h.fail(w, r, http.StatusUnprocessableEntity, "candidate policy rejected", err)
+			return
+		}
+		source = "candidate"
+	}
+
+	decision, err := policy.Explain(set, req.Hypothetical, policy.Options{Now: h.now(), LowercasePaths: h.LowercasePaths})
+	if err != nil {
+		h.fail(w, r, http.StatusBadRequest, "invalid hypothetical request", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"policies": source, "decision": decision})
+}
+
+func (h *Handlers) replacePolicies(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.fail(w, r, http.StatusRequestEntityTooLarge, "policy file too large", err)
+		return
+	}
+
+	if err := h.PolicyEngine.Replace(h.PolicyPath, data); err != nil {
+		h.fail(w, r, http.StatusUnprocessableEntity, "policy rejected", err)
+		return
+	}
+
+	count := len(h.PolicyEngine.GetPolicies())
+	audit.SetReason(r.Context(), fmt.Sprintf("admin: policies replaced (%d rules) by %s", count, actor(r)))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"policies": count})
+}
+
+/*
+Reload
+*/
+
+func (h *Handlers) reload(w http.ResponseWriter, r *http.Request) {
+	result := map[string]string{}
+	failed := false
+
+	// A failed reload leaves that component in deny-all, exactly like
+	// the file watchers do
+	if err := h.PolicyEngine.LoadFromFile(h.PolicyPath); err != nil {
+		result["policies"] = err.Error()
+		failed = true
+	} else {
+		result["policies"] = "ok"
+	}
+
+	if h.Keys != nil && h.KeyFile != "" {
+		if err := h.Keys.LoadFromFile(h.KeyFile); err != nil {
+			result["keys"] = err.Error()
+			failed = true
+		} else {
+			result["keys"] = "ok"
+		}
+	}
+
+	status := http.StatusOK
+	reason := "admin: reload by " + actor(r)
+	if failed {
+		status = http.StatusInternalServerError
+		reason = "admin: reload failed (deny all) by " + actor(r)
+	}
+
+	audit.SetReason(r.Context(), reason)
+	writeJSON(w, status, result)
+}
+
+/*
+helpers
+*/
+
+// fail answers with msg and audits the failure. err is detail for the
+// caller (already an authenticated admin) and the audit log.
+func (h *Handlers) fail(w http.ResponseWriter, r *http.Request, status int, msg string, err error) {
+	if err != nil {
+		msg += ": " + err.Error()
+	}
+	audit.SetReason(r.Context(), "admin: "+msg+" ("+actor(r)+")")
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func (h *Handlers) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+	return time.Now()
+}
+
+// actor names the authenticated caller for the audit log.
+func actor(r *http.Request) string {
+	id, ok := auth.FromContext(r.Context())
+	if !ok {
+		return "unknown"
+	}
+	return string(id.Type) + ":" + id.Subject
+}
+
+func hasRole(roles []string, want string) bool {
+	for _, r := range roles {
+		if r == want {
+			return true
+		}
+	}
+	return false
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
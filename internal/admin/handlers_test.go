@@ -0,0 +1,250 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Zero-TrustAPIGateWayServer/internal/audit"
+	"Zero-TrustAPIGateWayServer/internal/auth"
+	"Zero-TrustAPIGateWayServer/internal/policy"
+)
+
+const testPolicy = `
+policies:
+  - method: GET
+    path: /api/public
+    roles: [user]
+`
+
+func newTestHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	dir := t.TempDir()
+
+	keyFile := filepath.Join(dir, "api_keys.yaml")
+	if err := os.WriteFile(keyFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.NewFileStore(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policyPath := filepath.Join(dir, "policies.yaml")
+	if err := os.WriteFile(policyPath, []byte(testPolicy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine := policy.NewEngine()
+	if err := engine.LoadFromFile(policyPath); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Handlers{
+		KeyFile:      keyFile,
+		Keys:         keys,
+		PolicyPath:   policyPath,
+		PolicyEngine: engine,
+	}
+}
+
+// call serves one request as an identity with roles and returns the
+// response and the recorded audit reason.
+func call(h *Handlers, method, path, body string, roles ...string) (*httptest.ResponseRecorder, string) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	ctx := audit.WithReason(req.Context())
+	ctx = auth.WithIdentity(ctx, &auth.Identity{Type: auth.AuthJWT, Subject: "alice", Roles: roles})
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	h.Routes().ServeHTTP(rr, req)
+
+	reason, _ := audit.Reason(ctx)
+	return rr, reason
+}
+
+func TestAdminRoleRequired(t *testing.T) {
+	h := newTestHandlers(t)
+
+	// "admin" is a policy role, not the admin API role
+	rr, reason := call(h, "GET", "/admin/keys", "", "admin", "user")
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+	if !strings.Contains(reason, DefaultRole) {
+		t.Fatalf("expected audited role denial, got %q", reason)
+	}
+
+	h.Role = "ops"
+	if rr, _ := call(h, "GET", "/admin/keys", "", DefaultRole); rr.Code != http.StatusForbidden {
+		t.Fatalf("configured role must replace the default, got %d", rr.Code)
+	}
+	if rr, _ := call(h, "GET", "/admin/keys", "", "ops"); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for configured role, got %d", rr.Code)
+	}
+}
+
+func TestAdminCreateAndRevokeKey(t *testing.T) {
+	h := newTestHandlers(t)
+
+	rr, reason := call(h, "POST", "/admin/keys", `{"id": "ci-bot", "roles": ["deployer"], "owner": "platform"}`, DefaultRole)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if reason != "admin: key ci-bot created by jwt:alice" {
+		t.Fatalf("unexpected audit reason %q", reason)
+	}
+	if rr.Header().Get("Cache-Control") != "no-store" {
+		t.Fatal("raw key response must not be cached")
+	}
+
+	var created map[string]string
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	raw := created["key"]
+
+	// Usable immediately, without waiting for a file watcher
+	if _, ok := h.Keys.Lookup(raw); !ok {
+		t.Fatal("created key not loaded into the store")
+	}
+
+	// Listing never returns secrets
+	rr, _ = call(h, "GET", "/admin/keys", "", DefaultRole)
+	if strings.Contains(rr.Body.String(), raw) || strings.Contains(rr.Body.String(), "sha256:") {
+		t.Fatalf("secret material in key list: %s", rr.Body.String())
+	}
+
+	if rr, _ := call(h, "POST", "/admin/keys", `{"id": "ci-bot", "roles": ["x"]}`, DefaultRole); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate id, got %d", rr.Code)
+	}
+
+	rr, reason = call(h, "POST", "/admin/keys/ci-bot/revoke", "", DefaultRole)
+	if rr.Code != http.StatusOK || reason != "admin: key ci-bot revoked by jwt:alice" {
+		t.Fatalf("unexpected revoke result %d %q", rr.Code, reason)
+	}
+
+	record, ok := h.Keys.Lookup(raw)
+	if !ok || !record.Revoked {
+		t.Fatal("revocation not applied to the live store")
+	}
+
+	if rr, _ := call(h, "POST", "/admin/keys/missing/revoke", "", DefaultRole); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestAdminRejectsUnknownKeyFields(t *testing.T) {
+	h := newTestHandlers(t)
+
+	rr, reason := call(h, "POST", "/admin/keys", `{"id": "x", "roles": ["y"], "key": "chosen-by-caller"}`, DefaultRole)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	if !strings.HasPrefix(reason, "admin: invalid request body") {
+		t.Fatalf("failed mutation must be audited, got %q", reason)
+	}
+}
+
+func TestAdminPolicyUpload(t *testing.T) {
+	h := newTestHandlers(t)
+
+	broken := "policies:\n  - method: GET\n    path: no-slash\n    roles: [user]\n"
+
+	rr, _ := call(h, "POST", "/admin/policies/validate", broken, DefaultRole)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"valid":false`) {
+		t.Fatalf("unexpected validate result %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr, reason := call(h, "PUT", "/admin/policies", broken, DefaultRole)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", rr.Code)
+	}
+	if !strings.Contains(reason, "policy rejected") {
+		t.Fatalf("rejected upload must be audited, got %q", reason)
+	}
+
+	// The live policy set and the file are untouched
+	if got := h.PolicyEngine.GetPolicies(); len(got) != 1 || got[0].Path != "/api/public" {
+		t.Fatalf("live policies changed by a rejected upload: %+v", got)
+	}
+	if data, _ := os.ReadFile(h.PolicyPath); string(data) != testPolicy {
+		t.Fatal("policy file changed by a rejected upload")
+	}
+
+	updated := testPolicy + "  - method: POST\n    path: /api/admin\n    roles: [admin]\n"
+	rr, reason = call(h, "PUT", "/admin/policies", updated, DefaultRole)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if reason != "admin: policies replaced (2 rules) by jwt:alice" {
+		t.Fatalf("unexpected audit reason %q", reason)
+	}
+	if len(h.PolicyEngine.GetPolicies()) != 2 {
+		t.Fatal("uploaded policies not applied")
+	}
+}
+
+func TestAdminReload(t *testing.T) {
+	h := newTestHandlers(t)
+
+	if err := os.WriteFile(h.PolicyPath, []byte(testPolicy+"  - method: DELETE\n    path: /api/x\n    roles: [admin]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rr, reason := call(h, "POST", "/admin/reload", "", DefaultRole)
+	if rr.Code != http.StatusOK || reason != "admin: reload by jwt:alice" {
+		t.Fatalf("unexpected reload result %d %q", rr.Code, reason)
+	}
+	if len(h.PolicyEngine.GetPolicies()) != 2 {
+		t.Fatal("reload did not pick up the policy file")
+	}
+}
+
+func TestAdminPolicyExplain(t *testing.T) {
+	h := newTestHandlers(t)
+
+	explain := func(body string) (int, map[string]interface{}) {
+		rr, _ := call(h, "POST", "/admin/policies/explain", body, DefaultRole)
+		var out map[string]interface{}
+		json.NewDecoder(rr.Body).Decode(&out)
+		return rr.Code, out
+	}
+
+	code, out := explain(`{"method":"GET","path":"/api/public/items","identity":{"auth_type":"api_key","roles":["user"]}}`)
+	decision, _ := out["decision"].(map[string]interface{})
+	if code != http.StatusOK || out["policies"] != "current" || decision["allowed"] != true || decision["rule"] != 0.0 {
+		t.Fatalf("current: unexpected result %d %v", code, out)
+	}
+
+	// A candidate file is evaluated instead of the live one, and not loaded
+	candidate := testPolicy + "  - method: GET\n    path: /api/public/items\n    roles: [user]\n    effect: deny\n"
+	body, _ := json.Marshal(map[string]interface{}{
+		"method":    "GET",
+		"path":      "/api/public/items",
+		"identity":  map[string]interface{}{"roles": []string{"user"}},
+		"candidate": candidate,
+	})
+	code, out = explain(string(body))
+	decision, _ = out["decision"].(map[string]interface{})
+	if code != http.StatusOK || out["policies"] != "candidate" || decision["allowed"] != false || decision["rule"] != 1.0 {
+		t.Fatalf("candidate: unexpected result %d %v", code, out)
+	}
+	if len(h.PolicyEngine.GetPolicies()) != 1 {
+		t.Fatal("explain must not change the live policies")
+	}
+
+	if code, _ := explain(`{"method":"GET","path":"/api","candidate":"policies: []"}`); code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid candidate: expected 422, got %d", code)
+	}
+	if code, _ := explain(`{"method":"GET","path":"/api%2Fadmin"}`); code != http.StatusBadRequest {
+		t.Fatalf("ambiguous path: expected 400, got %d", code)
+	}
+	// A misspelt field would otherwise be dropped and change the question
+	if code, _ := explain(`{"method":"GET","path":"/api/public/items","identty":{"roles":["user"]}}`); code != http.StatusBadRequest {
+		t.Fatalf("unknown field: expected 400, got %d", code)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 )
@@ -65,6 +67,18 @@ func (l *Logger) Close() error {
 	return l.file.Close()
 }
 
+// RequestPath is the path to log for r: as the client sent it, still
+// percent-encoded and without the query. Take it before validation
+// canonicalizes the request, so a rejected traversal or encoded slash
+// is recorded as sent, not as the path it would have become.
+func RequestPath(r *http.Request) string {
+	if strings.HasPrefix(r.RequestURI, "/") {
+		path, _, _ := strings.Cut(r.RequestURI, "?")
+		return path
+	}
+	return r.URL.EscapedPath()
+}
+
 func (l *Logger) Log(method, path, decision, reason string) {
 	// Fail open never panic outward
 	defer func() {
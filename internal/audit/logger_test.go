@@ -1,6 +1,7 @@
 package audit
 
 import (
+	"net/http/httptest"
 	"os"
 	"testing"
 )
@@ -67,3 +68,18 @@ func TestTamperingDetection(t *testing.T) {
 		t.Fatal("expected tampering to be detected")
 	}
 }
+
+func TestRequestPathIsAsSent(t *testing.T) {
+	tests := map[string]string{
+		"/api/users":                "/api/users",
+		"/api/public/..%2Fadmin":    "/api/public/..%2Fadmin",
+		"//api/./%61dmin?token=abc": "//api/./%61dmin",
+	}
+
+	for target, want := range tests {
+		r := httptest.NewRequest("GET", target, nil)
+		if got := RequestPath(r); got != want {
+			t.Errorf("%s: got %q, want %q", target, got, want)
+		}
+	}
+}
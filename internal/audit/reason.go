@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+DECISION REASONS
+
+The audit middleware wraps the whole chain, so it only sees the final
+status code. A layer that denies a request can record WHY here; the
+reason goes to the audit log only, never to the client (responses stay
+generic so they do not reveal which check failed). Handlers that change
+gateway state (the admin API) record what they did the same way.
+
+The first reason recorded wins: the layer that actually denied the
+request is the earliest one to say so.
+*/
+
+type reasonKey struct{}
+
+type reasonHolder struct {
+	mu     sync.Mutex
+	reason string
+}
+
+// WithReason returns a context that can carry a decision reason.
+func WithReason(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reasonKey{}, &reasonHolder{})
+}
+
+// SetReason records why the request was decided. It is a no-op when the
+// context was not prepared with WithReason or a reason is already set.
+func SetReason(ctx context.Context, reason string) {
+	h, ok := ctx.Value(reasonKey{}).(*reasonHolder)
+	if !ok {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.reason == "" {
+		h.reason = reason
+	}
+}
+
+// Reason returns the recorded reason, if any.
+func Reason(ctx context.Context) (string, bool) {
+	h, ok := ctx.Value(reasonKey{}).(*reasonHolder)
+	if !ok {
+		return "", false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.reason, h.reason != ""
+}
@@ -0,0 +1,27 @@
+package audit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFirstReasonWins(t *testing.T) {
+	ctx := WithReason(context.Background())
+
+	SetReason(ctx, "key revoked")
+	SetReason(ctx, "access denied")
+
+	if reason, ok := Reason(ctx); !ok || reason != "key revoked" {
+		t.Fatalf("expected first reason, got %q", reason)
+	}
+}
+
+func TestReasonWithoutHolderIsIgnored(t *testing.T) {
+	ctx := context.Background()
+
+	SetReason(ctx, "ignored")
+
+	if _, ok := Reason(ctx); ok {
+		t.Fatal("expected no reason without WithReason")
+	}
+}
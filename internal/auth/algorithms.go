@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+/*
+SIGNING ALGORITHMS
+
+- Only asymmetric algorithms are supported; there is no way to enable
+  "none" or any HMAC algorithm, so a public key can never be abused as
+  an HMAC secret (algorithm confusion)
+- Each issuer declares an explicit allow-list
+- The verification key's type must match the token's algorithm:
+    RS256, PS256 -> RSA (>= 2048 bits)
+    ES256        -> ECDSA P-256
+    EdDSA        -> Ed25519
+*/
+
+// DefaultAlgorithms is used when JWTConfig.Algorithms is empty.
+var DefaultAlgorithms = []string{"RS256"}
+
+var supportedAlgorithms = map[string]bool{
+	"RS256": true,
+	"PS256": true,
+	"ES256": true,
+	"EdDSA": true,
+}
+
+// IsSupportedAlgorithm reports whether alg may appear in an allow-list.
+func IsSupportedAlgorithm(alg string) bool {
+	return supportedAlgorithms[alg]
+}
+
+// checkKeyForAlgorithm rejects keys whose type does not match alg.
+func checkKeyForAlgorithm(alg string, key crypto.PublicKey) error {
+	switch alg {
+	case "RS256", "PS256":
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key type does not match signing method")
+		}
+		if k.N.BitLen() < 2048 {
+			return errors.New("RSA key shorter than 2048 bits")
+		}
+	case "ES256":
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok || k.Curve != elliptic.P256() {
+			return errors.New("key type does not match signing method")
+		}
+	case "EdDSA":
+		if _, ok := key.(ed25519.PublicKey); !ok {
+			return errors.New("key type does not match signing method")
+		}
+	default:
+		return fmt.Errorf("unsupported signing method %q", alg)
+	}
+	return nil
+}
+
+// allowedAlgorithms returns the effective allow-list, dropping anything
+// we do not support (defence in depth; config validation rejects it).
+func allowedAlgorithms(configured []string) []string {
+	if len(configured) == 0 {
+		return DefaultAlgorithms
+	}
+
+	var algs []string
+	for _, a := range configured {
+		if IsSupportedAlgorithm(a) {
+			algs = append(algs, a)
+		}
+	}
+	return algs
+}
+
+// LoadPublicKey reads a PEM-encoded public key: PKIX (RSA, ECDSA P-256,
+// Ed25519) or PKCS#1 (RSA).
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("public key file is not PEM")
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return jwt.ParseRSAPublicKeyFromPEM(data)
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type algVector struct {
+	alg     string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+func algVectors(t *testing.T) []algVector {
+	t.Helper()
+
+	rsaKey := newRSAKey(t)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []algVector{
+		{"RS256", jwt.SigningMethodRS256, rsaKey, &rsaKey.PublicKey},
+		{"PS256", jwt.SigningMethodPS256, rsaKey, &rsaKey.PublicKey},
+		{"ES256", jwt.SigningMethodES256, ecKey, &ecKey.PublicKey},
+		{"EdDSA", jwt.SigningMethodEdDSA, edPriv, edPub},
+	}
+}
+
+func TestEachAlgorithmVerifies(t *testing.T) {
+	for _, v := range algVectors(t) {
+		cfg := singleIssuer(IssuerConfig{Issuer: "test-idp", Audiences: []string{"gateway"}, PublicKey: v.public, Algorithms: []string{v.alg}})
+
+		if code, _ := authenticate(t, cfg, sign(t, v.method, v.private, "", validClaims())); code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", v.alg, code)
+		}
+	}
+}
+
+func TestAlgorithmNotInAllowListRejected(t *testing.T) {
+	for _, v := range algVectors(t) {
+		// Allow-list contains every algorithm except the one used
+		var others []string
+		for _, o := range []string{"RS256", "PS256", "ES256", "EdDSA"} {
+			if o != v.alg {
+				others = append(others, o)
+			}
+		}
+		cfg := singleIssuer(IssuerConfig{Issuer: "test-idp", Audiences: []string{"gateway"}, PublicKey: v.public, Algorithms: others})
+
+		if code, _ := authenticate(t, cfg, sign(t, v.method, v.private, "", validClaims())); code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected 401 when not allowed, got %d", v.alg, code)
+		}
+	}
+}
+
+func TestDefaultAllowListIsRS256Only(t *testing.T) {
+	for _, v := range algVectors(t) {
+		cfg := singleIssuer(IssuerConfig{Issuer: "test-idp", Audiences: []string{"gateway"}, PublicKey: v.public})
+
+		want := http.StatusUnauthorized
+		if v.alg == "RS256" {
+			want = http.StatusOK
+		}
+		if code, _ := authenticate(t, cfg, sign(t, v.method, v.private, "", validClaims())); code != want {
+			t.Fatalf("%s: expected %d with default allow-list, got %d", v.alg, want, code)
+		}
+	}
+}
+
+func TestKeyTypeMustMatchAlgorithm(t *testing.T) {
+	vectors := algVectors(t)
+	rsaPub := vectors[0].public
+	ec := vectors[2]
+
+	// ES256 token, ES256 allowed, but the configured key is RSA
+	cfg := singleIssuer(IssuerConfig{Issuer: "test-idp", Audiences: []string{"gateway"}, PublicKey: rsaPub, Algorithms: []string{"RS256", "ES256"}})
+	if code, _ := authenticate(t, cfg, sign(t, ec.method, ec.private, "", validClaims())); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for key/alg mismatch, got %d", code)
+	}
+}
+
+func TestNoneAndHMACConfusionRejected(t *testing.T) {
+	rsaKey := newRSAKey(t)
+
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	// Even an allow-list naming HS256/none must not enable them
+	cfg := singleIssuer(IssuerConfig{Issuer: "test-idp", Audiences: []string{"gateway"}, PublicKey: &rsaKey.PublicKey, Algorithms: []string{"RS256", "HS256", "none"}})
+
+	// Classic confusion: HMAC keyed with the public key bytes
+	hmacToken := sign(t, jwt.SigningMethodHS256, pemBytes, "", validClaims())
+	if code, _ := authenticate(t, cfg, hmacToken); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for HS256 confusion, got %d", code)
+	}
+
+	noneToken := sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims())
+	if code, _ := authenticate(t, cfg, noneToken); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for alg=none, got %d", code)
+	}
+
+	// Hand-crafted alg=none with empty signature
+	parts := strings.Split(sign(t, jwt.SigningMethodRS256, rsaKey, "", validClaims()), ".")
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	if code, _ := authenticate(t, cfg, header+"."+parts[1]+"."); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for crafted none token, got %d", code)
+	}
+}
+
+func TestJWKSWithECAndEd25519Keys(t *testing.T) {
+	vectors := algVectors(t)
+	ec, ed := vectors[2], vectors[3]
+
+	ecPub := ec.public.(*ecdsa.PublicKey)
+	ecBytes, err := ecPub.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	doc := `{"keys":[` +
+		`{"kty":"EC","kid":"ec","crv":"P-256","x":"` + b64(ecBytes[1:33]) + `","y":"` + b64(ecBytes[33:]) + `"},` +
+		`{"kty":"OKP","kid":"ed","crv":"Ed25519","x":"` + b64(ed.public.(ed25519.PublicKey)) + `"}` +
+		`]}`
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	jwks, err := NewJWKS(JWKSConfig{File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := singleIssuer(IssuerConfig{Issuer: "test-idp", Audiences: []string{"gateway"}, Keys: jwks, Algorithms: []string{"ES256", "EdDSA"}})
+
+	for kid, v := range map[string]algVector{"ec": ec, "ed": ed} {
+		if code, _ := authenticate(t, cfg, sign(t, v.method, v.private, kid, validClaims())); code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", kid, code)
+		}
+	}
+
+	// ES256 token pointing at the Ed25519 key
+	if code, _ := authenticate(t, cfg, sign(t, ec.method, ec.private, "ed", validClaims())); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for kid/alg mismatch, got %d", code)
+	}
+}
+
+func TestLoadPublicKeyPEM(t *testing.T) {
+	for _, v := range algVectors(t) {
+		der, err := x509.MarshalPKIXPublicKey(v.public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), v.alg+".pem")
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		key, err := LoadPublicKey(path)
+		if err != nil {
+			t.Fatalf("%s: %v", v.alg, err)
+		}
+		if err := checkKeyForAlgorithm(v.alg, key); err != nil {
+			t.Fatalf("%s: loaded key does not match: %v", v.alg, err)
+		}
+	}
+}
@@ -1,60 +1,101 @@
 package auth
 
 import (
-	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
-	"fmt"
+	"errors"
 	"net/http"
+	"time"
 )
 
 /*
 SECURITY NOTES
  Keys are compared using constant time comparison
+ Persistent stores hold only a public prefix and a SHA-256 hash (keystore.go)
  No plaintext logging
  Fail closed on missing or invalid keys
 */
 
 type APIKey struct {
-	ID    string
-	Key   string // hashed in real deployments
-	Roles []string
+	ID     string
+	Key    string // raw key; in-memory demo/test stores only
+	Prefix string // public lookup prefix (hashed stores)
+	Hash   string // hex SHA-256 of the raw key (hashed stores)
+	Roles  []string
+
+	// Lifecycle (zero values = no restriction)
+	NotBefore time.Time
+	ExpiresAt time.Time
+	Revoked   bool
+
+	// Bookkeeping only; never used for decisions
+	Owner       string
+	Description string
 }
 
+// Distinct key lifecycle failures. They are recorded as the audit
+// reason; the client still gets the generic 401.
+var (
+	ErrKeyRevoked     = errors.New("key revoked")
+	ErrKeyExpired     = errors.New("key expired")
+	ErrKeyNotYetValid = errors.New("key not yet valid")
+)
+
+// checkLifecycle is only called AFTER the key has been verified, so a
+// caller without the key learns nothing about the record.
+// Revocation is checked first: it is the strongest statement.
+func (k *APIKey) checkLifecycle(now time.Time) error {
+	if k.Revoked {
+		return ErrKeyRevoked
+	}
+	if !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt) {
+		return ErrKeyExpired
+	}
+	if !k.NotBefore.IsZero() && now.Before(k.NotBefore) {
+		return ErrKeyNotYetValid
+	}
+	return nil
+}
+
+// APIKeyStore finds the candidate record for a presented key.
+// The authenticator still verifies the key against the record.
 type APIKeyStore interface {
 	Lookup(key string) (*APIKey, bool)
 }
 
-// GenerateTestAPIKey creates a random API key for testing purposes.
-// It returns both the APIKey struct (for store) and the raw key string (for HTTP headers).
-func GenerateTestAPIKey(id string, roles []string) (*APIKey, string, error) {
-	// Generate 32 random bytes and encode as hex (64 char string)
-	randomBytes := make([]byte, 32)
-	_, err := rand.Read(randomBytes)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate random key: %w", err)
-	}
-
-	keyString := hex.EncodeToString(randomBytes)
+// APIKeyIDStore finds a record by key ID. It lets a credential be
+// re-checked after login (dashboard sessions) without keeping the raw key.
+type APIKeyIDStore interface {
+	LookupID(id string) (*APIKey, bool)
+}
 
-	apiKey := &APIKey{
-		ID:    id,
-		Key:   keyString,
-		Roles: roles,
+// matches reports whether raw is this record's key, in constant time.
+// A record with a hash is only ever checked against the hash.
+func (k *APIKey) matches(raw string) bool {
+	if k.Hash != "" {
+		want, err := hex.DecodeString(k.Hash)
+		if err != nil || len(want) != sha256.Size {
+			return false
+		}
+		sum := sha256.Sum256([]byte(raw))
+		return subtle.ConstantTimeCompare(sum[:], want) == 1
 	}
 
-	return apiKey, keyString, nil
+	if k.Key == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(raw), []byte(k.Key)) == 1
 }
 
-// GenerateTestAPIKeySimple is a convenience function that generates a test key
-// with a default ID and common test roles. Returns the raw key string for easy copying.
-func GenerateTestAPIKeySimple() (string, error) {
-	apiKey, keyString, err := GenerateTestAPIKey("test-key-001", []string{"read", "write"})
-	if err != nil {
-		return "", err
+// fingerprint identifies this record's secret without revealing it:
+// rotating a key under the same ID changes it.
+func (k *APIKey) fingerprint() string {
+	if k.Hash != "" {
+		return k.Prefix + "." + k.Hash
 	}
-	_ = apiKey // Store this if needed for your mock
-	return keyString, nil
+	sum := sha256.Sum256([]byte(k.Key))
+	return hex.EncodeToString(sum[:])
 }
 
 // MapStore is an in-memory API key store. Keys are the raw key string.
@@ -68,16 +109,26 @@ func (m MapStore) Lookup(key string) (*APIKey, bool) {
 	return nil, false
 }
 
+// LookupID implements APIKeyIDStore.
+func (m MapStore) LookupID(id string) (*APIKey, bool) {
+	for _, apiKey := range m {
+		if apiKey.ID == id {
+			return apiKey, true
+		}
+	}
+	return nil, false
+}
+
 // NewDemoStore returns a pre-populated store with demo keys for testing.
 // Demo keys (use in X-API-Key header):
-//   - demo-admin: roles [admin] — for POST/DELETE /api/admin
+//   - demo-admin: roles [admin, dashboard-viewer] — for POST/DELETE /api/admin and the dashboard
 //   - demo-user:  roles [user]  — for GET /api/public
 func NewDemoStore() APIKeyStore {
 	store := make(MapStore)
 
 	// Demo admin key — full access to admin endpoints
 	adminKey := "deef0admin0000000000000000000000000000000000000000000000000000"
-	store[adminKey] = &APIKey{ID: "demo-admin", Key: adminKey, Roles: []string{"admin"}}
+	store[adminKey] = &APIKey{ID: "demo-admin", Key: adminKey, Roles: []string{"admin", "dashboard-viewer"}}
 
 	// Demo user key — access to public endpoints only
 	userKey := "deef0us3r0000000000000000000000000000000000000000000000000000"
@@ -86,42 +137,93 @@ func NewDemoStore() APIKeyStore {
 	return store
 }
 
-func APIKeyMiddleware(store APIKeyStore) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-			// Skip auth for health checks
-			if r.URL.Path == "/health" {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			key := r.Header.Get("X-API-Key")
-			if key == "" {
-				http.Error(w, "missing API key", http.StatusUnauthorized)
-				return
-			}
-
-			record, ok := store.Lookup(key)
-			if !ok {
-				http.Error(w, "invalid API key", http.StatusUnauthorized)
-				return
-			}
-
-			// Constant-time comparison (defensive)
-			if subtle.ConstantTimeCompare([]byte(key), []byte(record.Key)) != 1 {
-				http.Error(w, "invalid API key", http.StatusUnauthorized)
-				return
-			}
-
-			id := &Identity{
-				Type:    AuthAPIKey,
-				Subject: record.ID,
-				Roles:   record.Roles,
-			}
-
-			ctx := WithIdentity(r.Context(), id)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		})
+// APIKeyAuthenticator authenticates the X-API-Key header.
+type APIKeyAuthenticator struct {
+	Store APIKeyStore
+
+	// Usage records last-used times off the request path; nil = off
+	Usage *UsageTracker
+
+	// Now is used for NotBefore/ExpiresAt; nil = time.Now (tests only)
+	Now func() time.Time
+}
+
+// Authenticate implements Authenticator.
+func (a APIKeyAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		return nil, ErrNoCredentials
 	}
+
+	record, ok := a.Store.Lookup(key)
+	if !ok {
+		return nil, errors.New("invalid API key")
+	}
+
+	// Constant-time comparison (defensive for raw-key stores,
+	// authoritative for hashed stores)
+	if !record.matches(key) {
+		return nil, errors.New("invalid API key")
+	}
+
+	if err := record.checkLifecycle(a.now()); err != nil {
+		return nil, err
+	}
+
+	a.Usage.Touch(record.ID)
+
+	return &Identity{
+		Type:           AuthAPIKey,
+		Subject:        record.ID,
+		Roles:          record.Roles,
+		KeyFingerprint: record.fingerprint(),
+	}, nil
+}
+
+// Recheck re-validates an identity this authenticator issued earlier
+// against the live store: a key that has since been revoked, expired,
+// rotated, removed or reloaded away fails, and the current roles are
+// returned.
+// Identities from other schemes are returned unchanged.
+func (a APIKeyAuthenticator) Recheck(id Identity) (*Identity, error) {
+	if id.Type != AuthAPIKey {
+		return &id, nil
+	}
+
+	// Fail closed: a store that cannot be queried by ID cannot vouch
+	store, ok := a.Store.(APIKeyIDStore)
+	if !ok {
+		return nil, errors.New("API key store cannot re-check keys")
+	}
+	record, ok := store.LookupID(id.Subject)
+	if !ok {
+		return nil, errors.New("API key no longer exists")
+	}
+	// Same ID, new secret: the old (possibly leaked) key is gone
+	if subtle.ConstantTimeCompare([]byte(record.fingerprint()), []byte(id.KeyFingerprint)) != 1 {
+		return nil, errors.New("API key rotated")
+	}
+	if err := record.checkLifecycle(a.now()); err != nil {
+		return nil, err
+	}
+
+	id.Roles = record.Roles
+	return &id, nil
+}
+
+func (a APIKeyAuthenticator) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+// Challenge implements Authenticator.
+func (APIKeyAuthenticator) Challenge() string {
+	return `APIKey realm="` + Realm + `", header="X-API-Key"`
+}
+
+// APIKeyMiddleware authenticates with API keys only.
+func APIKeyMiddleware(store APIKeyStore) func(http.Handler) http.Handler {
+	return Middleware(APIKeyAuthenticator{Store: store})
 }
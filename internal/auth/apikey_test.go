@@ -1,9 +1,14 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/audit"
 )
 
 type mockStore struct {
@@ -98,20 +103,87 @@ func TestAPIKeyMiddlewareRejectsInvalidKey(t *testing.T) {
 	}
 }
 
-func TestAPIKeyMiddlewareSkipsHealth(t *testing.T) {
+func TestAPIKeyMiddlewareSkipsPublicRoutes(t *testing.T) {
 	store := &mockStore{} // empty no valid keys
 
 	handler := APIKeyMiddleware(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
-	req := httptest.NewRequest("GET", "/health", nil)
 	// no x-api-key header
+	req := httptest.NewRequest("GET", "/health", nil)
 	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req.WithContext(WithPublic(req.Context())))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 (public route), got %d", rr.Code)
+	}
 
+	// The path alone makes nothing public
+	rr = httptest.NewRecorder()
 	handler.ServeHTTP(rr, req)
 
-	if rr.Code != http.StatusOK {
-		t.Fatalf("expected 200 (health bypass), got %d", rr.Code)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for unmarked /health, got %d", rr.Code)
+	}
+}
+
+func TestAPIKeyLifecycleReasons(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		key    APIKey
+		code   int
+		reason string
+	}{
+		"valid window": {APIKey{NotBefore: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}, http.StatusOK, ""},
+		"expired":      {APIKey{ExpiresAt: now}, http.StatusUnauthorized, "key expired"},
+		"not yet":      {APIKey{NotBefore: now.Add(time.Minute)}, http.StatusUnauthorized, "key not yet valid"},
+		"revoked":      {APIKey{Revoked: true}, http.StatusUnauthorized, "key revoked"},
+		"revoked wins": {APIKey{Revoked: true, ExpiresAt: now.Add(-time.Hour)}, http.StatusUnauthorized, "key revoked"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			record := tc.key
+			record.ID, record.Key, record.Roles = "k", "secret-key", []string{"user"}
+
+			handler := Middleware(APIKeyAuthenticator{
+				Store: &mockStore{key: &record},
+				Now:   func() time.Time { return now },
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/api", nil)
+			req.Header.Set("X-API-Key", "secret-key")
+			req = req.WithContext(audit.WithReason(req.Context()))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.code {
+				t.Fatalf("expected %d, got %d", tc.code, rr.Code)
+			}
+			reason, _ := audit.Reason(req.Context())
+			if reason != tc.reason {
+				t.Fatalf("expected audit reason %q, got %q", tc.reason, reason)
+			}
+			if rr.Code != http.StatusOK && strings.Contains(rr.Body.String(), tc.reason) {
+				t.Fatal("lifecycle reason must not be revealed to the client")
+			}
+		})
+	}
+}
+
+func TestLifecycleNotCheckedForWrongKey(t *testing.T) {
+	record := &APIKey{ID: "k", Key: "secret-key", Roles: []string{"user"}, Revoked: true}
+	a := APIKeyAuthenticator{Store: MapStore{"guess": record}}
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("X-API-Key", "guess")
+
+	// A caller without the key must not learn that it was revoked
+	if _, err := a.Authenticate(req); err == nil || errors.Is(err, ErrKeyRevoked) {
+		t.Fatalf("expected generic invalid key error, got %v", err)
 	}
 }
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"Zero-TrustAPIGateWayServer/internal/audit"
+)
+
+/*
+COMPOSITE AUTHENTICATION
+
+- Schemes are tried in the configured order (e.g. Bearer JWT, then X-API-Key)
+- A scheme whose credentials are ABSENT is skipped
+- A scheme whose credentials are PRESENT but invalid fails the request;
+  we never fall back to a weaker scheme after a bad credential
+- Every failure gets the same 401 body and the same WWW-Authenticate
+  challenges, so responses do not reveal which check failed; the real
+  reason is recorded for the audit log only
+*/
+
+// ErrNoCredentials means the request carries no credentials for a scheme.
+var ErrNoCredentials = errors.New("no credentials for scheme")
+
+// Authenticator verifies a single credential scheme.
+type Authenticator interface {
+	// Authenticate returns the caller identity, ErrNoCredentials if the
+	// scheme's credentials are absent, or another error if they are invalid.
+	Authenticate(r *http.Request) (*Identity, error)
+
+	// Challenge is the WWW-Authenticate value advertised on 401.
+	Challenge() string
+}
+
+// Realm advertised in WWW-Authenticate challenges.
+const Realm = "zero-trust-gateway"
+
+// Middleware authenticates requests using the given schemes, in order.
+// With no schemes, every request is rejected.
+func Middleware(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			// Public routes are decided by policy, before us
+			if IsPublic(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for _, a := range authenticators {
+				id, err := a.Authenticate(r)
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+				if err != nil || id == nil {
+					if err != nil {
+						audit.SetReason(r.Context(), err.Error())
+					}
+					unauthorized(w, authenticators)
+					return
+				}
+
+				ctx := WithIdentity(r.Context(), id)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			// No scheme had credentials => deny
+			audit.SetReason(r.Context(), "missing credentials")
+			unauthorized(w, authenticators)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, authenticators []Authenticator) {
+	for _, a := range authenticators {
+		w.Header().Add("WWW-Authenticate", a.Challenge())
+	}
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newCompositeHandler(t *testing.T, key *rsa.PrivateKey, reached *Identity) http.Handler {
+	t.Helper()
+
+	store := &mockStore{key: &APIKey{ID: "svc-key", Key: "valid-key", Roles: []string{"user"}}}
+
+	return Middleware(
+		JWTAuthenticator{Config: singleIssuer(IssuerConfig{Issuer: "test-idp", Audiences: []string{"gateway"}, PublicKey: &key.PublicKey})},
+		APIKeyAuthenticator{Store: store},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := FromContext(r.Context())
+		*reached = *id
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestCompositeAcceptsJWT(t *testing.T) {
+	key := newRSAKey(t)
+	var got Identity
+	handler := newCompositeHandler(t, key, &got)
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("Authorization", "Bearer "+sign(t, jwt.SigningMethodRS256, key, "", validClaims()))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if got.Type != AuthJWT || got.Subject != "alice" {
+		t.Fatalf("expected JWT identity alice, got %+v", got)
+	}
+}
+
+func TestCompositeFallsBackToAPIKey(t *testing.T) {
+	key := newRSAKey(t)
+	var got Identity
+	handler := newCompositeHandler(t, key, &got)
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("X-API-Key", "valid-key")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if got.Type != AuthAPIKey || got.Subject != "svc-key" {
+		t.Fatalf("expected API key identity, got %+v", got)
+	}
+}
+
+func TestCompositeInvalidJWTDoesNotFallBack(t *testing.T) {
+	key := newRSAKey(t)
+	var got Identity
+	handler := newCompositeHandler(t, key, &got)
+
+	other := newRSAKey(t)
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("Authorization", "Bearer "+sign(t, jwt.SigningMethodRS256, other, "", validClaims()))
+	req.Header.Set("X-API-Key", "valid-key")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for bad token even with valid API key, got %d", rr.Code)
+	}
+}
+
+func TestCompositeConsistent401(t *testing.T) {
+	key := newRSAKey(t)
+	var got Identity
+	handler := newCompositeHandler(t, key, &got)
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	expiredToken := sign(t, jwt.SigningMethodRS256, key, "", expired)
+
+	requests := map[string]func(*http.Request){
+		"no credentials": func(*http.Request) {},
+		"bad api key":    func(r *http.Request) { r.Header.Set("X-API-Key", "nope") },
+		"expired token":  func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+expiredToken) },
+		"garbage token":  func(r *http.Request) { r.Header.Set("Authorization", "Bearer not.a.jwt") },
+	}
+
+	for name, mutate := range requests {
+		req := httptest.NewRequest("GET", "/api", nil)
+		mutate(req)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected 401, got %d", name, rr.Code)
+		}
+		if rr.Body.String() != "unauthorized\n" {
+			t.Fatalf("%s: expected uniform body, got %q", name, rr.Body.String())
+		}
+		challenges := rr.Header().Values("WWW-Authenticate")
+		if len(challenges) != 2 || challenges[0] != `Bearer realm="zero-trust-gateway"` {
+			t.Fatalf("%s: unexpected challenges %v", name, challenges)
+		}
+	}
+}
+
+func TestJWTRejectsWrongAlgorithm(t *testing.T) {
+	key := newRSAKey(t)
+
+	cfg := singleIssuer(IssuerConfig{Issuer: "test-idp", Audiences: []string{"gateway"}, PublicKey: &key.PublicKey})
+	token := sign(t, jwt.SigningMethodHS256, []byte("secret"), "", validClaims())
+
+	if code, _ := authenticate(t, cfg, token); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", code)
+	}
+}
@@ -1,6 +1,9 @@
 package auth
 
-import "context"
+import (
+	"context"
+	"strings"
+)
 
 // AuthType represents how the request was authenticated.
 type AuthType string
@@ -8,20 +11,53 @@ type AuthType string
 const (
 	AuthJWT    AuthType = "jwt"
 	AuthAPIKey AuthType = "api_key"
+	AuthMTLS   AuthType = "mtls"
 )
 
 // Identity represents the authenticated caller.
 type Identity struct {
 	Type     AuthType
-	Subject  string   // JWT sub OR API key ID
+	Subject  string   // JWT sub, API key ID, or cert SPIFFE ID / CN
 	Roles    []string // extracted roles
-	Issuer   string   // JWT issuer (empty for API keys)
+	Issuer   string   // JWT issuer or cert issuer CN (empty for API keys)
 	Audience string   // JWT audience (empty for API keys)
+
+	// KeyFingerprint identifies the API key secret that authenticated
+	// (empty for other schemes), so a rotated key can be told apart
+	// from its successor under the same ID.
+	KeyFingerprint string
+
+	// Claims holds the verified JWT claims (nil for other schemes).
+	Claims map[string]interface{}
+}
+
+// Claim looks up a verified JWT claim by dotted path
+// (e.g. "realm_access.roles").
+func (id *Identity) Claim(path string) (interface{}, bool) {
+	return lookupClaim(id.Claims, path)
+}
+
+func lookupClaim(claims map[string]interface{}, path string) (interface{}, bool) {
+	var raw interface{} = claims
+	for _, segment := range strings.Split(path, ".") {
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		raw, ok = obj[segment]
+		if !ok {
+			return nil, false
+		}
+	}
+	return raw, true
 }
 
 type contextKey string
 
-const identityKey contextKey = "auth_identity"
+const (
+	identityKey contextKey = "auth_identity"
+	publicKey   contextKey = "auth_public"
+)
 
 // WithIdentity attaches identity to request context.
 func WithIdentity(ctx context.Context, id *Identity) context.Context {
@@ -33,3 +69,15 @@ func FromContext(ctx context.Context) (*Identity, bool) {
 	id, ok := ctx.Value(identityKey).(*Identity)
 	return id, ok
 }
+
+// WithPublic marks the request as needing no authentication. Only the
+// policy layer sets it, from the policy file's public routes.
+func WithPublic(ctx context.Context) context.Context {
+	return context.WithValue(ctx, publicKey, true)
+}
+
+// IsPublic reports whether the request was marked public.
+func IsPublic(ctx context.Context) bool {
+	public, _ := ctx.Value(publicKey).(bool)
+	return public
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+/*
+Shared test helpers: keys, tokens, and running a token through the
+JWT middleware.
+*/
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   "test-idp",
+		"aud":   "gateway",
+		"sub":   "alice",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"admin"},
+	}
+}
+
+// sign returns a token over claims; kid is omitted when empty.
+func sign(t *testing.T, method jwt.SigningMethod, key crypto.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func singleIssuer(ic IssuerConfig) JWTConfig {
+	return JWTConfig{Issuers: []IssuerConfig{ic}}
+}
+
+// testIssuer is the issuer validClaims are for, verified with keys.
+func testIssuer(keys KeySource) JWTConfig {
+	return singleIssuer(IssuerConfig{Issuer: "test-idp", Audiences: []string{"gateway"}, Keys: keys})
+}
+
+// authenticate sends token through JWTMiddleware. It returns the status
+// and, when the request got through, the identity it carried.
+func authenticate(t *testing.T, cfg JWTConfig, token string) (int, *Identity) {
+	t.Helper()
+
+	var id *Identity
+	handler := JWTMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ = FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr.Code, id
+}
@@ -0,0 +1,377 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+JWKS KEY DISCOVERY
+
+- Keys come from a JSON Web Key Set, read from a local file or fetched
+  over HTTP(S)
+- Tokens select their key with the `kid` header
+- The key set is cached for a TTL, and refetched early when a token
+  names an unknown kid (signing key rotation)
+- Refetches run in the background, one at a time. A stale set keeps
+  being served while it refreshes, so a slow IdP never holds up
+  requests whose key is cached. Only a token whose kid is not in the
+  set waits for the refetch (there is no key to verify it with)
+- Refetches are rate limited, so a flood of tokens with random kids
+  cannot turn the gateway into a DoS amplifier against the IdP
+- A failed refresh NEVER discards the last good key set
+- A key that declares an `alg` only verifies tokens signed with it
+*/
+
+// KeySource resolves the verification key for a token.
+type KeySource interface {
+	// Key returns the public key for kid. kid may be empty if the
+	// token carries none. alg is the token's alg header; a source that
+	// knows a key's algorithm rejects any other.
+	Key(kid, alg string) (crypto.PublicKey, error)
+}
+
+// StaticKey is a KeySource holding a single key, used for every kid.
+type StaticKey struct {
+	PublicKey crypto.PublicKey
+}
+
+// Key implements KeySource.
+func (s StaticKey) Key(string, string) (crypto.PublicKey, error) {
+	if s.PublicKey == nil {
+		return nil, errors.New("no verification key configured")
+	}
+	return s.PublicKey, nil
+}
+
+// Defaults applied to zero values in JWKSConfig.
+const (
+	DefaultJWKSTTL          = 5 * time.Minute
+	DefaultJWKSMinRefresh   = 30 * time.Second
+	DefaultJWKSFetchTimeout = 5 * time.Second
+	maxJWKSBytes            = 1 << 20
+)
+
+// JWKSConfig selects where the key set comes from. Exactly one of URL
+// or File must be set.
+type JWKSConfig struct {
+	URL  string
+	File string
+
+	TTL                time.Duration // how long a fetched set is fresh
+	MinRefreshInterval time.Duration // minimum gap between fetches
+	Client             *http.Client  // optional, for URL sources
+}
+
+// JWKS is a cached, self-refreshing KeySource.
+type JWKS struct {
+	cfg JWKSConfig
+
+	current atomic.Pointer[keySet]
+
+	mu          sync.Mutex // guards lastAttempt and refreshing
+	lastAttempt time.Time
+	refreshing  chan struct{} // closed when the running refetch ends
+
+	now func() time.Time
+}
+
+type keySet struct {
+	keys      map[string]jwkKey
+	fetchedAt time.Time
+}
+
+// jwkKey is a parsed key and the algorithm it declares, if any.
+type jwkKey struct {
+	pub crypto.PublicKey
+	alg string
+}
+
+// NewJWKS creates the key source and performs the initial fetch.
+// Startup fails closed: without an initial key set, no error is swallowed.
+func NewJWKS(cfg JWKSConfig) (*JWKS, error) {
+	if (cfg.URL == "") == (cfg.File == "") {
+		return nil, errors.New("jwks: exactly one of URL or File is required")
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultJWKSTTL
+	}
+	if cfg.MinRefreshInterval <= 0 {
+		cfg.MinRefreshInterval = DefaultJWKSMinRefresh
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: DefaultJWKSFetchTimeout}
+	}
+
+	j := &JWKS{cfg: cfg, now: time.Now}
+
+	set, err := j.fetch()
+	if err != nil {
+		return nil, err
+	}
+	j.current.Store(set)
+	j.lastAttempt = j.now()
+
+	return j, nil
+}
+
+// Key implements KeySource.
+func (j *JWKS) Key(kid, alg string) (crypto.PublicKey, error) {
+	set := j.current.Load()
+
+	// Stale set: refresh in the background and keep serving this one
+	if j.now().Sub(set.fetchedAt) > j.cfg.TTL {
+		j.refresh()
+	}
+
+	key, ok := set.lookup(kid)
+	if !ok {
+		// Unknown kid: the IdP may have rotated its signing key
+		if done := j.refresh(); done != nil {
+			<-done
+		}
+		if key, ok = j.current.Load().lookup(kid); !ok {
+			return nil, fmt.Errorf("jwks: no key for kid %q", kid)
+		}
+	}
+
+	if key.alg != "" && key.alg != alg {
+		return nil, fmt.Errorf("jwks: key %q is for %s, token uses %s", kid, key.alg, alg)
+	}
+	return key.pub, nil
+}
+
+func (s *keySet) lookup(kid string) (jwkKey, bool) {
+	if kid == "" {
+		// Without a kid we only accept an unambiguous single-key set
+		if len(s.keys) != 1 {
+			return jwkKey{}, false
+		}
+		for _, k := range s.keys {
+			return k, true
+		}
+	}
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+// refresh starts a background refetch unless one is running or one
+// started recently. It returns a channel closed when the running
+// refetch ends, or nil if none is running.
+func (j *JWKS) refresh() <-chan struct{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.refreshing != nil {
+		return j.refreshing
+	}
+
+	now := j.now()
+	if now.Sub(j.lastAttempt) < j.cfg.MinRefreshInterval {
+		return nil
+	}
+	j.lastAttempt = now
+
+	done := make(chan struct{})
+	j.refreshing = done
+
+	go func() {
+		set, err := j.fetch()
+		if err != nil {
+			log.Printf("jwks refresh failed, keeping last good key set: %v", err)
+		} else {
+			j.current.Store(set)
+		}
+
+		j.mu.Lock()
+		j.refreshing = nil
+		j.mu.Unlock()
+		close(done)
+	}()
+
+	return done
+}
+
+func (j *JWKS) fetch() (*keySet, error) {
+	data, err := j.read()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keySet{keys: keys, fetchedAt: j.now()}, nil
+}
+
+func (j *JWKS) read() ([]byte, error) {
+	if j.cfg.File != "" {
+		data, err := os.ReadFile(j.cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("jwks: failed to read file: %w", err)
+		}
+		return data, nil
+	}
+
+	resp, err := j.cfg.Client.Get(j.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: fetch failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: fetch returned status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSBytes))
+}
+
+/*
+JWK parsing
+*/
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC (P-256) and OKP (Ed25519)
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS decodes a key set. Keys that are not for signatures or of
+// an unsupported type are skipped; a set with no usable keys is an error.
+func parseJWKS(data []byte) (map[string]jwkKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("jwks: invalid JSON: %w", err)
+	}
+
+	keys := make(map[string]jwkKey)
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		pub, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks: key %q: %w", k.Kid, err)
+		}
+		if pub == nil {
+			continue
+		}
+
+		if k.Alg != "" && !IsSupportedAlgorithm(k.Alg) {
+			continue
+		}
+
+		if _, dup := keys[k.Kid]; dup {
+			return nil, fmt.Errorf("jwks: duplicate kid %q", k.Kid)
+		}
+		keys[k.Kid] = jwkKey{pub: pub, alg: k.Alg}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwks: no usable signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey returns (nil, nil) for key types we do not support.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		if n.BitLen() < 2048 {
+			return nil, errors.New("RSA key shorter than 2048 bits")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeFixed(k.X, 32)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeFixed(k.Y, 32)
+		if err != nil {
+			return nil, err
+		}
+		// Uncompressed SEC 1 point; ParseUncompressedPublicKey checks it is on the curve
+		point := append([]byte{4}, append(x, y...)...)
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := decodeFixed(k.X, ed25519.PublicKeySize)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func decodeFixed(s string, size int) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid base64url key parameter")
+	}
+	if len(b) != size {
+		return nil, errors.New("key parameter has wrong length")
+	}
+	return b, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing key parameter")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid base64url key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,346 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+/*
+Test IdP: serves a JWKS that the test can rotate or break.
+*/
+
+type testIdP struct {
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	failing bool
+	hang    chan struct{} // if set, fetches wait until it is closed
+	fetches atomic.Int64
+	srv     *httptest.Server
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+
+	idp := &testIdP{keys: map[string]*rsa.PrivateKey{}}
+	idp.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idp.fetches.Add(1)
+
+		idp.mu.Lock()
+		hang := idp.hang
+		idp.mu.Unlock()
+		if hang != nil {
+			<-hang
+		}
+
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+
+		if idp.failing {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jwksDocument(idp.keys))
+	}))
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+func (idp *testIdP) setKeys(keys map[string]*rsa.PrivateKey) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.keys = keys
+}
+
+func (idp *testIdP) setFailing(f bool) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.failing = f
+}
+
+func jwksDocument(keys map[string]*rsa.PrivateKey) []byte {
+	var doc struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, k := range keys {
+		doc.Keys = append(doc.Keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		})
+	}
+	data, _ := json.Marshal(doc)
+	return data
+}
+
+// waitRefresh waits for a background refetch, if one is running.
+func waitRefresh(j *JWKS) {
+	j.mu.Lock()
+	done := j.refreshing
+	j.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+func TestJWKSSelectsKeyByKid(t *testing.T) {
+	k1, k2 := newRSAKey(t), newRSAKey(t)
+	idp := newTestIdP(t)
+	idp.setKeys(map[string]*rsa.PrivateKey{"k1": k1, "k2": k2})
+
+	jwks, err := NewJWKS(JWKSConfig{URL: idp.srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, k1, "k1", validClaims())); code != http.StatusOK {
+		t.Fatalf("k1: expected 200, got %d", code)
+	}
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, k2, "k2", validClaims())); code != http.StatusOK {
+		t.Fatalf("k2: expected 200, got %d", code)
+	}
+	// Right kid, wrong key
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, k2, "k1", validClaims())); code != http.StatusUnauthorized {
+		t.Fatalf("mismatched kid: expected 401, got %d", code)
+	}
+}
+
+func TestJWKSRefreshesOnUnknownKid(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	idp := newTestIdP(t)
+	idp.setKeys(map[string]*rsa.PrivateKey{"old": oldKey})
+
+	jwks, err := NewJWKS(JWKSConfig{URL: idp.srv.URL, MinRefreshInterval: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	jwks.now = func() time.Time { return now }
+
+	// IdP rotates
+	idp.setKeys(map[string]*rsa.PrivateKey{"new": newKey})
+
+	// Too soon after the initial fetch: refetch is rate limited
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, newKey, "new", validClaims())); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 while rate limited, got %d", code)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, newKey, "new", validClaims())); code != http.StatusOK {
+		t.Fatalf("expected 200 after rotation, got %d", code)
+	}
+}
+
+func TestJWKSRateLimitsUnknownKidFetches(t *testing.T) {
+	key := newRSAKey(t)
+	idp := newTestIdP(t)
+	idp.setKeys(map[string]*rsa.PrivateKey{"k1": key})
+
+	jwks, err := NewJWKS(JWKSConfig{URL: idp.srv.URL, MinRefreshInterval: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Add(time.Hour)
+	jwks.now = func() time.Time { return now }
+
+	for i := 0; i < 20; i++ {
+		authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, key, "random-"+string(rune('a'+i)), validClaims()))
+	}
+
+	// Initial fetch + one refetch
+	if got := idp.fetches.Load(); got != 2 {
+		t.Fatalf("expected 2 fetches, got %d", got)
+	}
+}
+
+func TestJWKSKeepsLastGoodSetOnFailure(t *testing.T) {
+	key := newRSAKey(t)
+	idp := newTestIdP(t)
+	idp.setKeys(map[string]*rsa.PrivateKey{"k1": key})
+
+	jwks, err := NewJWKS(JWKSConfig{URL: idp.srv.URL, TTL: time.Minute, MinRefreshInterval: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	jwks.now = func() time.Time { return now }
+
+	idp.setFailing(true)
+	now = now.Add(time.Hour) // set is stale, refresh will fail
+
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, key, "k1", validClaims())); code != http.StatusOK {
+		t.Fatalf("expected last good key to keep working, got %d", code)
+	}
+	waitRefresh(jwks)
+	if idp.fetches.Load() != 2 {
+		t.Fatalf("expected a refresh attempt, got %d fetches", idp.fetches.Load())
+	}
+
+	// An empty set is also a failed refresh, not a valid rotation
+	idp.setFailing(false)
+	idp.setKeys(map[string]*rsa.PrivateKey{})
+	now = now.Add(time.Hour)
+
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, key, "k1", validClaims())); code != http.StatusOK {
+		t.Fatalf("expected last good key after empty set, got %d", code)
+	}
+	waitRefresh(jwks)
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, key, "k1", validClaims())); code != http.StatusOK {
+		t.Fatalf("expected last good key after failed refresh, got %d", code)
+	}
+}
+
+func TestJWKSRefreshesAfterTTL(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	idp := newTestIdP(t)
+	idp.setKeys(map[string]*rsa.PrivateKey{"k1": oldKey})
+
+	jwks, err := NewJWKS(JWKSConfig{URL: idp.srv.URL, TTL: time.Minute, MinRefreshInterval: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	jwks.now = func() time.Time { return now }
+
+	// Same kid, new key material: only a TTL refresh can pick this up
+	idp.setKeys(map[string]*rsa.PrivateKey{"k1": newKey})
+	now = now.Add(2 * time.Minute)
+
+	// The stale set is served while it refreshes in the background
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, oldKey, "k1", validClaims())); code != http.StatusOK {
+		t.Fatalf("expected stale set served during refresh, got %d", code)
+	}
+	waitRefresh(jwks)
+
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, newKey, "k1", validClaims())); code != http.StatusOK {
+		t.Fatalf("expected 200 after TTL refresh, got %d", code)
+	}
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, oldKey, "k1", validClaims())); code != http.StatusUnauthorized {
+		t.Fatalf("expected old key rejected, got %d", code)
+	}
+}
+
+func TestJWKSSlowIdPDoesNotBlockCachedKeys(t *testing.T) {
+	key := newRSAKey(t)
+	idp := newTestIdP(t)
+	idp.setKeys(map[string]*rsa.PrivateKey{"k1": key})
+
+	jwks, err := NewJWKS(JWKSConfig{URL: idp.srv.URL, TTL: time.Minute, MinRefreshInterval: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Add(time.Hour) // stale
+	jwks.now = func() time.Time { return now }
+
+	// The IdP hangs until released
+	release := make(chan struct{})
+	idp.mu.Lock()
+	idp.hang = release
+	idp.mu.Unlock()
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, key, "k1", validClaims()))
+			codes <- code
+		}()
+	}
+
+	finished := make(chan struct{})
+	go func() { wg.Wait(); close(finished) }()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("requests with a cached key waited for the IdP")
+	}
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("expected 200 from the cached key, got %d", code)
+		}
+	}
+
+	close(release)
+	waitRefresh(jwks)
+	if got := idp.fetches.Load(); got != 2 {
+		t.Fatalf("expected a single background refetch, got %d fetches", got)
+	}
+}
+
+func TestJWKSEnforcesDeclaredAlgorithm(t *testing.T) {
+	key := newRSAKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksDocument(map[string]*rsa.PrivateKey{"k1": key}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	jwks, err := NewJWKS(JWKSConfig{File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testIssuer(jwks)
+	cfg.Issuers[0].Algorithms = []string{"RS256", "PS256"}
+
+	if code, _ := authenticate(t, cfg, sign(t, jwt.SigningMethodRS256, key, "k1", validClaims())); code != http.StatusOK {
+		t.Fatalf("RS256: expected 200, got %d", code)
+	}
+	// PS256 is allowed for the issuer and fits an RSA key, but the
+	// key is declared RS256
+	if code, _ := authenticate(t, cfg, sign(t, jwt.SigningMethodPS256, key, "k1", validClaims())); code != http.StatusUnauthorized {
+		t.Fatalf("PS256: expected 401, got %d", code)
+	}
+}
+
+func TestJWKSFromFile(t *testing.T) {
+	key := newRSAKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksDocument(map[string]*rsa.PrivateKey{"file-key": key}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	jwks, err := NewJWKS(JWKSConfig{File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, key, "file-key", validClaims())); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	// A single-key set also serves tokens without a kid
+	if code, _ := authenticate(t, testIssuer(jwks), sign(t, jwt.SigningMethodRS256, key, "", validClaims())); code != http.StatusOK {
+		t.Fatalf("expected 200 without kid, got %d", code)
+	}
+}
+
+func TestJWKSInitialFetchFailsClosed(t *testing.T) {
+	idp := newTestIdP(t)
+	idp.setFailing(true)
+
+	if _, err := NewJWKS(JWKSConfig{URL: idp.srv.URL}); err == nil {
+		t.Fatal("expected initial fetch failure to be an error")
+	}
+}
@@ -1,8 +1,8 @@
 package auth
 
 import (
-	"crypto/rsa"
-	"fmt"
+	"crypto"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -12,117 +12,268 @@ import (
 
 /*
 SECURITY NOTES:
-- RS256 only (explicitly enforced)
-- No "alg=none"
+- One or more trusted issuers, each with its own keys, audiences,
+  algorithm allow-list, clock-skew leeway and roles claim
+- The issuer is selected by exact `iss` match BEFORE verification;
+  an unknown issuer is rejected without touching any key
+- Explicit per-issuer algorithm allow-list (see algorithms.go)
+- Key type must match the algorithm
+- Verification key from a static key or a JWKS (selected by kid)
+- No "alg=none", no HMAC
 - No token issuance
 - Claims are validated manually and explicitly
 */
 
+// DefaultRolesClaim is used when IssuerConfig.RolesClaim is empty.
+const DefaultRolesClaim = "roles"
+
+// MaxLeeway caps clock-skew tolerance; anything larger stops
+// expiry from meaning much.
+const MaxLeeway = 5 * time.Minute
+
+// IssuerConfig describes one trusted token issuer.
+type IssuerConfig struct {
+	Issuer     string
+	Audiences  []string         // token aud (string or array) must contain one of these
+	PublicKey  crypto.PublicKey // single static key (used when Keys is nil)
+	Keys       KeySource        // e.g. JWKS; selected by the token's kid
+	Algorithms []string         // allow-list; empty = DefaultAlgorithms
+	Leeway     time.Duration    // clock skew tolerated for exp, nbf and iat
+	RolesClaim string           // dotted claim path, e.g. realm_access.roles; empty = DefaultRolesClaim
+}
+
+func (c IssuerConfig) keySource() KeySource {
+	if c.Keys != nil {
+		return c.Keys
+	}
+	return StaticKey{PublicKey: c.PublicKey}
+}
+
+// JWTConfig lists the trusted issuers.
 type JWTConfig struct {
-	Issuer    string
-	Audience  string
-	PublicKey *rsa.PublicKey
+	Issuers []IssuerConfig
+
+	// Now is used for time-based claims; nil = time.Now (tests only)
+	Now func() time.Time
 }
 
-func JWTMiddleware(cfg JWTConfig) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+func (c JWTConfig) issuer(iss string) (IssuerConfig, bool) {
+	for _, ic := range c.Issuers {
+		if ic.Issuer == iss {
+			return ic, true
+		}
+	}
+	return IssuerConfig{}, false
+}
 
-			if r.URL.Path == "/health" {
-				next.ServeHTTP(w, r)
-				return
-			}
+// JWTAuthenticator authenticates "Authorization: Bearer <token>".
+type JWTAuthenticator struct {
+	Config JWTConfig
+}
 
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				http.Error(w, "missing Authorization header", http.StatusUnauthorized)
-				return
-			}
+// Authenticate implements Authenticator.
+func (a JWTAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	cfg := a.Config
 
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Error(w, "invalid Authorization header format", http.StatusUnauthorized)
-				return
-			}
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, ErrNoCredentials
+	}
 
-			tokenStr := parts[1]
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		// Some other Authorization scheme: not ours
+		return nil, ErrNoCredentials
+	}
 
-			token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-				// Explicitly enforce RS256
-				if t.Method.Alg() != jwt.SigningMethodRS256.Alg() {
-					return nil, fmt.Errorf("unexpected signing method")
-				}
-				return cfg.PublicKey, nil
-			})
+	tokenStr := parts[1]
 
-			if err != nil || !token.Valid {
-				http.Error(w, "invalid token", http.StatusUnauthorized)
-				return
-			}
+	// Select the issuer from the (not yet trusted) iss claim.
+	// Nothing from the unverified token is used beyond this lookup.
+	unverified, _, err := jwt.NewParser().ParseUnverified(tokenStr, jwt.MapClaims{})
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+	iss, _ := unverified.Claims.(jwt.MapClaims)["iss"].(string)
 
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
-				http.Error(w, "invalid token claims", http.StatusUnauthorized)
-				return
-			}
+	ic, ok := cfg.issuer(iss)
+	if !ok || iss == "" {
+		return nil, errors.New("invalid token issuer")
+	}
 
-			// Validate issuer
-			if claims["iss"] != cfg.Issuer {
-				http.Error(w, "invalid token issuer", http.StatusUnauthorized)
-				return
-			}
+	keys := ic.keySource()
+	algs := allowedAlgorithms(ic.Algorithms)
+	if len(algs) == 0 {
+		return nil, errors.New("no signing algorithms allowed")
+	}
 
-			// Validate audience
-			if aud, ok := claims["aud"].(string); !ok || aud != cfg.Audience {
-				http.Error(w, "invalid token audience", http.StatusUnauthorized)
-				return
-			}
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := keys.Key(kid, t.Method.Alg())
+		if err != nil {
+			return nil, err
+		}
+
+		// The key decides what it can verify, not the token
+		if err := checkKeyForAlgorithm(t.Method.Alg(), key); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}, jwt.WithValidMethods(algs), jwt.WithoutClaimsValidation())
+
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+
+	// Validate issuer (verified claim must still be the one we selected)
+	if claims["iss"] != ic.Issuer {
+		return nil, errors.New("invalid token issuer")
+	}
+
+	// Validate audience (string or array, RFC 7519 §4.1.3)
+	aud, ok := matchAudience(claims["aud"], ic.Audiences)
+	if !ok {
+		return nil, errors.New("invalid token audience")
+	}
 
-			// Validate expiration
-			exp, ok := claims["exp"].(float64)
-			if !ok || time.Now().Unix() > int64(exp) {
-				http.Error(w, "token expired", http.StatusUnauthorized)
-				return
+	if err := validateTimes(claims, cfg.now(), ic.Leeway); err != nil {
+		return nil, err
+	}
+
+	sub, ok := claims["sub"].(string)
+	if !ok || sub == "" {
+		return nil, errors.New("token subject missing")
+	}
+
+	rolesClaim := ic.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = DefaultRolesClaim
+	}
+
+	return &Identity{
+		Type:     AuthJWT,
+		Subject:  sub,
+		Roles:    extractRoles(claims, rolesClaim),
+		Issuer:   ic.Issuer,
+		Audience: aud,
+		Claims:   claims,
+	}, nil
+}
+
+// Challenge implements Authenticator.
+func (JWTAuthenticator) Challenge() string {
+	return `Bearer realm="` + Realm + `"`
+}
+
+// JWTMiddleware authenticates with bearer JWTs only.
+func JWTMiddleware(cfg JWTConfig) func(http.Handler) http.Handler {
+	return Middleware(JWTAuthenticator{Config: cfg})
+}
+
+func (c JWTConfig) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+/*
+Claim helpers
+*/
+
+// matchAudience returns the first accepted audience found in aud.
+func matchAudience(raw interface{}, accepted []string) (string, bool) {
+	var auds []string
+	switch v := raw.(type) {
+	case string:
+		auds = []string{v}
+	case []interface{}:
+		for _, a := range v {
+			s, ok := a.(string)
+			if !ok {
+				// A malformed array is rejected as a whole
+				return "", false
 			}
+			auds = append(auds, s)
+		}
+	default:
+		return "", false
+	}
 
-			sub, ok := claims["sub"].(string)
-			if !ok || sub == "" {
-				http.Error(w, "token subject missing", http.StatusUnauthorized)
-				return
+	for _, a := range auds {
+		for _, want := range accepted {
+			if a != "" && a == want {
+				return a, true
 			}
+		}
+	}
+	return "", false
+}
 
-			roles := extractRoles(claims)
+// validateTimes checks exp (required), nbf and iat (if present).
+func validateTimes(claims jwt.MapClaims, now time.Time, leeway time.Duration) error {
+	if leeway < 0 || leeway > MaxLeeway {
+		leeway = 0
+	}
 
-			id := &Identity{
-				Type:     AuthJWT,
-				Subject:  sub,
-				Roles:    roles,
-				Issuer:   cfg.Issuer,
-				Audience: cfg.Audience,
-			}
+	exp, ok := numericDate(claims, "exp")
+	if !ok {
+		return errors.New("token expiry missing")
+	}
+	if !now.Before(exp.Add(leeway)) {
+		return errors.New("token expired")
+	}
 
-			ctx := WithIdentity(r.Context(), id)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		})
+	if _, present := claims["nbf"]; present {
+		nbf, ok := numericDate(claims, "nbf")
+		if !ok || now.Add(leeway).Before(nbf) {
+			return errors.New("token not yet valid")
+		}
+	}
+
+	if _, present := claims["iat"]; present {
+		iat, ok := numericDate(claims, "iat")
+		if !ok || now.Add(leeway).Before(iat) {
+			return errors.New("token issued in the future")
+		}
 	}
+
+	return nil
 }
 
-func extractRoles(claims jwt.MapClaims) []string {
-	raw, ok := claims["roles"]
+func numericDate(claims jwt.MapClaims, name string) (time.Time, bool) {
+	v, ok := claims[name].(float64)
 	if !ok {
-		return nil
+		return time.Time{}, false
 	}
+	sec := int64(v)
+	return time.Unix(sec, int64((v-float64(sec))*1e9)), true
+}
 
-	list, ok := raw.([]interface{})
+// extractRoles reads roles from a dotted claim path. The value may be
+// an array of strings (roles, groups) or a space-separated string (scope).
+func extractRoles(claims jwt.MapClaims, path string) []string {
+	raw, ok := lookupClaim(claims, path)
 	if !ok {
 		return nil
 	}
 
-	var roles []string
-	for _, r := range list {
-		if s, ok := r.(string); ok {
-			roles = append(roles, s)
+	switch v := raw.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		var roles []string
+		for _, r := range v {
+			if s, ok := r.(string); ok && s != "" {
+				roles = append(roles, s)
+			}
 		}
+		return roles
 	}
-	return roles
+	return nil
 }
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func claimsFor(iss string, mutate func(jwt.MapClaims)) jwt.MapClaims {
+	c := validClaims()
+	c["iss"] = iss
+	if mutate != nil {
+		mutate(c)
+	}
+	return c
+}
+
+func TestMultipleIssuersEachUseOwnKeys(t *testing.T) {
+	keyA, keyB := newRSAKey(t), newRSAKey(t)
+
+	cfg := JWTConfig{Issuers: []IssuerConfig{
+		{Issuer: "idp-a", Audiences: []string{"gateway"}, PublicKey: &keyA.PublicKey},
+		{Issuer: "idp-b", Audiences: []string{"gateway"}, PublicKey: &keyB.PublicKey},
+	}}
+
+	if code, id := authenticate(t, cfg, sign(t, jwt.SigningMethodRS256, keyA, "", claimsFor("idp-a", nil))); code != http.StatusOK || id.Issuer != "idp-a" {
+		t.Fatalf("idp-a: expected success, got %d", code)
+	}
+	if code, id := authenticate(t, cfg, sign(t, jwt.SigningMethodRS256, keyB, "", claimsFor("idp-b", nil))); code != http.StatusOK || id.Issuer != "idp-b" {
+		t.Fatalf("idp-b: expected success, got %d", code)
+	}
+
+	// Issuer B's key cannot mint tokens for issuer A
+	if code, _ := authenticate(t, cfg, sign(t, jwt.SigningMethodRS256, keyB, "", claimsFor("idp-a", nil))); code == http.StatusOK {
+		t.Fatal("expected cross-issuer signature to be rejected")
+	}
+	if code, _ := authenticate(t, cfg, sign(t, jwt.SigningMethodRS256, keyA, "", claimsFor("idp-unknown", nil))); code == http.StatusOK {
+		t.Fatal("expected unknown issuer to be rejected")
+	}
+}
+
+func TestAudienceStringOrArray(t *testing.T) {
+	key := newRSAKey(t)
+	cfg := singleIssuer(IssuerConfig{Issuer: "idp", Audiences: []string{"gateway", "gateway-v2"}, PublicKey: &key.PublicKey})
+
+	cases := []struct {
+		aud  interface{}
+		want string // "" = reject
+	}{
+		{"gateway", "gateway"},
+		{"gateway-v2", "gateway-v2"},
+		{[]string{"other", "gateway-v2"}, "gateway-v2"},
+		{[]string{"other"}, ""},
+		{"other", ""},
+		{[]interface{}{"gateway", 42}, ""},
+		{nil, ""},
+	}
+
+	for _, c := range cases {
+		claims := claimsFor("idp", func(m jwt.MapClaims) {
+			if c.aud == nil {
+				delete(m, "aud")
+			} else {
+				m["aud"] = c.aud
+			}
+		})
+
+		code, id := authenticate(t, cfg, sign(t, jwt.SigningMethodRS256, key, "", claims))
+		if c.want == "" {
+			if code == http.StatusOK {
+				t.Fatalf("aud %v: expected rejection", c.aud)
+			}
+			continue
+		}
+		if code != http.StatusOK || id.Audience != c.want {
+			t.Fatalf("aud %v: expected %q, got %d %v", c.aud, c.want, code, id)
+		}
+	}
+}
+
+func TestTimeClaimsWithLeeway(t *testing.T) {
+	key := newRSAKey(t)
+	now := time.Unix(1_700_000_000, 0)
+
+	strict := JWTConfig{
+		Issuers: []IssuerConfig{{Issuer: "idp", Audiences: []string{"gateway"}, PublicKey: &key.PublicKey}},
+		Now:     func() time.Time { return now },
+	}
+	lenient := strict
+	lenient.Issuers = []IssuerConfig{{Issuer: "idp", Audiences: []string{"gateway"}, PublicKey: &key.PublicKey, Leeway: 30 * time.Second}}
+
+	cases := []struct {
+		name            string
+		mutate          func(jwt.MapClaims)
+		strict, lenient bool // accepted?
+	}{
+		{"valid", func(m jwt.MapClaims) { m["exp"] = now.Add(time.Minute).Unix() }, true, true},
+		{"expired 10s ago", func(m jwt.MapClaims) { m["exp"] = now.Add(-10 * time.Second).Unix() }, false, true},
+		{"expired 1m ago", func(m jwt.MapClaims) { m["exp"] = now.Add(-time.Minute).Unix() }, false, false},
+		{"missing exp", func(m jwt.MapClaims) { delete(m, "exp") }, false, false},
+		{"nbf in 10s", func(m jwt.MapClaims) {
+			m["exp"] = now.Add(time.Hour).Unix()
+			m["nbf"] = now.Add(10 * time.Second).Unix()
+		}, false, true},
+		{"nbf in 1h", func(m jwt.MapClaims) {
+			m["exp"] = now.Add(2 * time.Hour).Unix()
+			m["nbf"] = now.Add(time.Hour).Unix()
+		}, false, false},
+		{"iat in future", func(m jwt.MapClaims) {
+			m["exp"] = now.Add(2 * time.Hour).Unix()
+			m["iat"] = now.Add(time.Hour).Unix()
+		}, false, false},
+		{"iat malformed", func(m jwt.MapClaims) {
+			m["exp"] = now.Add(time.Hour).Unix()
+			m["iat"] = "yesterday"
+		}, false, false},
+	}
+
+	for _, c := range cases {
+		token := sign(t, jwt.SigningMethodRS256, key, "", claimsFor("idp", c.mutate))
+
+		if code, _ := authenticate(t, strict, token); (code == http.StatusOK) != c.strict {
+			t.Fatalf("%s (no leeway): got %d, want accepted=%v", c.name, code, c.strict)
+		}
+		if code, _ := authenticate(t, lenient, token); (code == http.StatusOK) != c.lenient {
+			t.Fatalf("%s (30s leeway): got %d, want accepted=%v", c.name, code, c.lenient)
+		}
+	}
+}
+
+func TestRolesClaimPath(t *testing.T) {
+	key := newRSAKey(t)
+
+	cases := []struct {
+		path   string
+		claims map[string]interface{}
+		want   []string
+	}{
+		{"", map[string]interface{}{"roles": []string{"admin", "user"}}, []string{"admin", "user"}},
+		{"realm_access.roles", map[string]interface{}{"realm_access": map[string]interface{}{"roles": []string{"editor"}}}, []string{"editor"}},
+		{"scope", map[string]interface{}{"scope": "read:items write:items"}, []string{"read:items", "write:items"}},
+		{"groups", map[string]interface{}{"groups": []string{"ops"}, "roles": []string{"admin"}}, []string{"ops"}},
+		{"realm_access.roles", map[string]interface{}{"realm_access": "not-an-object"}, nil},
+		{"missing.path", map[string]interface{}{}, nil},
+	}
+
+	for _, c := range cases {
+		cfg := singleIssuer(IssuerConfig{Issuer: "idp", Audiences: []string{"gateway"}, PublicKey: &key.PublicKey, RolesClaim: c.path})
+
+		claims := claimsFor("idp", func(m jwt.MapClaims) {
+			delete(m, "roles")
+			for k, v := range c.claims {
+				m[k] = v
+			}
+		})
+
+		code, id := authenticate(t, cfg, sign(t, jwt.SigningMethodRS256, key, "", claims))
+		if code != http.StatusOK {
+			t.Fatalf("%q: expected 200, got %d", c.path, code)
+		}
+		if !reflect.DeepEqual(id.Roles, c.want) {
+			t.Fatalf("%q: expected roles %v, got %v", c.path, c.want, id.Roles)
+		}
+	}
+}
+
+func TestMultiIssuerMiddleware(t *testing.T) {
+	keys := map[string]*rsa.PrivateKey{"idp-a": newRSAKey(t), "idp-b": newRSAKey(t)}
+
+	var issuers []IssuerConfig
+	for iss, k := range keys {
+		issuers = append(issuers, IssuerConfig{Issuer: iss, Audiences: []string{"gateway"}, PublicKey: &k.PublicKey})
+	}
+
+	for iss, k := range keys {
+		code, id := authenticate(t, JWTConfig{Issuers: issuers}, sign(t, jwt.SigningMethodRS256, k, "", claimsFor(iss, nil)))
+		if code != http.StatusOK || id.Issuer != iss {
+			t.Fatalf("%s: expected 200, got %d", iss, code)
+		}
+	}
+}
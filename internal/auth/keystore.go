@@ -0,0 +1,342 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/fsutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+HASHED API KEY STORE
+
+Key format:  <prefix>.<secret>
+ prefix  12 hex chars, public; identifies the record (safe to log)
+ secret  64 hex chars (256 bits of entropy)
+
+The key file stores only the prefix and "sha256:<hex>" of the full key.
+The raw key is shown once when generated and never written anywhere.
+Keys are random, high-entropy values, so a fast hash is sufficient;
+there is nothing for a slow KDF to protect against.
+
+Lifecycle (not_before, expires_at, revoked) is enforced by
+APIKeyAuthenticator after the hash matches; owner and description are
+bookkeeping only.
+
+Lookup:
+ 1. Split the presented key at the first '.'
+ 2. Find the record by prefix (a map lookup on public data)
+ 3. Compare SHA-256(presented key) with the stored hash in constant time
+
+Same philosophy as the policy loader:
+ The file is validated before it is accepted
+ Unknown fields are rejected
+ Any error while (re)loading results in DENY-ALL (no key matches)
+ Records are swapped atomically on hot reload
+*/
+
+const (
+	apiKeyPrefixBytes = 6
+	apiKeySecretBytes = 32
+	apiKeyHashScheme  = "sha256:"
+)
+
+// KeyFile is the on-disk format of a hashed key store (YAML or JSON).
+type KeyFile struct {
+	Keys []KeyRecord `yaml:"keys" json:"keys"`
+}
+
+// KeyRecord is one stored key. It never contains the raw key.
+type KeyRecord struct {
+	ID     string   `yaml:"id" json:"id"`
+	Prefix string   `yaml:"prefix" json:"prefix"`
+	Hash   string   `yaml:"hash" json:"hash"` // "sha256:<64 hex>"
+	Roles  []string `yaml:"roles" json:"roles"`
+
+	NotBefore time.Time `yaml:"not_before,omitempty" json:"not_before,omitempty"` // RFC 3339
+	ExpiresAt time.Time `yaml:"expires_at,omitempty" json:"expires_at,omitempty"` // RFC 3339
+	Revoked   bool      `yaml:"revoked,omitempty" json:"revoked,omitempty"`
+
+	Owner       string `yaml:"owner,omitempty" json:"owner,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// Status describes the record's lifecycle state at now: "revoked",
+// "expired", "pending" (before not_before) or "active". The order
+// matches APIKeyAuthenticator's checks.
+func (k KeyRecord) Status(now time.Time) string {
+	switch {
+	case k.Revoked:
+		return "revoked"
+	case !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt):
+		return "expired"
+	case !k.NotBefore.IsZero() && now.Before(k.NotBefore):
+		return "pending"
+	default:
+		return "active"
+	}
+}
+
+// GenerateAPIKey creates a new random key. The raw key is returned for
+// the caller to hand out once; the record is what gets stored.
+func GenerateAPIKey(id string, roles []string) (raw string, record KeyRecord, err error) {
+	prefix := make([]byte, apiKeyPrefixBytes)
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", KeyRecord{}, fmt.Errorf("failed to generate key prefix: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", KeyRecord{}, fmt.Errorf("failed to generate key secret: %w", err)
+	}
+
+	raw = hex.EncodeToString(prefix) + "." + hex.EncodeToString(secret)
+
+	return raw, KeyRecord{
+		ID:     id,
+		Prefix: hex.EncodeToString(prefix),
+		Hash:   HashAPIKey(raw),
+		Roles:  roles,
+	}, nil
+}
+
+// HashAPIKey returns the stored form of a raw key.
+func HashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return apiKeyHashScheme + hex.EncodeToString(sum[:])
+}
+
+// APIKeyPrefix returns the public prefix of a raw key, if it has one.
+func APIKeyPrefix(raw string) (string, bool) {
+	prefix, _, ok := strings.Cut(raw, ".")
+	if !ok || prefix == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+// FileStore is an APIKeyStore backed by a hashed key file.
+// A nil record set means deny all.
+type FileStore struct {
+	current atomic.Pointer[map[string]*APIKey] // by prefix
+}
+
+// NewFileStore loads path. The store is usable (deny all) even when
+// the initial load fails.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{}
+	return s, s.LoadFromFile(path)
+}
+
+// Lookup implements APIKeyStore. It selects the record by prefix only;
+// APIKeyAuthenticator verifies the hash.
+func (s *FileStore) Lookup(key string) (*APIKey, bool) {
+	records := s.current.Load()
+	if records == nil {
+		return nil, false
+	}
+
+	prefix, ok := APIKeyPrefix(key)
+	if !ok {
+		return nil, false
+	}
+
+	record, ok := (*records)[prefix]
+	return record, ok
+}
+
+// LookupID implements APIKeyIDStore.
+func (s *FileStore) LookupID(id string) (*APIKey, bool) {
+	records := s.current.Load()
+	if records == nil {
+		return nil, false
+	}
+
+	for _, record := range *records {
+		if record.ID == id {
+			return record, true
+		}
+	}
+	return nil, false
+}
+
+// Len returns the number of loaded keys.
+func (s *FileStore) Len() int {
+	records := s.current.Load()
+	if records == nil {
+		return 0
+	}
+	return len(*records)
+}
+
+// LoadFromFile loads and validates the key file.
+func (s *FileStore) LoadFromFile(path string) error {
+	kf, err := readKeyFile(path)
+	if err != nil {
+		s.invalidate()
+		return err
+	}
+
+	records, err := compileKeyFile(kf)
+	if err != nil {
+		s.invalidate()
+		return err
+	}
+
+	s.current.Store(&records)
+	return nil
+}
+
+// Watch polls the key file for changes and reloads it.
+// On ANY error → no key is accepted (deny all).
+// The returned function stops the watcher.
+func (s *FileStore) Watch(path string, interval time.Duration) (stop func()) {
+	// On error LoadFromFile has already invalidated the keys
+	return fsutil.Watch(path, interval, s.LoadFromFile, s.invalidate)
+}
+
+func (s *FileStore) invalidate() {
+	s.current.Store(nil)
+}
+
+/*
+Key file management (used by gatewayctl)
+*/
+
+// LoadKeyFile reads and validates a key file for editing.
+func LoadKeyFile(path string) (KeyFile, error) {
+	kf, err := readKeyFile(path)
+	if err != nil {
+		return KeyFile{}, err
+	}
+	if _, err := compileKeyFile(kf); err != nil {
+		return KeyFile{}, err
+	}
+	return kf, nil
+}
+
+// SaveKeyFile validates kf and atomically replaces path with it, so a
+// watching gateway never loads a half-written file.
+func SaveKeyFile(path string, kf KeyFile) error {
+	if _, err := compileKeyFile(kf); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(kf)
+	if err != nil {
+		return err
+	}
+
+	if err := fsutil.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return nil
+}
+
+// Find returns the index of the record with the given ID, or -1.
+func (kf KeyFile) Find(id string) int {
+	for i, k := range kf.Keys {
+		if k.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func readKeyFile(path string) (KeyFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return KeyFile{}, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	// JSON is valid YAML, so one strict decoder handles both
+	var kf KeyFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&kf); err != nil {
+		// An empty file is a valid store with no keys
+		if errors.Is(err, io.EOF) {
+			return KeyFile{}, nil
+		}
+		return KeyFile{}, fmt.Errorf("invalid key file: %w", err)
+	}
+	return kf, nil
+}
+
+// compileKeyFile validates every record and indexes it by prefix.
+func compileKeyFile(kf KeyFile) (map[string]*APIKey, error) {
+	records := make(map[string]*APIKey, len(kf.Keys))
+	ids := make(map[string]bool, len(kf.Keys))
+
+	for i, k := range kf.Keys {
+		if strings.TrimSpace(k.ID) == "" {
+			return nil, keyFileError(i, "id is required")
+		}
+		if ids[k.ID] {
+			return nil, keyFileError(i, "id is duplicated")
+		}
+		ids[k.ID] = true
+
+		if !isHex(k.Prefix, 2*apiKeyPrefixBytes) {
+			return nil, keyFileError(i, "prefix must be "+strconv.Itoa(2*apiKeyPrefixBytes)+" hex characters")
+		}
+		if _, dup := records[k.Prefix]; dup {
+			return nil, keyFileError(i, "prefix is duplicated")
+		}
+
+		hash, ok := strings.CutPrefix(k.Hash, apiKeyHashScheme)
+		if !ok || !isHex(hash, 2*sha256.Size) {
+			return nil, keyFileError(i, "hash must be sha256:<64 hex characters>")
+		}
+
+		if len(k.Roles) == 0 {
+			return nil, keyFileError(i, "roles must not be empty")
+		}
+		for _, role := range k.Roles {
+			if strings.TrimSpace(role) == "" {
+				return nil, keyFileError(i, "roles must not contain empty entries")
+			}
+		}
+
+		if !k.NotBefore.IsZero() && !k.ExpiresAt.IsZero() && !k.ExpiresAt.After(k.NotBefore) {
+			return nil, keyFileError(i, "expires_at must be after not_before")
+		}
+
+		records[k.Prefix] = &APIKey{
+			ID:          k.ID,
+			Prefix:      k.Prefix,
+			Hash:        strings.ToLower(hash),
+			Roles:       k.Roles,
+			NotBefore:   k.NotBefore,
+			ExpiresAt:   k.ExpiresAt,
+			Revoked:     k.Revoked,
+			Owner:       k.Owner,
+			Description: k.Description,
+		}
+	}
+
+	return records, nil
+}
+
+func keyFileError(i int, msg string) error {
+	return errors.New("keys[" + strconv.Itoa(i) + "]: " + msg)
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,294 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeKeyFile(t *testing.T, path string, records ...KeyRecord) {
+	t.Helper()
+
+	var b strings.Builder
+	b.WriteString("keys:\n")
+	for _, r := range records {
+		b.WriteString("  - id: " + r.ID + "\n")
+		b.WriteString("    prefix: \"" + r.Prefix + "\"\n")
+		b.WriteString("    hash: " + r.Hash + "\n")
+		b.WriteString("    roles: [" + strings.Join(r.Roles, ", ") + "]\n")
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func serveKey(store APIKeyStore, key string) int {
+	handler := APIKeyMiddleware(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("X-API-Key", key)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+func TestFileStoreAcceptsHashedKey(t *testing.T) {
+	raw, record, err := GenerateAPIKey("ci-bot", []string{"deployer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	writeKeyFile(t, path, record)
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("expected load success, got %v", err)
+	}
+
+	// The raw key must never be persisted
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), raw) || strings.Contains(string(data), strings.SplitN(raw, ".", 2)[1]) {
+		t.Fatal("raw key found in key file")
+	}
+
+	if code := serveKey(store, raw); code != http.StatusOK {
+		t.Fatalf("expected 200 for valid key, got %d", code)
+	}
+
+	prefix, _ := APIKeyPrefix(raw)
+	wrongSecret := prefix + "." + strings.Repeat("0", 64)
+	for _, key := range []string{wrongSecret, "not-a-key", prefix, prefix + ".", "." + raw} {
+		if code := serveKey(store, key); code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for %q, got %d", key, code)
+		}
+	}
+}
+
+func TestFileStoreAcceptsJSON(t *testing.T) {
+	raw, record, err := GenerateAPIKey("ci-bot", []string{"deployer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	data := `{"keys": [{"id": "ci-bot", "prefix": "` + record.Prefix + `", "hash": "` + record.Hash + `", "roles": ["deployer"]}]}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("expected load success, got %v", err)
+	}
+	if code := serveKey(store, raw); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+}
+
+func TestFileStoreRejectsInvalidFile(t *testing.T) {
+	_, good, err := GenerateAPIKey("a", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string][]KeyRecord{
+		"missing id":       {{Prefix: good.Prefix, Hash: good.Hash, Roles: good.Roles}},
+		"short prefix":     {{ID: "a", Prefix: "abc", Hash: good.Hash, Roles: good.Roles}},
+		"plaintext hash":   {{ID: "a", Prefix: good.Prefix, Hash: "deadbeef", Roles: good.Roles}},
+		"unknown scheme":   {{ID: "a", Prefix: good.Prefix, Hash: "md5:" + strings.Repeat("0", 64), Roles: good.Roles}},
+		"no roles":         {{ID: "a", Prefix: good.Prefix, Hash: good.Hash}},
+		"duplicate id":     {good, {ID: "a", Prefix: "0123456789ab", Hash: good.Hash, Roles: good.Roles}},
+		"duplicate prefix": {good, {ID: "b", Prefix: good.Prefix, Hash: good.Hash, Roles: good.Roles}},
+	}
+
+	for name, records := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys.yaml")
+			writeKeyFile(t, path, records...)
+
+			store, err := NewFileStore(path)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if store.Len() != 0 {
+				t.Fatal("invalid file must leave the store empty (deny all)")
+			}
+		})
+	}
+}
+
+func TestFileStoreRejectsUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	data := "keys:\n  - id: a\n    key: plaintext\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileStore(path); err == nil {
+		t.Fatal("expected unknown field to be rejected")
+	}
+}
+
+func TestFileStoreWatchReloads(t *testing.T) {
+	rawA, recordA, _ := GenerateAPIKey("a", []string{"user"})
+	rawB, recordB, _ := GenerateAPIKey("b", []string{"user"})
+
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	writeKeyFile(t, path, recordA)
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := store.Watch(path, 10*time.Millisecond)
+	defer stop()
+
+	// Remove a, add b
+	writeKeyFile(t, path, recordB)
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for serveKey(store, rawB) != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("reload not picked up")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if code := serveKey(store, rawA); code != http.StatusUnauthorized {
+		t.Fatalf("removed key must be rejected, got %d", code)
+	}
+
+	// A broken edit denies everything rather than keeping stale keys
+	if err := os.WriteFile(path, []byte("keys: [oops"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := future.Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	for serveKey(store, rawB) != http.StatusUnauthorized {
+		if time.Now().After(deadline) {
+			t.Fatal("broken key file did not invalidate the store")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFileStoreLifecycleFields(t *testing.T) {
+	raw, record, err := GenerateAPIKey("old-bot", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	data := `{"keys": [{"id": "old-bot", "prefix": "` + record.Prefix + `", "hash": "` + record.Hash + `",
+		"roles": ["user"], "not_before": "2026-01-01T00:00:00Z", "expires_at": "2026-02-01T00:00:00Z",
+		"owner": "platform-team", "description": "nightly export"}]}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("expected load success, got %v", err)
+	}
+
+	got, ok := store.Lookup(raw)
+	if !ok || got.Owner != "platform-team" || got.ExpiresAt.IsZero() || got.NotBefore.IsZero() {
+		t.Fatalf("lifecycle fields not loaded: %+v", got)
+	}
+
+	a := APIKeyAuthenticator{Store: store, Now: func() time.Time { return got.ExpiresAt.Add(time.Second) }}
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("X-API-Key", raw)
+	if _, err := a.Authenticate(req); err != ErrKeyExpired {
+		t.Fatalf("expected ErrKeyExpired, got %v", err)
+	}
+}
+
+func TestFileStoreRejectsInvertedWindow(t *testing.T) {
+	_, record, _ := GenerateAPIKey("a", []string{"user"})
+	record.NotBefore = time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	record.ExpiresAt = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := compileKeyFile(KeyFile{Keys: []KeyRecord{record}}); err == nil {
+		t.Fatal("expected expires_at before not_before to be rejected")
+	}
+}
+
+func TestRecheckFollowsKeyFile(t *testing.T) {
+	raw, record, err := GenerateAPIKey("ops", []string{"dashboard-viewer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	writeKeyFile(t, path, record)
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := APIKeyAuthenticator{Store: store}
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("X-API-Key", raw)
+	id, err := a.Authenticate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Recheck(*id); err != nil {
+		t.Fatalf("live key must pass, got %v", err)
+	}
+
+	// Roles come from the current record
+	record.Roles = []string{"user"}
+	writeKeyFile(t, path, record)
+	store.LoadFromFile(path)
+	if got, err := a.Recheck(*id); err != nil || len(got.Roles) != 1 || got.Roles[0] != "user" {
+		t.Fatalf("expected current roles, got %+v, %v", got, err)
+	}
+
+	// Rotated: same ID and roles, new secret
+	_, rotated, _ := GenerateAPIKey("ops", []string{"user"})
+	writeKeyFile(t, path, rotated)
+	store.LoadFromFile(path)
+	if _, err := a.Recheck(*id); err == nil {
+		t.Fatal("a rotated key must fail the re-check")
+	}
+
+	// Removed from the file
+	_, otherRecord, _ := GenerateAPIKey("other", []string{"user"})
+	writeKeyFile(t, path, otherRecord)
+	store.LoadFromFile(path)
+	if _, err := a.Recheck(*id); err == nil {
+		t.Fatal("a removed key must fail the re-check")
+	}
+
+	// A store that cannot look up by ID fails closed
+	if _, err := (APIKeyAuthenticator{Store: lookupOnly{}}).Recheck(*id); err == nil {
+		t.Fatal("expected a re-check failure without an ID index")
+	}
+
+	// Other schemes are not re-checked here
+	jwt := Identity{Type: AuthJWT, Subject: "alice"}
+	if got, err := a.Recheck(jwt); err != nil || got.Subject != "alice" {
+		t.Fatalf("expected JWT identity unchanged, got %+v, %v", got, err)
+	}
+}
+
+type lookupOnly struct{}
+
+func (lookupOnly) Lookup(string) (*APIKey, bool) { return nil, false }
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"strings"
+
+	"Zero-TrustAPIGateWayServer/internal/certs"
+)
+
+/*
+MUTUAL TLS CLIENT AUTHENTICATION
+
+The TLS layer verifies the client certificate against the configured CA
+bundle; this authenticator only turns a VERIFIED certificate into an
+identity. A certificate that was presented but not verified is rejected.
+
+Subject:
+ The SPIFFE ID (spiffe:// URI SAN) if present, otherwise the CN.
+ SPIFFE allows exactly one SPIFFE URI SAN; more than one is rejected.
+
+Roles:
+ Static mappings from certificate attributes. A mapping applies when
+ ALL of its attributes match; roles from every matching mapping are
+ granted. No mapping matching means no roles (RBAC then denies).
+*/
+
+// CertRoleMapping grants Role to certificates matching every set field.
+type CertRoleMapping struct {
+	Role string
+
+	SPIFFEID           string // exact SPIFFE ID
+	SPIFFEIDPrefix     string // e.g. "spiffe://example.org/payments/"
+	CommonName         string
+	Organization       string // any O entry
+	OrganizationalUnit string // any OU entry
+	DNSName            string // any DNS SAN
+}
+
+// IsEmpty reports whether the mapping has no attributes; such a mapping
+// would match every certificate and is rejected by config validation.
+func (m CertRoleMapping) IsEmpty() bool {
+	return m.SPIFFEID == "" && m.SPIFFEIDPrefix == "" && m.CommonName == "" &&
+		m.Organization == "" && m.OrganizationalUnit == "" && m.DNSName == ""
+}
+
+func (m CertRoleMapping) matches(cert *x509.Certificate, spiffeID string) bool {
+	if m.IsEmpty() {
+		return false
+	}
+	if m.SPIFFEID != "" && spiffeID != m.SPIFFEID {
+		return false
+	}
+	if m.SPIFFEIDPrefix != "" && (spiffeID == "" || !strings.HasPrefix(spiffeID, m.SPIFFEIDPrefix)) {
+		return false
+	}
+	if m.CommonName != "" && cert.Subject.CommonName != m.CommonName {
+		return false
+	}
+	if m.Organization != "" && !containsString(cert.Subject.Organization, m.Organization) {
+		return false
+	}
+	if m.OrganizationalUnit != "" && !containsString(cert.Subject.OrganizationalUnit, m.OrganizationalUnit) {
+		return false
+	}
+	if m.DNSName != "" && !containsString(cert.DNSNames, m.DNSName) {
+		return false
+	}
+	return true
+}
+
+// MTLSAuthenticator authenticates verified TLS client certificates.
+type MTLSAuthenticator struct {
+	Roles []CertRoleMapping
+}
+
+// Authenticate implements Authenticator.
+func (a MTLSAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, ErrNoCredentials
+	}
+
+	// Only trust what the handshake verified against our CA bundle
+	if len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, errors.New("client certificate not verified")
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+
+	spiffeID, err := certs.SPIFFEID(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := spiffeID
+	if subject == "" {
+		subject = cert.Subject.CommonName
+	}
+	if subject == "" {
+		return nil, errors.New("client certificate has no SPIFFE ID or CN")
+	}
+
+	var roles []string
+	for _, m := range a.Roles {
+		if m.matches(cert, spiffeID) && !containsString(roles, m.Role) {
+			roles = append(roles, m.Role)
+		}
+	}
+
+	return &Identity{
+		Type:    AuthMTLS,
+		Subject: subject,
+		Roles:   roles,
+		Issuer:  cert.Issuer.CommonName,
+	}, nil
+}
+
+// Challenge implements Authenticator.
+func (MTLSAuthenticator) Challenge() string {
+	return `MutualTLS realm="` + Realm + `"`
+}
+
+func containsString(list []string, want string) bool {
+	for _, s := range list {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
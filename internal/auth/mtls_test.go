@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"Zero-TrustAPIGateWayServer/internal/certs/certstest"
+)
+
+func spiffeURI(t *testing.T, id string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// verifiedRequest fakes what net/http sets after a successful mTLS handshake.
+func verifiedRequest(cert tls.Certificate) *http.Request {
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert.Leaf},
+		VerifiedChains:   [][]*x509.Certificate{{cert.Leaf}},
+	}
+	return req
+}
+
+func TestMTLSSubjectFromSPIFFEID(t *testing.T) {
+	ca := certstest.NewCA(t, "test-ca")
+	cert := ca.KeyPair(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "payments"},
+		URIs:    []*url.URL{spiffeURI(t, "spiffe://example.org/ns/prod/sa/payments")},
+	})
+
+	a := MTLSAuthenticator{Roles: []CertRoleMapping{
+		{Role: "payments", SPIFFEIDPrefix: "spiffe://example.org/ns/prod/"},
+		{Role: "admin", CommonName: "payments", Organization: "Ops"},
+	}}
+
+	id, err := a.Authenticate(verifiedRequest(cert))
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if id.Type != AuthMTLS || id.Subject != "spiffe://example.org/ns/prod/sa/payments" || id.Issuer != "test-ca" {
+		t.Fatalf("unexpected identity %+v", id)
+	}
+	// The admin mapping needs O=Ops as well as the CN
+	if len(id.Roles) != 1 || id.Roles[0] != "payments" {
+		t.Fatalf("expected only the payments role, got %v", id.Roles)
+	}
+}
+
+func TestMTLSSubjectFromCommonName(t *testing.T) {
+	ca := certstest.NewCA(t, "test-ca")
+	cert := ca.KeyPair(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "batch-job", Organization: []string{"Ops"}},
+		DNSNames: []string{"batch.internal"},
+	})
+
+	a := MTLSAuthenticator{Roles: []CertRoleMapping{
+		{Role: "admin", CommonName: "batch-job", Organization: "Ops"},
+		{Role: "user", DNSName: "batch.internal"},
+		{Role: "payments", SPIFFEIDPrefix: "spiffe://example.org/"},
+	}}
+
+	id, err := a.Authenticate(verifiedRequest(cert))
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if id.Subject != "batch-job" {
+		t.Fatalf("expected CN subject, got %q", id.Subject)
+	}
+	if len(id.Roles) != 2 || id.Roles[0] != "admin" || id.Roles[1] != "user" {
+		t.Fatalf("unexpected roles %v", id.Roles)
+	}
+}
+
+func TestMTLSRejections(t *testing.T) {
+	ca := certstest.NewCA(t, "test-ca")
+	a := MTLSAuthenticator{}
+
+	// No TLS or no certificate: let other schemes try
+	if _, err := a.Authenticate(httptest.NewRequest("GET", "/api", nil)); err != ErrNoCredentials {
+		t.Fatalf("expected ErrNoCredentials without TLS, got %v", err)
+	}
+
+	cert := ca.KeyPair(t, &x509.Certificate{Subject: pkix.Name{CommonName: "svc"}})
+	unverified := verifiedRequest(cert)
+	unverified.TLS.VerifiedChains = nil
+	if _, err := a.Authenticate(unverified); err == nil {
+		t.Fatal("unverified certificate must be rejected")
+	}
+
+	twoIDs := ca.KeyPair(t, &x509.Certificate{URIs: []*url.URL{
+		spiffeURI(t, "spiffe://example.org/a"),
+		spiffeURI(t, "spiffe://example.org/b"),
+	}})
+	if _, err := a.Authenticate(verifiedRequest(twoIDs)); err == nil {
+		t.Fatal("more than one SPIFFE ID must be rejected")
+	}
+
+	anonymous := ca.KeyPair(t, &x509.Certificate{})
+	if _, err := a.Authenticate(verifiedRequest(anonymous)); err == nil {
+		t.Fatal("certificate without SPIFFE ID or CN must be rejected")
+	}
+}
+
+func TestMTLSHandshake(t *testing.T) {
+	ca := certstest.NewCA(t, "test-ca")
+	rogue := certstest.NewCA(t, "rogue-ca")
+
+	a := MTLSAuthenticator{Roles: []CertRoleMapping{{Role: "user", CommonName: "svc"}}}
+	var reached *Identity
+	server := httptest.NewUnstartedServer(Middleware(a)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached, _ = FromContext(r.Context())
+	})))
+	server.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: ca.Pool()}
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(cert *tls.Certificate) (int, error) {
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		if cert != nil {
+			// Always send it, even when the server does not list its CA
+			transport.TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return cert, nil
+			}
+		}
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL + "/api")
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	good := ca.KeyPair(t, certstest.Client("svc", ""))
+	if code, err := get(&good); err != nil || code != http.StatusOK {
+		t.Fatalf("expected 200 with trusted cert, got %d %v", code, err)
+	}
+	if reached == nil || reached.Subject != "svc" || reached.Roles[0] != "user" {
+		t.Fatalf("unexpected identity %+v", reached)
+	}
+
+	if code, err := get(nil); err != nil || code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without cert, got %d %v", code, err)
+	}
+
+	// A certificate from an untrusted CA never reaches the handler
+	bad := rogue.KeyPair(t, certstest.Client("svc", ""))
+	if _, err := get(&bad); err == nil {
+		t.Fatal("expected handshake failure for untrusted CA")
+	}
+}
@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/*
+LOGIN SESSIONS
+
+Used by the dashboard so a browser can authenticate once (with an API
+key or JWT) instead of sending the credential on every request.
+
+- The session token is 256 random bits, sent only in an HttpOnly,
+  SameSite=Strict cookie
+- The server stores SHA-256(token), never the token itself
+- Sessions expire after a fixed TTL; there is no sliding renewal. A
+  session opened with a JWT also ends when the token expires
+- Each session carries its own CSRF token for state-changing requests
+- In memory only: a restart logs everyone out (fail closed)
+- The credential behind a session is re-checked on every use
+  (Revalidate): revoking, expiring or rotating an API key ends its
+  sessions at once, and role changes apply to the next request
+*/
+
+// SessionCookie is the name of the session cookie.
+const SessionCookie = "gw_session"
+
+// DefaultSessionTTL is used when NewSessionStore gets a non-positive TTL.
+const DefaultSessionTTL = time.Hour
+
+// Session is one logged-in browser. For an API key login,
+// Identity.Subject is the key ID that Revalidate re-checks, and
+// Identity.KeyFingerprint must still match the live key.
+type Session struct {
+	Identity  Identity
+	CSRFToken string
+	ExpiresAt time.Time
+}
+
+// ValidCSRF reports whether token is this session's CSRF token.
+func (s *Session) ValidCSRF(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.CSRFToken)) == 1
+}
+
+// SessionStore holds active sessions.
+type SessionStore struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*Session // by hex SHA-256 of the token
+
+	// Revalidate re-checks a session's identity on every Get and returns
+	// its current form; an error ends the session. nil = trusted until
+	// the TTL. Set before the store is used.
+	Revalidate func(Identity) (*Identity, error)
+
+	// now is used for expiry; replaced in tests
+	now func() time.Time
+}
+
+// NewSessionStore creates an empty store.
+func NewSessionStore(ttl time.Duration) *SessionStore {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	return &SessionStore{
+		ttl:      ttl,
+		sessions: make(map[string]*Session),
+		now:      time.Now,
+	}
+}
+
+// Create starts a session for id and returns its token.
+func (s *SessionStore) Create(id *Identity) (token string, session *Session, err error) {
+	token, err = randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	csrf, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	session = &Session{
+		Identity:  *id,
+		CSRFToken: csrf,
+		ExpiresAt: now.Add(s.ttl),
+	}
+	// Never outlive the credential the session was opened with
+	if exp, ok := credentialExpiry(id); ok && exp.Before(session.ExpiresAt) {
+		session.ExpiresAt = exp
+	}
+	s.sessions[tokenKey(token)] = session
+	return token, session, nil
+}
+
+// credentialExpiry is when the credential behind id stops being valid,
+// if it says so itself (a JWT's exp). API keys are re-checked instead.
+func credentialExpiry(id *Identity) (time.Time, bool) {
+	if id.Type != AuthJWT {
+		return time.Time{}, false
+	}
+	return numericDate(id.Claims, "exp")
+}
+
+// Get returns the live session for token. The result is a copy
+// carrying the revalidated identity.
+func (s *SessionStore) Get(token string) (*Session, bool) {
+	if token == "" {
+		return nil, false
+	}
+
+	session, ok := s.lookup(token)
+	if !ok {
+		return nil, false
+	}
+
+	if s.Revalidate != nil {
+		id, err := s.Revalidate(session.Identity)
+		if err != nil {
+			s.Delete(token)
+			return nil, false
+		}
+		session.Identity = *id
+	}
+	return &session, true
+}
+
+// lookup returns a copy of the unexpired session for token.
+func (s *SessionStore) lookup(token string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := tokenKey(token)
+	session, ok := s.sessions[key]
+	if !ok {
+		return Session{}, false
+	}
+	if !s.now().Before(session.ExpiresAt) {
+		delete(s.sessions, key)
+		return Session{}, false
+	}
+	return *session, true
+}
+
+// Delete ends the session for token (logout).
+func (s *SessionStore) Delete(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, tokenKey(token))
+}
+
+// TTL returns the session lifetime.
+func (s *SessionStore) TTL() time.Duration {
+	return s.ttl
+}
+
+// sweep drops expired sessions. Caller holds mu.
+func (s *SessionStore) sweep(now time.Time) {
+	for key, session := range s.sessions {
+		if !now.Before(session.ExpiresAt) {
+			delete(s.sessions, key)
+		}
+	}
+}
+
+// FromRequest returns the session named by the request's cookie.
+func (s *SessionStore) FromRequest(r *http.Request) (token string, session *Session, ok bool) {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return "", nil, false
+	}
+	session, ok = s.Get(cookie.Value)
+	return cookie.Value, session, ok
+}
+
+// SessionAuthenticator authenticates the session cookie.
+type SessionAuthenticator struct {
+	Store *SessionStore
+}
+
+// Authenticate implements Authenticator.
+func (a SessionAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if _, err := r.Cookie(SessionCookie); err != nil {
+		return nil, ErrNoCredentials
+	}
+
+	_, session, ok := a.Store.FromRequest(r)
+	if !ok {
+		return nil, errors.New("invalid or expired session")
+	}
+
+	id := session.Identity
+	return &id, nil
+}
+
+// Challenge implements Authenticator.
+func (SessionAuthenticator) Challenge() string {
+	return `Session realm="` + Realm + `"`
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
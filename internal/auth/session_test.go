@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSessionExpires(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewSessionStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	token, _, err := store.Create(&Identity{Type: AuthAPIKey, Subject: "ops", Roles: []string{"dashboard-viewer"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", SessionCookie+"="+token)
+
+	id, err := SessionAuthenticator{Store: store}.Authenticate(req)
+	if err != nil || id.Subject != "ops" {
+		t.Fatalf("expected live session, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := (SessionAuthenticator{Store: store}).Authenticate(req); err == nil {
+		t.Fatal("expired session must be rejected")
+	}
+}
+
+func TestSessionEndsWithToken(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewSessionStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	exp := now.Add(10 * time.Minute)
+	_, session, err := store.Create(&Identity{
+		Type:    AuthJWT,
+		Subject: "alice",
+		Claims:  map[string]interface{}{"exp": float64(exp.Unix())},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !session.ExpiresAt.Equal(exp) {
+		t.Fatalf("expected the session to end with the token at %v, got %v", exp, session.ExpiresAt)
+	}
+}
+
+func TestSessionTokenNotStored(t *testing.T) {
+	store := NewSessionStore(0)
+
+	token, _, err := store.Create(&Identity{Subject: "ops"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := store.sessions[token]; ok {
+		t.Fatal("raw session token used as the store key")
+	}
+	if _, ok := store.Get(token); !ok {
+		t.Fatal("session not found by token")
+	}
+}
+
+func TestNoSessionCookieIsNoCredentials(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+
+	if _, err := (SessionAuthenticator{Store: NewSessionStore(0)}).Authenticate(req); err != ErrNoCredentials {
+		t.Fatalf("expected ErrNoCredentials, got %v", err)
+	}
+}
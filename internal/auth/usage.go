@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/fsutil"
+)
+
+/*
+API KEY USAGE TRACKING
+
+Last-used times exist to find and retire stale keys. They are
+bookkeeping, never an input to an authentication decision, so:
+ Recording never blocks a request (a full buffer drops the event)
+ Timestamps are kept per key ID in memory and optionally flushed to a
+  JSON file that survives restarts and can be read by tooling
+ A flush failure is logged by the caller and retried on the next tick
+*/
+
+// DefaultUsageFlushInterval is used when Start gets a non-positive interval.
+const DefaultUsageFlushInterval = 30 * time.Second
+
+const usageBuffer = 1024
+
+// UsageTracker records when each API key was last used.
+// A nil tracker is valid and records nothing.
+type UsageTracker struct {
+	path   string // empty = memory only
+	events chan string
+
+	mu       sync.RWMutex
+	lastUsed map[string]time.Time
+	dirty    bool
+
+	now func() time.Time
+}
+
+// NewUsageTracker creates a tracker. If path is set, previously flushed
+// times are loaded from it; a missing file is not an error.
+func NewUsageTracker(path string) (*UsageTracker, error) {
+	u := &UsageTracker{
+		path:     path,
+		events:   make(chan string, usageBuffer),
+		lastUsed: make(map[string]time.Time),
+		now:      time.Now,
+	}
+
+	if path != "" {
+		loaded, err := LoadUsage(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		for id, t := range loaded {
+			u.lastUsed[id] = t
+		}
+	}
+
+	return u, nil
+}
+
+// Touch records a use of key id. It never blocks.
+func (u *UsageTracker) Touch(id string) {
+	if u == nil {
+		return
+	}
+
+	select {
+	case u.events <- id:
+	default:
+		// Busy: losing a timestamp is better than slowing a request
+	}
+}
+
+// Start consumes recorded uses and flushes them every interval.
+// The returned function stops the tracker after a final flush.
+func (u *UsageTracker) Start(interval time.Duration, onFlushError func(error)) (stop func()) {
+	if interval <= 0 {
+		interval = DefaultUsageFlushInterval
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case id := <-u.events:
+				u.record(id)
+			case <-ticker.C:
+				if err := u.Flush(); err != nil && onFlushError != nil {
+					onFlushError(err)
+				}
+			case <-done:
+				u.drain()
+				if err := u.Flush(); err != nil && onFlushError != nil {
+					onFlushError(err)
+				}
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+func (u *UsageTracker) drain() {
+	for {
+		select {
+		case id := <-u.events:
+			u.record(id)
+		default:
+			return
+		}
+	}
+}
+
+func (u *UsageTracker) record(id string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.lastUsed[id] = u.now().UTC()
+	u.dirty = true
+}
+
+// LastUsed returns when key id was last used, if ever.
+func (u *UsageTracker) LastUsed(id string) (time.Time, bool) {
+	if u == nil {
+		return time.Time{}, false
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	t, ok := u.lastUsed[id]
+	return t, ok
+}
+
+// Snapshot returns a copy of all last-used times.
+func (u *UsageTracker) Snapshot() map[string]time.Time {
+	out := make(map[string]time.Time)
+	if u == nil {
+		return out
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	for id, t := range u.lastUsed {
+		out[id] = t
+	}
+	return out
+}
+
+// Flush writes the times to the usage file, if one is configured and
+// anything changed. The file is replaced atomically.
+func (u *UsageTracker) Flush() error {
+	if u == nil || u.path == "" {
+		return nil
+	}
+
+	u.mu.Lock()
+	if !u.dirty {
+		u.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(u.lastUsed, "", "  ")
+	u.dirty = false
+	u.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if err := fsutil.WriteFileAtomic(u.path, data, 0600); err != nil {
+		u.mu.Lock()
+		u.dirty = true
+		u.mu.Unlock()
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	return nil
+}
+
+// LoadUsage reads a usage file written by Flush.
+func LoadUsage(path string) (map[string]time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]time.Time)
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("invalid usage file: %w", err)
+	}
+	return usage, nil
+}
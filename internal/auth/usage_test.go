@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageTrackedAfterSuccessfulAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	usage, err := NewUsageTracker(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := usage.Start(time.Hour, nil)
+
+	a := APIKeyAuthenticator{
+		Store: MapStore{"good-key": {ID: "ci-bot", Key: "good-key", Roles: []string{"user"}}},
+		Usage: usage,
+	}
+
+	for _, key := range []string{"good-key", "bad-key"} {
+		req := httptest.NewRequest("GET", "/api", nil)
+		req.Header.Set("X-API-Key", key)
+		_, _ = a.Authenticate(req)
+	}
+
+	// Stop drains pending events and flushes
+	stop()
+
+	if _, ok := usage.LastUsed("ci-bot"); !ok {
+		t.Fatal("expected last-used time for ci-bot")
+	}
+	if len(usage.Snapshot()) != 1 {
+		t.Fatalf("only successful uses are tracked, got %v", usage.Snapshot())
+	}
+
+	persisted, err := LoadUsage(path)
+	if err != nil {
+		t.Fatalf("expected usage file, got %v", err)
+	}
+	if _, ok := persisted["ci-bot"]; !ok {
+		t.Fatal("last-used time not flushed to disk")
+	}
+
+	// A restart picks up where it left off
+	reloaded, err := NewUsageTracker(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.LastUsed("ci-bot"); !ok {
+		t.Fatal("last-used time not reloaded")
+	}
+}
+
+func TestUsageTouchNeverBlocks(t *testing.T) {
+	usage, err := NewUsageTracker("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Not started: the buffer fills and further events are dropped
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*usageBuffer; i++ {
+			usage.Touch("k")
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Touch blocked on a full buffer")
+	}
+
+	var nilTracker *UsageTracker
+	nilTracker.Touch("k") // must not panic
+}
@@ -0,0 +1,77 @@
+package certs
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/fsutil"
+)
+
+/*
+CA BUNDLE (hot reloaded)
+
+Same philosophy as the policy engine and the API key store:
+ The bundle is read from a PEM file and polled for changes
+ A missing, unreadable or certificate-free file leaves an EMPTY pool:
+ no client certificate verifies (fail closed)
+ Never a nil pool: crypto/tls treats nil as "use the system roots"
+*/
+
+// CAPool is a hot-reloadable set of trusted CA certificates.
+type CAPool struct {
+	pool atomic.Pointer[x509.CertPool]
+}
+
+// NewCAPool loads path. On error the pool is empty and the error is
+// returned so the caller can log it; the pool is still usable.
+func NewCAPool(path string) (*CAPool, error) {
+	p := &CAPool{}
+	p.pool.Store(x509.NewCertPool())
+	return p, p.LoadFromFile(path)
+}
+
+// Pool returns the current pool. It is never nil.
+func (p *CAPool) Pool() *x509.CertPool {
+	return p.pool.Load()
+}
+
+// LoadFromFile replaces the pool with the certificates in path.
+// On any error the pool is emptied.
+func (p *CAPool) LoadFromFile(path string) error {
+	pool, err := LoadCABundle(path)
+	if err != nil {
+		p.invalidate()
+		return err
+	}
+	p.pool.Store(pool)
+	return nil
+}
+
+// Watch polls the bundle for changes and reloads it.
+// Returns a function that stops watching.
+func (p *CAPool) Watch(path string, interval time.Duration) (stop func()) {
+	// On error LoadFromFile has already emptied the pool
+	return fsutil.Watch(path, interval, p.LoadFromFile, p.invalidate)
+}
+
+func (p *CAPool) invalidate() {
+	p.pool.Store(x509.NewCertPool())
+}
+
+// LoadCABundle reads a PEM file containing one or more CA certificates.
+func LoadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("CA bundle contains no PEM certificates")
+	}
+	return pool, nil
+}
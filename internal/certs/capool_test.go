@@ -0,0 +1,39 @@
+package certs
+
+import (
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"Zero-TrustAPIGateWayServer/internal/certs/certstest"
+)
+
+func TestCAPoolInvalidBundleIsEmptyNotNil(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ca.pem")
+	certstest.WriteFile(t, path, certstest.NewCA(t, "ca").PEM())
+
+	p, err := NewCAPool(path)
+	if err != nil {
+		t.Fatalf("expected load success, got %v", err)
+	}
+
+	certstest.WriteFile(t, path, []byte("not a certificate"))
+	if err := p.LoadFromFile(path); err == nil {
+		t.Fatal("expected error for bundle without certificates")
+	}
+	// nil would mean "trust the system roots"
+	if p.Pool() == nil || !p.Pool().Equal(x509.NewCertPool()) {
+		t.Fatal("invalid bundle must leave an empty, non-nil pool")
+	}
+
+	os.Remove(path)
+	missing, err := NewCAPool(path)
+	if err == nil {
+		t.Fatal("expected error for missing bundle")
+	}
+	if missing.Pool() == nil {
+		t.Fatal("missing bundle must leave an empty, non-nil pool")
+	}
+}
@@ -0,0 +1,55 @@
+package certs
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"Zero-TrustAPIGateWayServer/internal/certs/certstest"
+)
+
+func serverCert(t *testing.T, dir string, ca *certstest.CA, names ...string) (certFile, keyFile string) {
+	t.Helper()
+	return ca.WriteFiles(t, dir, names[0], certstest.Server(names...))
+}
+
+// handshake dials a TLS listener using cfg and reports the handshake error.
+func handshake(t *testing.T, serverCfg *tls.Config, clientCfg *tls.Config) error {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if err := conn.(*tls.Conn).Handshake(); err == nil {
+			conn.Write([]byte("ok"))
+		}
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), clientCfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// With TLS 1.3 a rejected client certificate surfaces on first read
+	buf := make([]byte, 2)
+	_, err = conn.Read(buf)
+	return err
+}
+
+func mustCertStore(t *testing.T, certFile, keyFile string) *CertStore {
+	t.Helper()
+	store, err := NewCertStore([]KeyPair{{CertFile: certFile, KeyFile: keyFile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
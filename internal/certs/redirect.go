@@ -0,0 +1,36 @@
+package certs
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RedirectHandler sends every plain-HTTP request to the same host and
+// path on httpsPort. Nothing is ever served or proxied over HTTP.
+// 308 keeps the method and body on the retried request.
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+
+		// The Host header must not be able to turn this into a redirect
+		// to some other URL
+		if host == "" || strings.ContainsAny(host, "/\\@?#[] ") {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		switch {
+		case httpsPort != "" && httpsPort != "443":
+			host = net.JoinHostPort(host, httpsPort)
+		case strings.Contains(host, ":"):
+			host = "[" + host + "]" // IPv6 literal
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,44 @@
+package certs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		port, host, target string
+		want               string
+	}{
+		{"443", "example.com", "/api/x?y=1", "https://example.com/api/x?y=1"},
+		{"443", "example.com:80", "/", "https://example.com/"},
+		{"8443", "example.com:8080", "/api", "https://example.com:8443/api"},
+		{"8443", "[::1]:8080", "/", "https://[::1]:8443/"},
+		{"443", "[::1]:8080", "/", "https://[::1]/"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.target, nil)
+		req.Host = tt.host
+		rr := httptest.NewRecorder()
+		RedirectHandler(tt.port).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusPermanentRedirect || rr.Header().Get("Location") != tt.want {
+			t.Errorf("%s%s: got %d %q, want %q", tt.host, tt.target, rr.Code, rr.Header().Get("Location"), tt.want)
+		}
+	}
+}
+
+func TestRedirectHandlerRejectsOddHosts(t *testing.T) {
+	for _, host := range []string{"", "evil.com/x", "user@evil.com", "evil.com\\x"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = host
+		rr := httptest.NewRecorder()
+		RedirectHandler("443").ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("host %q: expected 400, got %d %q", host, rr.Code, rr.Header().Get("Location"))
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package certs
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+)
+
+/*
+SERVER TLS
+
+Protocol:
+ TLS 1.2 or later (1.3 may be required). Cipher suites can be narrowed
+ for TLS 1.2 but only to suites crypto/tls considers secure; TLS 1.3
+ suites are not configurable.
+
+Client certificate modes:
+ none     : no client certificate is requested
+ optional : a certificate is verified if the client sends one; clients
+            without one fall through to the other auth schemes
+ required : the handshake fails without a verified certificate
+
+The client CA pool is looked up per handshake (GetConfigForClient), so
+a reloaded bundle applies to the next connection without a restart.
+
+ALPN:
+ NextProtos is set here, not left to net/http. The per-handshake config
+ is cloned from this one, never from the server's copy that net/http
+ adds "h2" to, so without it every connection would fall back to
+ HTTP/1.1.
+*/
+
+// ClientAuth selects how client certificates are handled.
+type ClientAuth string
+
+const (
+	ClientAuthNone     ClientAuth = "none"
+	ClientAuthOptional ClientAuth = "optional"
+	ClientAuthRequired ClientAuth = "required"
+)
+
+// ServerOptions describes the gateway's TLS listener.
+type ServerOptions struct {
+	Certificates *CertStore
+
+	MinVersion   uint16   // 0 = TLS 1.2
+	CipherSuites []uint16 // nil = Go defaults
+
+	ClientAuth ClientAuth // "" = none
+	ClientCAs  *CAPool    // required unless ClientAuth is none
+}
+
+// ServerTLSConfig builds the listener's tls.Config.
+func ServerTLSConfig(opts ServerOptions) (*tls.Config, error) {
+	if opts.Certificates == nil {
+		return nil, errors.New("server certificates are required")
+	}
+
+	minVersion := opts.MinVersion
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+	if minVersion < tls.VersionTLS12 {
+		return nil, errors.New("minimum TLS version must be 1.2 or later")
+	}
+
+	cfg := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   opts.CipherSuites,
+		GetCertificate: opts.Certificates.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+
+	switch opts.ClientAuth {
+	case "", ClientAuthNone:
+		return cfg, nil
+	case ClientAuthOptional:
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequired:
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client_auth mode %q", opts.ClientAuth)
+	}
+
+	if opts.ClientCAs == nil {
+		return nil, errors.New("client certificate verification requires a CA bundle")
+	}
+
+	cfg.ClientCAs = opts.ClientCAs.Pool()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := cfg.Clone()
+		c.GetConfigForClient = nil
+		c.ClientCAs = opts.ClientCAs.Pool()
+		return c, nil
+	}
+	return cfg, nil
+}
+
+// ParseVersion maps "1.2" or "1.3" to its crypto/tls constant.
+func ParseVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version %q", v)
+}
+
+// ParseCipherSuites maps IANA suite names (e.g.
+// TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256) to IDs. Only suites that
+// crypto/tls lists as secure and that apply to TLS 1.2 are accepted.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		for _, v := range cs.SupportedVersions {
+			if v == tls.VersionTLS12 {
+				known[cs.Name] = cs.ID
+			}
+		}
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
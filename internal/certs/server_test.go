@@ -0,0 +1,166 @@
+package certs
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"Zero-TrustAPIGateWayServer/internal/certs/certstest"
+)
+
+func clientConfig(serverCA *certstest.CA, cert *tls.Certificate) *tls.Config {
+	cfg := &tls.Config{RootCAs: serverCA.Pool(), ServerName: "gateway.test"}
+	if cert != nil {
+		// Always send it, even when the server does not list its CA
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return cert, nil
+		}
+	}
+	return cfg
+}
+
+func TestServerTLSClientAuthModes(t *testing.T) {
+	dir := t.TempDir()
+	serverCA := certstest.NewCA(t, "server-ca")
+	clientCA := certstest.NewCA(t, "client-ca")
+	certFile, keyFile := serverCert(t, dir, serverCA, "gateway.test")
+
+	caFile := filepath.Join(dir, "clients.pem")
+	certstest.WriteFile(t, caFile, clientCA.PEM())
+	pool, err := NewCAPool(caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := clientCA.KeyPair(t, certstest.Client("svc", ""))
+
+	tests := []struct {
+		mode       ClientAuth
+		cert       *tls.Certificate
+		shouldPass bool
+	}{
+		{ClientAuthNone, nil, true},
+		{ClientAuthOptional, nil, true},
+		{ClientAuthOptional, &client, true},
+		{ClientAuthRequired, nil, false},
+		{ClientAuthRequired, &client, true},
+	}
+
+	for _, tt := range tests {
+		cfg, err := ServerTLSConfig(ServerOptions{Certificates: mustCertStore(t, certFile, keyFile), ClientAuth: tt.mode, ClientCAs: pool})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = handshake(t, cfg, clientConfig(serverCA, tt.cert))
+		if (err == nil) != tt.shouldPass {
+			t.Errorf("mode %s, cert %v: unexpected result %v", tt.mode, tt.cert != nil, err)
+		}
+	}
+}
+
+func TestServerTLSRequiresCAPool(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := serverCert(t, dir, certstest.NewCA(t, "server-ca"), "gateway.test")
+
+	if _, err := ServerTLSConfig(ServerOptions{Certificates: mustCertStore(t, certFile, keyFile), ClientAuth: ClientAuthRequired}); err == nil {
+		t.Fatal("expected error without a client CA bundle")
+	}
+	if _, err := ServerTLSConfig(ServerOptions{Certificates: mustCertStore(t, certFile, keyFile), ClientAuth: "sometimes"}); err == nil {
+		t.Fatal("expected error for unknown client_auth mode")
+	}
+}
+
+func TestServerTLSClientCAReload(t *testing.T) {
+	dir := t.TempDir()
+	serverCA := certstest.NewCA(t, "server-ca")
+	oldCA := certstest.NewCA(t, "old-client-ca")
+	newCA := certstest.NewCA(t, "new-client-ca")
+	certFile, keyFile := serverCert(t, dir, serverCA, "gateway.test")
+
+	caFile := filepath.Join(dir, "clients.pem")
+	certstest.WriteFile(t, caFile, oldCA.PEM())
+	pool, err := NewCAPool(caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := ServerTLSConfig(ServerOptions{Certificates: mustCertStore(t, certFile, keyFile), ClientAuth: ClientAuthRequired, ClientCAs: pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldClient := oldCA.KeyPair(t, certstest.Client("svc", ""))
+	newClient := newCA.KeyPair(t, certstest.Client("svc", ""))
+
+	if err := handshake(t, cfg, clientConfig(serverCA, &oldClient)); err != nil {
+		t.Fatalf("expected old CA to be trusted, got %v", err)
+	}
+
+	// Rotate the bundle; the same tls.Config picks it up
+	certstest.WriteFile(t, caFile, newCA.PEM())
+	if err := pool.LoadFromFile(caFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handshake(t, cfg, clientConfig(serverCA, &oldClient)); err == nil {
+		t.Fatal("old CA must no longer be trusted after reload")
+	}
+	if err := handshake(t, cfg, clientConfig(serverCA, &newClient)); err != nil {
+		t.Fatalf("expected new CA to be trusted, got %v", err)
+	}
+
+	// A broken bundle trusts nothing
+	certstest.WriteFile(t, caFile, []byte("garbage"))
+	pool.LoadFromFile(caFile)
+	if err := handshake(t, cfg, clientConfig(serverCA, &newClient)); err == nil {
+		t.Fatal("invalid bundle must reject every client certificate")
+	}
+}
+
+func TestServerTLSNegotiatesHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	serverCA := certstest.NewCA(t, "server-ca")
+	clientCA := certstest.NewCA(t, "client-ca")
+	certFile, keyFile := serverCert(t, dir, serverCA, "gateway.test")
+
+	caFile := filepath.Join(dir, "clients.pem")
+	certstest.WriteFile(t, caFile, clientCA.PEM())
+	pool, err := NewCAPool(caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Optional client auth goes through GetConfigForClient
+	for _, mode := range []ClientAuth{ClientAuthNone, ClientAuthOptional} {
+		cfg, err := ServerTLSConfig(ServerOptions{Certificates: mustCertStore(t, certFile, keyFile), ClientAuth: mode, ClientCAs: pool})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := &http.Server{
+			TLSConfig: cfg,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(r.Proto))
+			}),
+		}
+		go server.ServeTLS(ln, "", "")
+
+		client := clientConfig(serverCA, nil)
+		client.NextProtos = []string{"h2", "http/1.1"}
+		conn, err := tls.Dial("tcp", ln.Addr().String(), client)
+		if err != nil {
+			t.Fatalf("mode %s: %v", mode, err)
+		}
+		if got := conn.ConnectionState().NegotiatedProtocol; got != "h2" {
+			t.Errorf("mode %s: negotiated %q, want h2", mode, got)
+		}
+		conn.Close()
+		server.Close()
+	}
+}
@@ -0,0 +1,38 @@
+package certs
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// SPIFFEID returns the certificate's SPIFFE ID (its spiffe:// URI SAN),
+// or "" if it has none. SPIFFE allows exactly one; more is an error.
+func SPIFFEID(cert *x509.Certificate) (string, error) {
+	var id string
+	for _, u := range cert.URIs {
+		if !strings.EqualFold(u.Scheme, "spiffe") {
+			continue
+		}
+		if id != "" {
+			return "", errors.New("certificate has more than one SPIFFE ID")
+		}
+		if u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+			return "", errors.New("certificate has a malformed SPIFFE ID")
+		}
+		id = "spiffe://" + strings.ToLower(u.Host) + u.EscapedPath()
+	}
+	return id, nil
+}
+
+// normalizeSPIFFEID lowercases the trust domain of a configured SPIFFE
+// ID so it compares equal to what SPIFFEID returns. The path is case
+// sensitive and kept as written.
+func normalizeSPIFFEID(id string) string {
+	u, err := url.Parse(id)
+	if err != nil || !strings.EqualFold(u.Scheme, "spiffe") {
+		return id
+	}
+	return "spiffe://" + strings.ToLower(u.Host) + u.EscapedPath()
+}
@@ -0,0 +1,138 @@
+package certs
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+/*
+SERVER CERTIFICATES (hot reloaded, selected by SNI)
+
+- The first pair is the default, served when no other certificate
+  matches the client's SNI (or the client sends none)
+- Certificates are chosen per handshake, so a reload applies to new
+  connections while established ones keep running undisturbed
+- Reloads are all or nothing: if any pair fails to load (e.g. a cert
+  written before its key) the previous set keeps being served and the
+  reload is retried on the next tick. Unlike the CA bundle, keeping the
+  old certificate does not widen trust; dropping it would only take the
+  gateway offline.
+*/
+
+// KeyPair names a PEM certificate chain and its private key.
+type KeyPair struct {
+	CertFile string
+	KeyFile  string
+}
+
+// CertStore serves the current server certificates.
+type CertStore struct {
+	pairs []KeyPair
+	certs atomic.Pointer[[]tls.Certificate]
+}
+
+// NewCertStore loads every pair. Any failure is returned: the gateway
+// must not start without its certificates.
+func NewCertStore(pairs []KeyPair) (*CertStore, error) {
+	if len(pairs) == 0 {
+		return nil, errors.New("at least one certificate is required")
+	}
+
+	s := &CertStore{pairs: pairs}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads every pair again. On error the current set is kept.
+func (s *CertStore) Reload() error {
+	certs := make([]tls.Certificate, len(s.pairs))
+	for i, p := range s.pairs {
+		cert, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load certificate %s: %w", p.CertFile, err)
+		}
+		certs[i] = cert
+	}
+	s.certs.Store(&certs)
+	return nil
+}
+
+// GetCertificate picks the first certificate valid for the client's
+// SNI, or the default one. It is used as tls.Config.GetCertificate.
+func (s *CertStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certs := *s.certs.Load()
+	if hello.ServerName != "" {
+		for i := range certs {
+			if hello.SupportsCertificate(&certs[i]) == nil {
+				return &certs[i], nil
+			}
+		}
+	}
+	return &certs[0], nil
+}
+
+// Watch polls the certificate and key files and reloads them when any
+// changes. onError (may be nil) is told about failed reloads.
+// Returns a function that stops watching.
+func (s *CertStore) Watch(interval time.Duration, onError func(error)) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// Loaded by NewCertStore; only later changes matter
+		lastMod, _ := s.modTimes()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			mod, err := s.modTimes()
+			if err != nil {
+				if onError != nil {
+					onError(err)
+				}
+				continue
+			}
+			if mod == lastMod {
+				continue
+			}
+			if err := s.Reload(); err != nil {
+				if onError != nil {
+					onError(err)
+				}
+				// Retry next tick
+				continue
+			}
+			lastMod = mod
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// modTimes fingerprints every file's modification time.
+func (s *CertStore) modTimes() (string, error) {
+	var b []byte
+	for _, p := range s.pairs {
+		for _, path := range []string{p.CertFile, p.KeyFile} {
+			info, err := os.Stat(path)
+			if err != nil {
+				return "", err
+			}
+			b = info.ModTime().AppendFormat(b, time.RFC3339Nano)
+			b = append(b, ';')
+		}
+	}
+	return string(b), nil
+}
@@ -0,0 +1,209 @@
+package certs
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/certs/certstest"
+)
+
+// echoServer serves cfg on a local port, echoing one line per read.
+func echoServer(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					conn.Write([]byte(line))
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// servedCN dials addr with SNI name and returns the server cert's CN.
+func servedCN(t *testing.T, addr, name string, roots *x509.CertPool) (string, *tls.Conn) {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: roots, ServerName: name})
+	if err != nil {
+		t.Fatalf("dial %s: %v", name, err)
+	}
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName, conn
+}
+
+func TestCertStoreSelectsBySNI(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t, "server-ca")
+	defCert, defKey := serverCert(t, dir, ca, "gateway.test")
+	apiCert, apiKey := serverCert(t, dir, ca, "api.example.com", "*.api.example.com")
+
+	store, err := NewCertStore([]KeyPair{{defCert, defKey}, {apiCert, apiKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ServerTLSConfig(ServerOptions{Certificates: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := echoServer(t, cfg)
+
+	roots := ca.Pool()
+
+	for name, want := range map[string]string{
+		"gateway.test":       "gateway.test",
+		"api.example.com":    "api.example.com",
+		"eu.api.example.com": "api.example.com",
+	} {
+		cn, conn := servedCN(t, addr, name, roots)
+		conn.Close()
+		if cn != want {
+			t.Errorf("SNI %s: served %s, want %s", name, cn, want)
+		}
+	}
+
+	// No matching name: the default certificate (which then fails
+	// verification for the unknown name)
+	if _, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: roots, ServerName: "other.test"}); err == nil {
+		t.Fatal("expected verification failure for a name no certificate covers")
+	}
+}
+
+func TestCertStoreReloadKeepsConnections(t *testing.T) {
+	dir := t.TempDir()
+	oldCA := certstest.NewCA(t, "old-ca")
+	newCA := certstest.NewCA(t, "new-ca")
+	certFile, keyFile := serverCert(t, dir, oldCA, "gateway.test")
+
+	store := mustCertStore(t, certFile, keyFile)
+	stop := store.Watch(10*time.Millisecond, nil)
+	defer stop()
+
+	cfg, err := ServerTLSConfig(ServerOptions{Certificates: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := echoServer(t, cfg)
+
+	oldRoots := oldCA.Pool()
+	newRoots := newCA.Pool()
+
+	_, established := servedCN(t, addr, "gateway.test", oldRoots)
+	defer established.Close()
+
+	// Rotate to a certificate from a new CA
+	certPEM, keyPEM := newCA.Issue(t, certstest.Server("gateway.test"))
+	certstest.WriteFile(t, certFile, certPEM)
+	certstest.WriteFile(t, keyFile, keyPEM)
+	future := time.Now().Add(time.Second)
+	os.Chtimes(certFile, future, future)
+	os.Chtimes(keyFile, future, future)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: newRoots, ServerName: "gateway.test"})
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("new certificate never served: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The connection made before the reload still works
+	if _, err := established.Write([]byte("ping\n")); err != nil {
+		t.Fatalf("established connection dropped: %v", err)
+	}
+	line, err := bufio.NewReader(established).ReadString('\n')
+	if err != nil || line != "ping\n" {
+		t.Fatalf("established connection broken: %q %v", line, err)
+	}
+}
+
+func TestCertStoreKeepsLastGoodOnBrokenReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t, "server-ca")
+	certFile, keyFile := serverCert(t, dir, ca, "gateway.test")
+	store := mustCertStore(t, certFile, keyFile)
+
+	// A key that does not match the certificate, as during a rotation
+	// that has written only one of the two files
+	_, otherKey := ca.Issue(t, certstest.Server("gateway.test"))
+	certstest.WriteFile(t, keyFile, otherKey)
+
+	if err := store.Reload(); err == nil {
+		t.Fatal("expected mismatched key pair to fail")
+	}
+	cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: "gateway.test"})
+	if err != nil || cert == nil || cert.Leaf == nil || cert.Leaf.DNSNames[0] != "gateway.test" {
+		t.Fatalf("previous certificate must keep being served, got %v", err)
+	}
+
+	if _, err := NewCertStore([]KeyPair{{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.key")}}); err == nil {
+		t.Fatal("startup must fail without a usable certificate")
+	}
+}
+
+func TestParseVersionAndCipherSuites(t *testing.T) {
+	if v, err := ParseVersion("1.3"); err != nil || v != tls.VersionTLS13 {
+		t.Fatalf("unexpected 1.3 result %x %v", v, err)
+	}
+	if _, err := ParseVersion("1.1"); err == nil {
+		t.Fatal("TLS 1.1 must be rejected")
+	}
+
+	ids, err := ParseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"})
+	if err != nil || len(ids) != 1 || ids[0] != tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+		t.Fatalf("unexpected suites %v %v", ids, err)
+	}
+	for _, name := range []string{"TLS_RSA_WITH_RC4_128_SHA", "TLS_AES_128_GCM_SHA256", "bogus"} {
+		if _, err := ParseCipherSuites([]string{name}); err == nil {
+			t.Errorf("%s must be rejected", name)
+		}
+	}
+}
+
+func TestServerTLSMinVersion(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t, "server-ca")
+	certFile, keyFile := serverCert(t, dir, ca, "gateway.test")
+
+	cfg, err := ServerTLSConfig(ServerOptions{Certificates: mustCertStore(t, certFile, keyFile), MinVersion: tls.VersionTLS13})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := clientConfig(ca, nil)
+	client.MaxVersion = tls.VersionTLS12
+	if err := handshake(t, cfg, client); err == nil {
+		t.Fatal("TLS 1.2 client must be rejected when 1.3 is required")
+	}
+
+	if _, err := ServerTLSConfig(ServerOptions{Certificates: mustCertStore(t, certFile, keyFile), MinVersion: tls.VersionTLS11}); err == nil {
+		t.Fatal("minimum below TLS 1.2 must be rejected")
+	}
+}
@@ -0,0 +1,125 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+)
+
+/*
+UPSTREAM TLS (gateway as client)
+
+- RootCAs: a private CA bundle instead of the system roots
+- Client certificate: the gateway's own identity for upstream mTLS
+- ServerName: verify the upstream certificate against this name instead
+  of the target host (e.g. when targets are IP addresses)
+- SPIFFE ID pinning: after normal chain verification, the upstream's
+  certificate must carry one of the expected SPIFFE IDs
+
+Normal verification is never switched off; pinning only narrows it.
+Files are read once at startup.
+*/
+
+// UpstreamOptions configures TLS towards one upstream.
+type UpstreamOptions struct {
+	CAFile     string // empty = system roots
+	CertFile   string // gateway client certificate (with KeyFile)
+	KeyFile    string
+	ServerName string   // empty = target host
+	SPIFFEIDs  []string // empty = no pinning
+}
+
+// PinError reports an upstream certificate without an expected SPIFFE ID.
+type PinError struct {
+	Got string // "" if the certificate has no SPIFFE ID
+	Err error  // set if the SPIFFE ID could not be read
+}
+
+func (e *PinError) Error() string {
+	if e.Err != nil {
+		return "upstream certificate: " + e.Err.Error()
+	}
+	if e.Got == "" {
+		return "upstream certificate has no SPIFFE ID"
+	}
+	return fmt.Sprintf("upstream SPIFFE ID %q is not pinned", e.Got)
+}
+
+// UpstreamTLSConfig builds the client tls.Config for an upstream.
+func UpstreamTLSConfig(opts UpstreamOptions) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.ServerName,
+	}
+
+	if opts.CAFile != "" {
+		pool, err := LoadCABundle(opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, errors.New("client certificate and key must be set together")
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(opts.SPIFFEIDs) > 0 {
+		pinned := make(map[string]bool, len(opts.SPIFFEIDs))
+		for _, id := range opts.SPIFFEIDs {
+			pinned[normalizeSPIFFEID(id)] = true
+		}
+
+		// Runs after the chain and host name have been verified
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return &PinError{}
+			}
+			id, err := SPIFFEID(cs.PeerCertificates[0])
+			if err != nil {
+				return &PinError{Err: err}
+			}
+			if !pinned[id] {
+				return &PinError{Got: id}
+			}
+			return nil
+		}
+	}
+
+	return cfg, nil
+}
+
+// IsTLSError reports whether err comes from a failed TLS handshake or
+// certificate check, as opposed to e.g. a refused connection.
+func IsTLSError(err error) bool {
+	var (
+		pinErr      *PinError
+		verifyErr   *tls.CertificateVerificationError
+		unknownCA   x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidErr  x509.CertificateInvalidError
+		recordErr   tls.RecordHeaderError
+		opErr       *net.OpError
+	)
+	if errors.As(err, &pinErr) ||
+		errors.As(err, &verifyErr) ||
+		errors.As(err, &unknownCA) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) ||
+		errors.As(err, &recordErr) {
+		return true
+	}
+
+	// The upstream rejected us (e.g. no or untrusted client certificate):
+	// crypto/tls reports its alert as a "remote error"
+	return errors.As(err, &opErr) && opErr.Op == "remote error"
+}
@@ -0,0 +1,323 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+GATEWAY CONFIGURATION
+
+Precedence (lowest to highest):
+ 1. Built-in defaults (Default)
+ 2. YAML config file
+ 3. Environment variables (GATEWAY_*)
+ 4. Command-line flags
+
+Same philosophy as the policy loader:
+ Configuration is static data
+ Unknown keys are rejected (typos must not silently disable a control)
+ Validation happens BEFORE the gateway starts
+ Any error is fatal: the gateway never runs with a half-valid config
+*/
+
+type Config struct {
+	Server     ServerConfig     `yaml:"server"`
+	Upstreams  []UpstreamConfig `yaml:"upstreams"`
+	Routes     []RouteConfig    `yaml:"routes"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Audit      AuditConfig      `yaml:"audit"`
+	Policy     PolicyConfig     `yaml:"policy"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	Validation ValidationConfig `yaml:"validation"`
+	Admin      AdminConfig      `yaml:"admin"`
+	Dashboard  DashboardConfig  `yaml:"dashboard"`
+}
+
+type ServerConfig struct {
+	Listen       string        `yaml:"listen"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	TLS          TLSConfig     `yaml:"tls"`
+}
+
+// TLSConfig enables TLS on server.listen when CertFile is set.
+// Certificate, key and CA files are polled every ReloadInterval.
+type TLSConfig struct {
+	// Default certificate, served when no other one matches the SNI
+	CertFile     string              `yaml:"cert_file"`
+	KeyFile      string              `yaml:"key_file"`
+	Certificates []CertificateConfig `yaml:"certificates"` // extra, selected by SNI
+
+	MinVersion   string   `yaml:"min_version"`   // "1.2" (default) or "1.3"
+	CipherSuites []string `yaml:"cipher_suites"` // TLS 1.2 only; empty = Go defaults
+
+	// Client certificates: "none" (default), "optional" or "required".
+	// Anything but none needs ClientCAFile.
+	ClientAuth     string        `yaml:"client_auth"`
+	ClientCAFile   string        `yaml:"client_ca_file"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+
+	// Plain-HTTP listener that only redirects to HTTPS; empty = off
+	RedirectListen string `yaml:"redirect_listen"`
+}
+
+// CertificateConfig is a PEM certificate chain and its key.
+type CertificateConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// UpstreamConfig is a named service with one or more instances.
+type UpstreamConfig struct {
+	Name        string            `yaml:"name"`
+	Targets     []string          `yaml:"targets"`
+	Strategy    string            `yaml:"strategy"`
+	HealthCheck HealthCheckConfig `yaml:"health_check"`
+	Passive     PassiveConfig     `yaml:"passive"`
+	TLS         UpstreamTLSConfig `yaml:"tls"`
+}
+
+// UpstreamTLSConfig applies to https targets. Files are read at startup.
+type UpstreamTLSConfig struct {
+	CAFile     string   `yaml:"ca_file"`   // empty = system roots
+	CertFile   string   `yaml:"cert_file"` // gateway client certificate
+	KeyFile    string   `yaml:"key_file"`
+	ServerName string   `yaml:"server_name"` // verify against this name, not the target host
+	SPIFFEIDs  []string `yaml:"spiffe_ids"`  // upstream must present one of these
+}
+
+// IsSet reports whether any upstream TLS setting is configured.
+func (t UpstreamTLSConfig) IsSet() bool {
+	return t.CAFile != "" || t.CertFile != "" || t.KeyFile != "" || t.ServerName != "" || len(t.SPIFFEIDs) > 0
+}
+
+// HealthCheckConfig enables active probing when Path is set.
+type HealthCheckConfig struct {
+	Path     string        `yaml:"path"`
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// PassiveConfig ejects an instance after MaxFailures consecutive
+// 5xx responses or connection errors. Zero disables it.
+type PassiveConfig struct {
+	MaxFailures  int           `yaml:"max_failures"`
+	EjectionTime time.Duration `yaml:"ejection_time"`
+}
+
+// RouteConfig maps requests to a named upstream.
+// Routes are matched in order; unmatched requests are denied.
+type RouteConfig struct {
+	Name          string        `yaml:"name"`
+	PathPrefix    string        `yaml:"path_prefix"`
+	Hosts         []string      `yaml:"hosts"`
+	Methods       []string      `yaml:"methods"`
+	Upstream      string        `yaml:"upstream"`
+	StripPrefix   bool          `yaml:"strip_prefix"`
+	ReplacePrefix string        `yaml:"replace_prefix"`
+	Timeout       time.Duration `yaml:"timeout"`
+}
+
+// AuthConfig lists the accepted authentication schemes, tried in order.
+type AuthConfig struct {
+	Schemes []string     `yaml:"schemes"` // "jwt", "api_key", "mtls"
+	JWT     JWTConfig    `yaml:"jwt"`
+	APIKeys APIKeyConfig `yaml:"api_keys"`
+	MTLS    MTLSConfig   `yaml:"mtls"`
+}
+
+// HasScheme reports whether scheme is one of the accepted schemes.
+func (a AuthConfig) HasScheme(scheme string) bool {
+	for _, s := range a.Schemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// MTLSConfig maps client certificate attributes to roles.
+type MTLSConfig struct {
+	Roles []CertRoleConfig `yaml:"roles"`
+}
+
+// CertRoleConfig grants Role to certificates matching every set field.
+type CertRoleConfig struct {
+	Role               string `yaml:"role"`
+	SPIFFEID           string `yaml:"spiffe_id"`
+	SPIFFEIDPrefix     string `yaml:"spiffe_id_prefix"`
+	CommonName         string `yaml:"common_name"`
+	Organization       string `yaml:"organization"`
+	OrganizationalUnit string `yaml:"organizational_unit"`
+	DNSName            string `yaml:"dns_name"`
+}
+
+// APIKeyConfig selects the API key store. With no file the built-in
+// demo keys are used.
+type APIKeyConfig struct {
+	File           string        `yaml:"file"` // hashed key file (YAML or JSON)
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+
+	// Demo accepts the well-known built-in demo keys instead of the key
+	// file. Local development only: rejected together with TLS.
+	Demo bool `yaml:"demo"`
+
+	// Last-used times per key ID; empty = tracked in memory only
+	UsageFile          string        `yaml:"usage_file"`
+	UsageFlushInterval time.Duration `yaml:"usage_flush_interval"`
+}
+
+// JWTConfig is required when the "jwt" scheme is enabled.
+type JWTConfig struct {
+	Issuers []JWTIssuerConfig `yaml:"issuers"`
+}
+
+// JWTIssuerConfig describes one trusted issuer.
+// Exactly one key source must be set: a static PEM key, a JWKS URL or
+// a JWKS file.
+type JWTIssuerConfig struct {
+	Issuer        string        `yaml:"issuer"`
+	Audiences     []string      `yaml:"audiences"`
+	Leeway        time.Duration `yaml:"leeway"`          // clock skew for exp/nbf/iat
+	RolesClaim    string        `yaml:"roles_claim"`     // dotted path; default "roles"
+	PublicKeyFile string        `yaml:"public_key_file"` // PEM: RSA, ECDSA P-256 or Ed25519
+	Algorithms    []string      `yaml:"algorithms"`      // allow-list; default [RS256]
+
+	JWKSURL                string        `yaml:"jwks_url"`
+	JWKSFile               string        `yaml:"jwks_file"`
+	JWKSTTL                time.Duration `yaml:"jwks_ttl"`
+	JWKSMinRefreshInterval time.Duration `yaml:"jwks_min_refresh_interval"`
+}
+
+// AdminConfig enables the admin API on its own listener.
+// Callers authenticate with auth.schemes and must hold Role.
+// The listener uses server.tls; without it, Insecure must be set.
+type AdminConfig struct {
+	Listen   string `yaml:"listen"` // empty = admin API disabled
+	Role     string `yaml:"role"`
+	Insecure bool   `yaml:"insecure"` // explicit opt-in to plain HTTP
+}
+
+// DashboardConfig controls access to the dashboard. Callers log in with
+// auth.schemes and must hold Role.
+type DashboardConfig struct {
+	Role       string        `yaml:"role"`
+	SessionTTL time.Duration `yaml:"session_ttl"`
+}
+
+type AuditConfig struct {
+	Path string `yaml:"path"`
+}
+
+type PolicyConfig struct {
+	Path           string        `yaml:"path"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+	ShadowPath     string        `yaml:"shadow_path"` // candidate, audit only; empty = off
+}
+
+type RateLimitConfig struct {
+	IPCapacity   int `yaml:"ip_capacity"`
+	IPRefillPS   int `yaml:"ip_refill_per_second"`
+	UserCapacity int `yaml:"user_capacity"`
+	UserRefillPS int `yaml:"user_refill_per_second"`
+}
+
+type ValidationConfig struct {
+	MaxBodyBytes        int64    `yaml:"max_body_bytes"`
+	AllowedContentTypes []string `yaml:"allowed_content_types"`
+	RequiredHeaders     []string `yaml:"required_headers"`
+	LowercasePaths      bool     `yaml:"lowercase_paths"`
+}
+
+// DefaultAPIKeyFile is the default hashed key file. A missing file
+// accepts no API key; the demo keys are never a fallback.
+const DefaultAPIKeyFile = "./config/api_keys.yaml"
+
+// Default returns the configuration the gateway used before it was
+// configurable. Every field is set, so a partial file only overrides
+// what it mentions.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Listen:       ":8080",
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+			IdleTimeout:  60 * time.Second,
+			TLS:          TLSConfig{MinVersion: "1.2", ClientAuth: "none", ReloadInterval: 5 * time.Second},
+		},
+		Upstreams: []UpstreamConfig{
+			{Name: "demo", Targets: []string{"http://localhost:9000"}},
+		},
+		Routes: []RouteConfig{
+			{Name: "default", PathPrefix: "/", Upstream: "demo", Timeout: 10 * time.Second},
+		},
+		Auth: AuthConfig{
+			Schemes: []string{"api_key"},
+			APIKeys: APIKeyConfig{File: DefaultAPIKeyFile, ReloadInterval: 5 * time.Second, UsageFlushInterval: 30 * time.Second},
+		},
+		Audit: AuditConfig{
+			Path: "./audit.log",
+		},
+		Policy: PolicyConfig{
+			Path:           "./policies/policies.yaml",
+			ReloadInterval: 5 * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			IPCapacity:   20,
+			IPRefillPS:   5,
+			UserCapacity: 40,
+			UserRefillPS: 10,
+		},
+		Validation: ValidationConfig{
+			MaxBodyBytes:        1 << 20,
+			AllowedContentTypes: []string{"application/json", "text/plain"},
+			RequiredHeaders:     []string{"User-Agent"},
+		},
+		Admin: AdminConfig{
+			Role: "gateway-admin",
+		},
+		Dashboard: DashboardConfig{
+			Role:       "dashboard-viewer",
+			SessionTTL: time.Hour,
+		},
+	}
+}
+
+// LoadFile reads a YAML config file on top of the defaults.
+// The result is NOT validated; call Validate once all overrides are applied.
+func LoadFile(path string) (Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := decodeStrict(data, &cfg); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func decodeStrict(data []byte, cfg *Config) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(cfg); err != nil {
+		// An empty file is a valid "all defaults" config
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("invalid config YAML: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gateway.yaml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func noEnv(string) string { return "" }
+
+func TestDefaultsAreValid(t *testing.T) {
+	if err := Validate(Default()); err != nil {
+		t.Fatalf("expected defaults to validate, got %v", err)
+	}
+}
+
+func TestPartialFileKeepsDefaults(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  listen: ":9090"
+rate_limit:
+  ip_capacity: 100
+`)
+
+	cfg, err := Load([]string{"-config", path}, noEnv)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+
+	if cfg.Server.Listen != ":9090" {
+		t.Fatalf("expected listen :9090, got %s", cfg.Server.Listen)
+	}
+	if cfg.RateLimit.IPCapacity != 100 {
+		t.Fatalf("expected ip_capacity 100, got %d", cfg.RateLimit.IPCapacity)
+	}
+	if cfg.Server.ReadTimeout != 10*time.Second {
+		t.Fatalf("expected default read timeout, got %v", cfg.Server.ReadTimeout)
+	}
+	if len(cfg.Upstreams) != 1 || cfg.Upstreams[0].Targets[0] != "http://localhost:9000" {
+		t.Fatalf("expected default upstream, got %+v", cfg.Upstreams)
+	}
+}
+
+func TestUnknownKeyRejected(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  listne: ":9090"
+`)
+
+	if _, err := Load([]string{"-config", path}, noEnv); err == nil {
+		t.Fatal("expected unknown key to be rejected")
+	}
+}
+
+func TestExplicitMissingFileFails(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "nope.yaml")
+
+	if _, err := Load([]string{"-config", missing}, noEnv); err == nil {
+		t.Fatal("expected error for missing explicit config file")
+	}
+}
+
+func TestInvalidValuesRejected(t *testing.T) {
+	cases := map[string]string{
+		"zero timeout":             "server:\n  read_timeout: 0s\n",
+		"bad listen":               "server:\n  listen: \"8080\"\n",
+		"bad scheme":               "upstreams:\n  - name: a\n    targets: [ftp://localhost:9000]\n",
+		"no host":                  "upstreams:\n  - name: a\n    targets: [http://]\n",
+		"no upstreams":             "upstreams: []\n",
+		"no targets":               "upstreams:\n  - name: demo\n    targets: []\n",
+		"bad strategy":             "upstreams:\n  - name: demo\n    targets: [http://a:1]\n    strategy: random\n",
+		"health timeout":           "upstreams:\n  - name: demo\n    targets: [http://a:1]\n    health_check:\n      path: /health\n      interval: 1s\n      timeout: 2s\n",
+		"health path":              "upstreams:\n  - name: demo\n    targets: [http://a:1]\n    health_check:\n      path: health\n",
+		"no routes":                "routes: []\n",
+		"unknown upstream":         "routes:\n  - name: r\n    path_prefix: /\n    upstream: nope\n",
+		"relative prefix":          "routes:\n  - name: r\n    path_prefix: api\n    upstream: demo\n",
+		"lower method":             "routes:\n  - name: r\n    path_prefix: /\n    upstream: demo\n    methods: [get]\n",
+		"both rewrites":            "routes:\n  - name: r\n    path_prefix: /api\n    upstream: demo\n    strip_prefix: true\n    replace_prefix: /v1\n",
+		"duplicate route":          "routes:\n  - name: r\n    path_prefix: /a\n    upstream: demo\n  - name: r\n    path_prefix: /b\n    upstream: demo\n",
+		"zero rate":                "rate_limit:\n  ip_refill_per_second: 0\n",
+		"negative body":            "validation:\n  max_body_bytes: -1\n",
+		"no content types":         "validation:\n  allowed_content_types: []\n",
+		"empty policy path":        "policy:\n  path: \"\"\n",
+		"shadow is active policy":  "policy:\n  path: ./p.yaml\n  shadow_path: p.yaml\n",
+		"no auth schemes":          "auth:\n  schemes: []\n",
+		"unknown scheme":           "auth:\n  schemes: [basic]\n",
+		"duplicate scheme":         "auth:\n  schemes: [api_key, api_key]\n",
+		"key reload zero":          "auth:\n  api_keys:\n    file: keys.yaml\n    reload_interval: 0s\n",
+		"admin bad listen":         "admin:\n  listen: localhost\n",
+		"admin shared port":        "server:\n  listen: \":8080\"\nadmin:\n  listen: \":8080\"\n  insecure: true\n",
+		"admin no role":            "admin:\n  listen: 127.0.0.1:9090\n  role: \"\"\n  insecure: true\n",
+		"api_key no file":          "auth:\n  api_keys:\n    file: \"\"\n",
+		"demo keys with tls":       tlsBase + "auth:\n  api_keys:\n    demo: true\n",
+		"admin plaintext":          "admin:\n  listen: 127.0.0.1:9090\n",
+		"admin insecure with tls":  tlsBase + "admin:\n  listen: 127.0.0.1:9090\n  insecure: true\n",
+		"dashboard no role":        "dashboard:\n  role: \"\"\n",
+		"session ttl zero":         "dashboard:\n  session_ttl: 0s\n",
+		"jwt no issuers":           "auth:\n  schemes: [jwt]\n",
+		"jwt without key":          "auth:\n  schemes: [jwt]\n  jwt:\n    issuers:\n      - issuer: idp\n        audiences: [gw]\n",
+		"jwt two sources":          "auth:\n  schemes: [jwt]\n  jwt:\n    issuers:\n      - issuer: idp\n        audiences: [gw]\n        public_key_file: k.pem\n        jwks_file: k.json\n",
+		"hmac algorithm":           "auth:\n  schemes: [jwt]\n  jwt:\n    issuers:\n      - issuer: idp\n        audiences: [gw]\n        public_key_file: k.pem\n        algorithms: [HS256]\n",
+		"jwks over http":           "auth:\n  schemes: [jwt]\n  jwt:\n    issuers:\n      - issuer: idp\n        audiences: [gw]\n        jwks_url: http://idp/jwks\n",
+		"huge leeway":              "auth:\n  schemes: [jwt]\n  jwt:\n    issuers:\n      - issuer: idp\n        audiences: [gw]\n        public_key_file: k.pem\n        leeway: 1h\n",
+		"bad roles claim":          "auth:\n  schemes: [jwt]\n  jwt:\n    issuers:\n      - issuer: idp\n        audiences: [gw]\n        public_key_file: k.pem\n        roles_claim: realm_access..roles\n",
+		"no audiences":             "auth:\n  schemes: [jwt]\n  jwt:\n    issuers:\n      - issuer: idp\n        public_key_file: k.pem\n",
+		"duplicate issuer":         "auth:\n  schemes: [jwt]\n  jwt:\n    issuers:\n      - issuer: idp\n        audiences: [gw]\n        public_key_file: k.pem\n      - issuer: idp\n        audiences: [gw]\n        public_key_file: k.pem\n",
+		"tls key missing":          "server:\n  tls:\n    cert_file: gw.crt\n",
+		"bad client auth":          "server:\n  tls:\n    cert_file: gw.crt\n    key_file: gw.key\n    client_auth: maybe\n",
+		"client auth no tls":       "server:\n  tls:\n    client_auth: required\n    client_ca_file: ca.pem\n",
+		"client auth no ca":        "server:\n  tls:\n    cert_file: gw.crt\n    key_file: gw.key\n    client_auth: optional\n",
+		"mtls without client auth": "server:\n  tls:\n    cert_file: gw.crt\n    key_file: gw.key\nauth:\n  schemes: [mtls]\n",
+		"mtls empty mapping":       mtlsBase + "  mtls:\n    roles:\n      - role: admin\n",
+		"mtls mapping no role":     mtlsBase + "  mtls:\n    roles:\n      - common_name: svc\n",
+		"mtls bad spiffe":          mtlsBase + "  mtls:\n    roles:\n      - role: admin\n        spiffe_id_prefix: example.org/\n",
+		"tls 1.1":                  tlsBase + "    min_version: \"1.1\"\n",
+		"insecure cipher":          tlsBase + "    cipher_suites: [TLS_RSA_WITH_RC4_128_SHA]\n",
+		"ciphers with tls 1.3":     tlsBase + "    min_version: \"1.3\"\n    cipher_suites: [TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256]\n",
+		"sni cert without key":     tlsBase + "    certificates:\n      - cert_file: api.crt\n",
+		"redirect same port":       tlsBase + "    redirect_listen: \":8080\"\n",
+		"redirect without tls":     "server:\n  tls:\n    redirect_listen: \":80\"\n",
+		"tls reload zero":          tlsBase + "    reload_interval: 0s\n",
+		"upstream tls over http":   "upstreams:\n  - name: demo\n    targets: [http://a:1]\n    tls:\n      ca_file: ca.pem\n",
+		"upstream cert no key":     "upstreams:\n  - name: demo\n    targets: [https://a:1]\n    tls:\n      cert_file: gw.crt\n",
+		"upstream bad spiffe":      "upstreams:\n  - name: demo\n    targets: [https://a:1]\n    tls:\n      spiffe_ids: [example.org/users]\n",
+	}
+
+	for name, data := range cases {
+		path := writeConfig(t, data)
+		if _, err := Load([]string{"-config", path}, noEnv); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+const tlsBase = "server:\n  listen: \":8080\"\n  tls:\n    cert_file: gw.crt\n    key_file: gw.key\n"
+
+const mtlsBase = "server:\n  tls:\n    cert_file: gw.crt\n    key_file: gw.key\n    client_auth: required\n    client_ca_file: ca.pem\nauth:\n  schemes: [mtls]\n"
+
+func TestMTLSConfigLoads(t *testing.T) {
+	path := writeConfig(t, mtlsBase+`  mtls:
+    roles:
+      - role: payments
+        spiffe_id_prefix: spiffe://example.org/ns/prod/
+      - role: admin
+        common_name: ops-console
+        organization: Ops
+`)
+
+	cfg, err := Load([]string{"-config", path}, noEnv)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if cfg.Server.TLS.ClientAuth != "required" || cfg.Server.TLS.ReloadInterval <= 0 {
+		t.Fatalf("unexpected tls config %+v", cfg.Server.TLS)
+	}
+	if len(cfg.Auth.MTLS.Roles) != 2 || cfg.Auth.MTLS.Roles[1].Organization != "Ops" {
+		t.Fatalf("unexpected mtls roles %+v", cfg.Auth.MTLS.Roles)
+	}
+}
+
+func TestTLSConfigLoads(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  listen: ":8443"
+  tls:
+    cert_file: gw.crt
+    key_file: gw.key
+    certificates:
+      - cert_file: api.crt
+        key_file: api.key
+    min_version: "1.2"
+    cipher_suites: [TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256]
+    redirect_listen: ":8080"
+admin:
+  listen: 127.0.0.1:9443
+`)
+
+	cfg, err := Load([]string{"-config", path}, noEnv)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	tls := cfg.Server.TLS
+	if len(tls.Certificates) != 1 || len(tls.CipherSuites) != 2 || tls.RedirectListen != ":8080" {
+		t.Fatalf("unexpected tls config %+v", tls)
+	}
+}
+
+func TestOverridePrecedence(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  listen: ":1111"
+audit:
+  path: /file/audit.log
+policy:
+  path: /file/policies.yaml
+`)
+
+	env := map[string]string{
+		EnvConfig:    path,
+		EnvListen:    ":2222",
+		EnvAuditPath: "/env/audit.log",
+	}
+
+	cfg, err := Load([]string{"-listen", ":3333"}, func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+
+	if cfg.Server.Listen != ":3333" {
+		t.Fatalf("expected flag to win, got %s", cfg.Server.Listen)
+	}
+	if cfg.Audit.Path != "/env/audit.log" {
+		t.Fatalf("expected env to beat file, got %s", cfg.Audit.Path)
+	}
+	if cfg.Policy.Path != "/file/policies.yaml" {
+		t.Fatalf("expected file value, got %s", cfg.Policy.Path)
+	}
+}
+
+func TestEmptyFlagValueFailsClosed(t *testing.T) {
+	path := writeConfig(t, "")
+
+	_, err := Load([]string{"-config", path, "-listen", ""}, noEnv)
+	if err == nil || !strings.Contains(err.Error(), "server.listen") {
+		t.Fatalf("expected server.listen error, got %v", err)
+	}
+}
+
+func TestRoutingTableLoads(t *testing.T) {
+	path := writeConfig(t, `
+upstreams:
+  - name: users
+    targets: [http://localhost:9001, http://localhost:9003]
+    strategy: least_connections
+    health_check:
+      path: /health
+      interval: 5s
+      timeout: 1s
+    passive:
+      max_failures: 3
+      ejection_time: 30s
+  - name: billing
+    targets: [http://localhost:9002]
+routes:
+  - name: users-api
+    path_prefix: /api/users
+    methods: [GET, POST]
+    upstream: users
+    strip_prefix: true
+  - name: billing-api
+    path_prefix: /api/billing
+    hosts: [billing.internal]
+    upstream: billing
+    replace_prefix: /v2
+    timeout: 3s
+`)
+
+	cfg, err := Load([]string{"-config", path}, noEnv)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+
+	if len(cfg.Routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(cfg.Routes))
+	}
+	if cfg.Routes[1].Timeout != 3*time.Second || cfg.Routes[1].ReplacePrefix != "/v2" {
+		t.Fatalf("unexpected billing route: %+v", cfg.Routes[1])
+	}
+}
+
+func TestMultipleJWTIssuersLoad(t *testing.T) {
+	path := writeConfig(t, `
+auth:
+  schemes: [jwt, api_key]
+  jwt:
+    issuers:
+      - issuer: https://corp-idp.example.com
+        audiences: [zero-trust-gateway]
+        jwks_url: https://corp-idp.example.com/jwks.json
+        roles_claim: realm_access.roles
+        leeway: 30s
+      - issuer: https://partner.example.com
+        audiences: [gateway, gateway-v2]
+        public_key_file: ./keys/partner.pem
+        algorithms: [ES256]
+        roles_claim: scope
+`)
+
+	cfg, err := Load([]string{"-config", path}, noEnv)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+
+	issuers := cfg.Auth.JWT.Issuers
+	if len(issuers) != 2 || issuers[0].Leeway != 30*time.Second || len(issuers[1].Audiences) != 2 {
+		t.Fatalf("unexpected issuers: %+v", issuers)
+	}
+}
+
+func TestDemoKeysAreExplicitOptIn(t *testing.T) {
+	cfg, err := Load([]string{"-config", writeConfig(t, "auth:\n  api_keys:\n    demo: true\n")}, noEnv)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if !cfg.Auth.APIKeys.Demo {
+		t.Fatal("expected demo keys enabled")
+	}
+
+	if Default().Auth.APIKeys.Demo || Default().Auth.APIKeys.File == "" {
+		t.Fatal("defaults must use a key file, never the demo keys")
+	}
+}
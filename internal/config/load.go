@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+)
+
+// DefaultPath is used when neither -config nor GATEWAY_CONFIG is set.
+// A missing file at the default path means "use built-in defaults";
+// a missing file at an explicitly requested path is an error.
+const DefaultPath = "./config/gateway.yaml"
+
+// Environment variables recognised by Load.
+const (
+	EnvConfig    = "GATEWAY_CONFIG"
+	EnvListen    = "GATEWAY_LISTEN"
+	EnvAuditPath = "GATEWAY_AUDIT_LOG"
+	EnvPolicy    = "GATEWAY_POLICIES"
+)
+
+// Load builds the effective, validated configuration from command-line
+// arguments (without the program name) and the environment.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	fset := flag.NewFlagSet("gateway", flag.ContinueOnError)
+
+	configPath := fset.String("config", "", "path to gateway config file (default "+DefaultPath+")")
+	listen := fset.String("listen", "", "listen address, e.g. :8080")
+	auditPath := fset.String("audit-log", "", "audit log file path")
+	policyPath := fset.String("policies", "", "policy file path")
+
+	if err := fset.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if fset.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected arguments: %v", fset.Args())
+	}
+
+	// 1 + 2. Defaults, then file
+	path := firstNonEmpty(*configPath, getenv(EnvConfig))
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		if explicit || !errors.Is(err, fs.ErrNotExist) {
+			return Config{}, err
+		}
+		cfg = Default()
+	}
+
+	// 3. Environment
+	override(&cfg.Server.Listen, getenv(EnvListen))
+	override(&cfg.Audit.Path, getenv(EnvAuditPath))
+	override(&cfg.Policy.Path, getenv(EnvPolicy))
+
+	// 4. Flags (only those actually passed)
+	fset.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			cfg.Server.Listen = *listen
+		case "audit-log":
+			cfg.Audit.Path = *auditPath
+		case "policies":
+			cfg.Policy.Path = *policyPath
+		}
+	})
+
+	if err := Validate(cfg); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func override(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
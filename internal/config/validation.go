@@ -0,0 +1,533 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+	"Zero-TrustAPIGateWayServer/internal/certs"
+)
+
+/*
+Validation rules are intentionally strict.
+A gateway that starts with a loose config is a security risk.
+*/
+
+// Validate checks every field. The first problem found is returned.
+func Validate(cfg Config) error {
+	if err := validateServer(cfg.Server); err != nil {
+		return err
+	}
+
+	if err := validateRouting(cfg.Upstreams, cfg.Routes); err != nil {
+		return err
+	}
+
+	if err := validateAuth(cfg.Auth, cfg.Server.TLS); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(cfg.Audit.Path) == "" {
+		return configError("audit.path", "is required")
+	}
+
+	if strings.TrimSpace(cfg.Policy.Path) == "" {
+		return configError("policy.path", "is required")
+	}
+	if cfg.Policy.ReloadInterval <= 0 {
+		return configError("policy.reload_interval", "must be positive")
+	}
+	if cfg.Policy.ShadowPath != "" && filepath.Clean(cfg.Policy.ShadowPath) == filepath.Clean(cfg.Policy.Path) {
+		return configError("policy.shadow_path", "must differ from policy.path")
+	}
+
+	if err := validateRateLimit(cfg.RateLimit); err != nil {
+		return err
+	}
+
+	if err := validateValidation(cfg.Validation); err != nil {
+		return err
+	}
+
+	if err := validateAdmin(cfg.Admin, cfg.Server); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(cfg.Dashboard.Role) == "" {
+		return configError("dashboard.role", "is required")
+	}
+	if cfg.Dashboard.SessionTTL <= 0 {
+		return configError("dashboard.session_ttl", "must be positive")
+	}
+	return nil
+}
+
+func validateAdmin(a AdminConfig, s ServerConfig) error {
+	if a.Listen == "" {
+		return nil
+	}
+
+	if _, _, err := net.SplitHostPort(a.Listen); err != nil {
+		return configError("admin.listen", "must be host:port")
+	}
+	// Sharing the public listener would expose the admin API to it
+	if a.Listen == s.Listen || a.Listen == s.TLS.RedirectListen {
+		return configError("admin.listen", "must differ from server.listen and server.tls.redirect_listen")
+	}
+	if strings.TrimSpace(a.Role) == "" {
+		return configError("admin.role", "is required when the admin API is enabled")
+	}
+	// Admin credentials must not cross the network in cleartext by default
+	tlsEnabled := s.TLS.CertFile != ""
+	if !tlsEnabled && !a.Insecure {
+		return configError("admin.insecure", "must be true to serve the admin API without server.tls")
+	}
+	if tlsEnabled && a.Insecure {
+		return configError("admin.insecure", "must not be set with server.tls (the admin API always uses TLS)")
+	}
+	return nil
+}
+
+func validateServer(s ServerConfig) error {
+	if strings.TrimSpace(s.Listen) == "" {
+		return configError("server.listen", "is required")
+	}
+	if _, _, err := net.SplitHostPort(s.Listen); err != nil {
+		return configError("server.listen", "must be host:port")
+	}
+
+	// Zero timeouts mean "no timeout" in net/http; never allow that
+	if s.ReadTimeout <= 0 {
+		return configError("server.read_timeout", "must be positive")
+	}
+	if s.WriteTimeout <= 0 {
+		return configError("server.write_timeout", "must be positive")
+	}
+	if s.IdleTimeout <= 0 {
+		return configError("server.idle_timeout", "must be positive")
+	}
+
+	if s.TLS.RedirectListen != "" && s.TLS.RedirectListen == s.Listen {
+		return configError("server.tls.redirect_listen", "must differ from server.listen")
+	}
+	return validateTLS(s.TLS)
+}
+
+func validateTLS(t TLSConfig) error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return configError("server.tls", "cert_file and key_file must be set together")
+	}
+
+	if t.CertFile == "" {
+		// Without a default certificate nothing else here can take effect
+		switch {
+		case len(t.Certificates) > 0:
+			return configError("server.tls.certificates", "requires cert_file and key_file")
+		case len(t.CipherSuites) > 0:
+			return configError("server.tls.cipher_suites", "requires cert_file and key_file")
+		case t.RedirectListen != "":
+			return configError("server.tls.redirect_listen", "requires cert_file and key_file")
+		case t.ClientAuth != "" && t.ClientAuth != "none":
+			return configError("server.tls.client_auth", "requires cert_file and key_file")
+		}
+		return nil
+	}
+
+	for i, c := range t.Certificates {
+		if strings.TrimSpace(c.CertFile) == "" || strings.TrimSpace(c.KeyFile) == "" {
+			return configError(indexed("server.tls.certificates", i), "requires cert_file and key_file")
+		}
+	}
+
+	version, err := certs.ParseVersion(t.MinVersion)
+	if err != nil {
+		return configError("server.tls.min_version", "must be 1.2 or 1.3")
+	}
+	if _, err := certs.ParseCipherSuites(t.CipherSuites); err != nil {
+		return configError("server.tls.cipher_suites", err.Error())
+	}
+	// TLS 1.3 suites are fixed; a list here would silently do nothing
+	if version == tls.VersionTLS13 && len(t.CipherSuites) > 0 {
+		return configError("server.tls.cipher_suites", "have no effect with min_version 1.3")
+	}
+
+	if t.ReloadInterval <= 0 {
+		return configError("server.tls.reload_interval", "must be positive")
+	}
+
+	switch t.ClientAuth {
+	case "", "none":
+	case "optional", "required":
+		if strings.TrimSpace(t.ClientCAFile) == "" {
+			return configError("server.tls.client_ca_file", "is required when client_auth is enabled")
+		}
+	default:
+		return configError("server.tls.client_auth", "must be none, optional or required")
+	}
+
+	if t.RedirectListen != "" {
+		if _, _, err := net.SplitHostPort(t.RedirectListen); err != nil {
+			return configError("server.tls.redirect_listen", "must be host:port")
+		}
+	}
+	return nil
+}
+
+func validateRouting(upstreams []UpstreamConfig, routes []RouteConfig) error {
+	if len(upstreams) == 0 {
+		return configError("upstreams", "must not be empty")
+	}
+
+	names := make(map[string]bool, len(upstreams))
+	for i, u := range upstreams {
+		field := indexed("upstreams", i)
+
+		if strings.TrimSpace(u.Name) == "" {
+			return configError(field+".name", "is required")
+		}
+		if names[u.Name] {
+			return configError(field+".name", "is duplicated")
+		}
+		names[u.Name] = true
+
+		if err := validateUpstream(field, u); err != nil {
+			return err
+		}
+	}
+
+	// No routes would deny everything; that is almost certainly a mistake
+	if len(routes) == 0 {
+		return configError("routes", "must not be empty")
+	}
+
+	routeNames := make(map[string]bool, len(routes))
+	for i, r := range routes {
+		field := indexed("routes", i)
+
+		if strings.TrimSpace(r.Name) == "" {
+			return configError(field+".name", "is required")
+		}
+		if routeNames[r.Name] {
+			return configError(field+".name", "is duplicated")
+		}
+		routeNames[r.Name] = true
+
+		if !strings.HasPrefix(r.PathPrefix, "/") {
+			return configError(field+".path_prefix", "must start with '/'")
+		}
+		if !names[r.Upstream] {
+			return configError(field+".upstream", "refers to an unknown upstream")
+		}
+
+		for _, h := range r.Hosts {
+			if strings.TrimSpace(h) == "" || strings.Contains(h, "/") {
+				return configError(field+".hosts", "entries must be bare host names")
+			}
+		}
+		for _, m := range r.Methods {
+			if !isMethodToken(m) {
+				return configError(field+".methods", "entries must be upper-case HTTP methods")
+			}
+		}
+
+		if r.StripPrefix && r.ReplacePrefix != "" {
+			return configError(field, "strip_prefix and replace_prefix are mutually exclusive")
+		}
+		if r.ReplacePrefix != "" && !strings.HasPrefix(r.ReplacePrefix, "/") {
+			return configError(field+".replace_prefix", "must start with '/'")
+		}
+		if r.Timeout < 0 {
+			return configError(field+".timeout", "must not be negative")
+		}
+	}
+
+	return nil
+}
+
+func validateUpstream(field string, u UpstreamConfig) error {
+	if len(u.Targets) == 0 {
+		return configError(field+".targets", "must not be empty")
+	}
+
+	seen := make(map[string]bool, len(u.Targets))
+	for i, target := range u.Targets {
+		if err := validateURL(indexed(field+".targets", i), target); err != nil {
+			return err
+		}
+		if seen[target] {
+			return configError(indexed(field+".targets", i), "is duplicated")
+		}
+		seen[target] = true
+	}
+
+	switch u.Strategy {
+	case "", "round_robin", "least_connections", "consistent_hash":
+	default:
+		return configError(field+".strategy", "must be round_robin, least_connections or consistent_hash")
+	}
+
+	hc := u.HealthCheck
+	if hc.Path != "" && !strings.HasPrefix(hc.Path, "/") {
+		return configError(field+".health_check.path", "must start with '/'")
+	}
+	if hc.Interval < 0 || hc.Timeout < 0 {
+		return configError(field+".health_check", "interval and timeout must not be negative")
+	}
+	if hc.Interval > 0 && hc.Timeout > hc.Interval {
+		return configError(field+".health_check.timeout", "must not exceed interval")
+	}
+
+	if u.Passive.MaxFailures < 0 {
+		return configError(field+".passive.max_failures", "must not be negative")
+	}
+	if u.Passive.EjectionTime < 0 {
+		return configError(field+".passive.ejection_time", "must not be negative")
+	}
+
+	if u.TLS.IsSet() {
+		// Settings that could never apply are a mistake, not a default
+		for i, target := range u.Targets {
+			if !strings.HasPrefix(target, "https://") {
+				return configError(indexed(field+".targets", i), "must be https when tls is configured")
+			}
+		}
+		if (u.TLS.CertFile == "") != (u.TLS.KeyFile == "") {
+			return configError(field+".tls", "cert_file and key_file must be set together")
+		}
+		for i, id := range u.TLS.SPIFFEIDs {
+			if !strings.HasPrefix(id, "spiffe://") {
+				return configError(indexed(field+".tls.spiffe_ids", i), "must start with spiffe://")
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateURL(field, raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		return configError(field, "is required")
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return configError(field, "is not a valid URL")
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return configError(field, "scheme must be http or https")
+	}
+	if parsed.Host == "" {
+		return configError(field, "host is required")
+	}
+	return nil
+}
+
+func validateAuth(a AuthConfig, t TLSConfig) error {
+	// No schemes would reject every request; almost certainly a mistake
+	if len(a.Schemes) == 0 {
+		return configError("auth.schemes", "must not be empty")
+	}
+
+	seen := make(map[string]bool, len(a.Schemes))
+	for i, scheme := range a.Schemes {
+		field := indexed("auth.schemes", i)
+
+		switch scheme {
+		case "jwt", "api_key", "mtls":
+		default:
+			return configError(field, "must be jwt, api_key or mtls")
+		}
+		if seen[scheme] {
+			return configError(field, "is duplicated")
+		}
+		seen[scheme] = true
+	}
+
+	if seen["jwt"] {
+		if len(a.JWT.Issuers) == 0 {
+			return configError("auth.jwt.issuers", "must not be empty when jwt is enabled")
+		}
+
+		issuers := make(map[string]bool, len(a.JWT.Issuers))
+		for i, ic := range a.JWT.Issuers {
+			field := indexed("auth.jwt.issuers", i)
+
+			if strings.TrimSpace(ic.Issuer) == "" {
+				return configError(field+".issuer", "is required")
+			}
+			if issuers[ic.Issuer] {
+				return configError(field+".issuer", "is duplicated")
+			}
+			issuers[ic.Issuer] = true
+
+			if err := validateJWTIssuer(field, ic); err != nil {
+				return err
+			}
+		}
+	}
+
+	if seen["mtls"] {
+		// Without verification at the handshake there is no certificate to trust
+		if t.ClientAuth != "optional" && t.ClientAuth != "required" {
+			return configError("auth.schemes", "mtls requires server.tls.client_auth optional or required")
+		}
+		if err := validateCertRoles(a.MTLS.Roles); err != nil {
+			return err
+		}
+	}
+
+	// The demo keys are public knowledge: never a silent fallback
+	switch {
+	case seen["api_key"] && a.APIKeys.File == "" && !a.APIKeys.Demo:
+		return configError("auth.api_keys.file", "is required for the api_key scheme (or set auth.api_keys.demo for local development)")
+	case a.APIKeys.Demo && t.CertFile != "":
+		return configError("auth.api_keys.demo", "must not be enabled with server.tls (demo keys are for local development only)")
+	}
+
+	if a.APIKeys.File != "" && a.APIKeys.ReloadInterval <= 0 {
+		return configError("auth.api_keys.reload_interval", "must be positive")
+	}
+	if a.APIKeys.UsageFlushInterval <= 0 {
+		return configError("auth.api_keys.usage_flush_interval", "must be positive")
+	}
+
+	return nil
+}
+
+func validateJWTIssuer(field string, j JWTIssuerConfig) error {
+	if len(j.Audiences) == 0 {
+		return configError(field+".audiences", "must not be empty")
+	}
+	for _, aud := range j.Audiences {
+		if strings.TrimSpace(aud) == "" {
+			return configError(field+".audiences", "entries must not be empty")
+		}
+	}
+
+	if j.Leeway < 0 || j.Leeway > auth.MaxLeeway {
+		return configError(field+".leeway", "must be between 0 and "+auth.MaxLeeway.String())
+	}
+
+	if j.RolesClaim != "" {
+		for _, segment := range strings.Split(j.RolesClaim, ".") {
+			if strings.TrimSpace(segment) == "" {
+				return configError(field+".roles_claim", "must be a dotted claim path")
+			}
+		}
+	}
+
+	sources := 0
+	for _, v := range []string{j.PublicKeyFile, j.JWKSURL, j.JWKSFile} {
+		if strings.TrimSpace(v) != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return configError(field, "requires exactly one of public_key_file, jwks_url or jwks_file")
+	}
+
+	if j.JWKSURL != "" {
+		// Keys fetched over plain HTTP could be swapped in transit
+		parsed, err := url.Parse(j.JWKSURL)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return configError(field+".jwks_url", "must be an https URL")
+		}
+	}
+
+	for i, alg := range j.Algorithms {
+		if !auth.IsSupportedAlgorithm(alg) {
+			return configError(indexed(field+".algorithms", i), "must be one of RS256, PS256, ES256, EdDSA")
+		}
+	}
+
+	if j.JWKSTTL < 0 || j.JWKSMinRefreshInterval < 0 {
+		return configError(field, "jwks_ttl and jwks_min_refresh_interval must not be negative")
+	}
+	return nil
+}
+
+func validateCertRoles(roles []CertRoleConfig) error {
+	for i, m := range roles {
+		field := indexed("auth.mtls.roles", i)
+
+		if strings.TrimSpace(m.Role) == "" {
+			return configError(field+".role", "is required")
+		}
+		// A mapping without attributes would grant the role to every client
+		if auth.CertRoleMapping(m).IsEmpty() {
+			return configError(field, "must match at least one certificate attribute")
+		}
+		if m.SPIFFEID != "" && !strings.HasPrefix(m.SPIFFEID, "spiffe://") {
+			return configError(field+".spiffe_id", "must start with spiffe://")
+		}
+		if m.SPIFFEIDPrefix != "" && !strings.HasPrefix(m.SPIFFEIDPrefix, "spiffe://") {
+			return configError(field+".spiffe_id_prefix", "must start with spiffe://")
+		}
+	}
+	return nil
+}
+
+func validateRateLimit(r RateLimitConfig) error {
+	// Zero would mean "block everything" or "never refill"; negative is nonsense.
+	// Neither is a sane way to express "unlimited", which is not supported.
+	if r.IPCapacity <= 0 {
+		return configError("rate_limit.ip_capacity", "must be positive")
+	}
+	if r.IPRefillPS <= 0 {
+		return configError("rate_limit.ip_refill_per_second", "must be positive")
+	}
+	if r.UserCapacity <= 0 {
+		return configError("rate_limit.user_capacity", "must be positive")
+	}
+	if r.UserRefillPS <= 0 {
+		return configError("rate_limit.user_refill_per_second", "must be positive")
+	}
+	return nil
+}
+
+func validateValidation(v ValidationConfig) error {
+	if v.MaxBodyBytes <= 0 {
+		return configError("validation.max_body_bytes", "must be positive")
+	}
+
+	if len(v.AllowedContentTypes) == 0 {
+		return configError("validation.allowed_content_types", "must not be empty")
+	}
+	for _, ct := range v.AllowedContentTypes {
+		if strings.TrimSpace(ct) == "" {
+			return configError("validation.allowed_content_types", "entries must not be empty")
+		}
+	}
+
+	for _, h := range v.RequiredHeaders {
+		if strings.TrimSpace(h) == "" {
+			return configError("validation.required_headers", "entries must not be empty")
+		}
+	}
+	return nil
+}
+
+func configError(field, msg string) error {
+	return errors.New("config: " + field + " " + msg)
+}
+
+func indexed(field string, i int) string {
+	return field + "[" + strconv.Itoa(i) + "]"
+}
+
+func isMethodToken(m string) bool {
+	if m == "" {
+		return false
+	}
+	for _, c := range m {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
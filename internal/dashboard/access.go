@@ -0,0 +1,302 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+/*
+DASHBOARD ACCESS CONTROL
+
+The dashboard exposes audit entries, policies and upstream topology, so
+it is never public:
+
+- API calls (/api/dashboard/*) accept a session cookie, an API key or a
+  bearer JWT; pages (/dashboard/*) need a session and redirect to the
+  login form otherwise
+- Every caller must hold the dashboard role (default dashboard-viewer);
+  the role comes from the gateway config, not from policies.yaml
+- Login takes an API key or JWT once and issues an HttpOnly,
+  SameSite=Strict session cookie; login and logout are audited
+- Cookies are ambient credentials, so any state-changing request that
+  carries one must come from the same origin AND present the session's
+  CSRF token (X-CSRF-Token header; the logout form may send it as a
+  csrf_token field). Requests authenticated by header only are not
+  exposed to CSRF.
+- Only the login form and its stylesheet are served without a session
+*/
+
+// DefaultRole is required when Access.Role is empty.
+const DefaultRole = "dashboard-viewer"
+
+// CSRFHeader carries the session's CSRF token on API requests.
+const CSRFHeader = "X-CSRF-Token"
+
+// Access configures who may use the dashboard.
+type Access struct {
+	Role           string
+	Authenticators []auth.Authenticator // accepted at login and on API calls
+	Sessions       *auth.SessionStore
+
+	// Audit records login and logout; nil = off
+	Audit func(method, path, decision, reason string)
+}
+
+func (a Access) role() string {
+	if a.Role == "" {
+		return DefaultRole
+	}
+	return a.Role
+}
+
+// NewHandler serves /dashboard/* pages from files and /api/dashboard/*
+// from h, behind authentication and the dashboard role.
+func NewHandler(h *Handlers, files fs.FS, access Access) http.Handler {
+	static := http.StripPrefix("/dashboard", http.FileServer(http.FS(files)))
+
+	api := auth.Middleware(append(
+		[]auth.Authenticator{auth.SessionAuthenticator{Store: access.Sessions}},
+		access.Authenticators...,
+	)...)(access.requireRole(access.csrfProtect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/dashboard/session" {
+			access.serveSession(w, r)
+			return
+		}
+		h.ServeAPI(w, r)
+	}))))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dashboard", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/dashboard/", http.StatusFound)
+	})
+	mux.HandleFunc("GET /dashboard/login", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFileFS(w, r, files, "login.html")
+	})
+	mux.HandleFunc("POST /dashboard/login", access.login)
+	mux.HandleFunc("POST /dashboard/logout", access.logout)
+	mux.Handle("GET /dashboard/styles.css", static)
+	mux.Handle("/dashboard/", access.requireSession(access.requireRole(static)))
+	mux.Handle("/api/dashboard/", api)
+
+	return noStore(mux)
+}
+
+// requireSession sends browsers without a live session to the login form.
+func (a Access) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, session, ok := a.Sessions.FromRequest(r)
+		if !ok {
+			http.Redirect(w, r, "/dashboard/login", http.StatusSeeOther)
+			return
+		}
+
+		id := session.Identity
+		next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), &id)))
+	})
+}
+
+func (a Access) requireRole(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := auth.FromContext(r.Context())
+		if !ok || !hasRole(id.Roles, a.role()) {
+			http.Error(w, "access denied", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// csrfProtect guards state-changing requests that carry a session cookie.
+func (a Access) csrfProtect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isSafeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if _, err := r.Cookie(auth.SessionCookie); err == nil {
+			_, session, ok := a.Sessions.FromRequest(r)
+			if !ok || !sameOrigin(r) || !session.ValidCSRF(r.Header.Get(CSRFHeader)) {
+				http.Error(w, "invalid CSRF token", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+/*
+Login / logout
+*/
+
+func (a Access) login(w http.ResponseWriter, r *http.Request) {
+	// Login CSRF: a foreign page must not log the browser in as someone else
+	if !sameOrigin(r) {
+		a.audit(r, "DENY", "dashboard: cross-origin login rejected")
+		http.Error(w, "cross-origin request rejected", http.StatusForbidden)
+		return
+	}
+
+	id, err := a.authenticateLogin(r)
+	if err == nil && !hasRole(id.Roles, a.role()) {
+		err = errors.New(a.role() + " role required")
+	}
+	if err != nil {
+		a.audit(r, "DENY", "dashboard: login failed: "+err.Error())
+		http.Redirect(w, r, "/dashboard/login?failed=1", http.StatusSeeOther)
+		return
+	}
+
+	token, session, err := a.Sessions.Create(id)
+	if err != nil {
+		http.Error(w, "login unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(time.Until(session.ExpiresAt) / time.Second), // TTL, or sooner for a JWT
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	a.audit(r, "ALLOW", "dashboard: login "+string(id.Type)+":"+id.Subject)
+	http.Redirect(w, r, "/dashboard/", http.StatusSeeOther)
+}
+
+// authenticateLogin checks the submitted credential with the configured
+// schemes, exactly as if it had been sent in its usual header.
+func (a Access) authenticateLogin(r *http.Request) (*auth.Identity, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, errors.New("invalid form")
+	}
+
+	probe := r.Clone(r.Context())
+	probe.Header = http.Header{}
+	switch {
+	case r.PostForm.Get("api_key") != "":
+		probe.Header.Set("X-API-Key", r.PostForm.Get("api_key"))
+	case r.PostForm.Get("token") != "":
+		probe.Header.Set("Authorization", "Bearer "+r.PostForm.Get("token"))
+	default:
+		return nil, auth.ErrNoCredentials
+	}
+
+	for _, authenticator := range a.Authenticators {
+		id, err := authenticator.Authenticate(probe)
+		if errors.Is(err, auth.ErrNoCredentials) {
+			continue
+		}
+		if err != nil || id == nil {
+			return nil, errors.New("invalid credentials")
+		}
+		return id, nil
+	}
+	return nil, errors.New("credential type not accepted")
+}
+
+func (a Access) logout(w http.ResponseWriter, r *http.Request) {
+	token, session, ok := a.Sessions.FromRequest(r)
+	if !ok {
+		http.Redirect(w, r, "/dashboard/login", http.StatusSeeOther)
+		return
+	}
+
+	csrf := r.Header.Get(CSRFHeader)
+	if csrf == "" {
+		csrf = r.PostFormValue("csrf_token")
+	}
+	if !sameOrigin(r) || !session.ValidCSRF(csrf) {
+		http.Error(w, "invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	a.Sessions.Delete(token)
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	a.audit(r, "ALLOW", "dashboard: logout "+string(session.Identity.Type)+":"+session.Identity.Subject)
+	http.Redirect(w, r, "/dashboard/login", http.StatusSeeOther)
+}
+
+// serveSession tells the page who is logged in and its CSRF token.
+func (a Access) serveSession(w http.ResponseWriter, r *http.Request) {
+	id, _ := auth.FromContext(r.Context())
+	out := map[string]interface{}{
+		"subject": id.Subject,
+		"type":    id.Type,
+		"roles":   id.Roles,
+	}
+	if _, session, ok := a.Sessions.FromRequest(r); ok {
+		out["csrf_token"] = session.CSRFToken
+		out["expires_at"] = session.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+func (a Access) audit(r *http.Request, decision, reason string) {
+	if a.Audit != nil {
+		a.Audit(r.Method, r.URL.Path, decision, reason)
+	}
+}
+
+/*
+helpers
+*/
+
+// sameOrigin rejects requests that a browser marks as cross-site.
+// Non-browser clients send neither header and are allowed.
+func sameOrigin(r *http.Request) bool {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+		return true
+	}
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func hasRole(roles []string, want string) bool {
+	for _, r := range roles {
+		if r == want {
+			return true
+		}
+	}
+	return false
+}
+
+// noStore keeps dashboard data out of shared and browser caches.
+func noStore(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,272 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+	"Zero-TrustAPIGateWayServer/internal/policy"
+)
+
+const (
+	viewerKey = "viewer-key"
+	userKey   = "user-key"
+)
+
+var testFiles = fstest.MapFS{
+	"index.html": {Data: []byte("<h1>dashboard</h1>")},
+	"login.html": {Data: []byte("<form>login</form>")},
+	"styles.css": {Data: []byte("body {}")},
+}
+
+type auditCall struct{ decision, reason string }
+
+func newTestDashboard(t *testing.T) (http.Handler, *[]auditCall) {
+	t.Helper()
+
+	var audited []auditCall
+	store := auth.MapStore{
+		viewerKey: {ID: "ops", Key: viewerKey, Roles: []string{DefaultRole}},
+		userKey:   {ID: "bob", Key: userKey, Roles: []string{"user", "admin"}},
+	}
+
+	h := &Handlers{Stats: NewStatsCollector(), PolicyEngine: policy.NewEngine()}
+	handler := NewHandler(h, testFiles, Access{
+		Authenticators: []auth.Authenticator{auth.APIKeyAuthenticator{Store: store}},
+		Sessions:       auth.NewSessionStore(0),
+		Audit: func(method, path, decision, reason string) {
+			audited = append(audited, auditCall{decision, reason})
+		},
+	})
+	return handler, &audited
+}
+
+func serve(handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func login(t *testing.T, handler http.Handler, key string) *http.Cookie {
+	t.Helper()
+
+	form := url.Values{"api_key": {key}}
+	req := httptest.NewRequest("POST", "/dashboard/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := serve(handler, req)
+
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == auth.SessionCookie && c.Value != "" {
+			if !c.HttpOnly || c.SameSite != http.SameSiteStrictMode {
+				t.Fatalf("session cookie must be HttpOnly and SameSite=Strict: %+v", c)
+			}
+			return c
+		}
+	}
+	return nil
+}
+
+func TestDashboardRequiresAuthentication(t *testing.T) {
+	handler, _ := newTestDashboard(t)
+
+	rr := serve(handler, httptest.NewRequest("GET", "/api/dashboard/policies", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for anonymous API call, got %d", rr.Code)
+	}
+
+	rr = serve(handler, httptest.NewRequest("GET", "/dashboard/", nil))
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/dashboard/login" {
+		t.Fatalf("expected redirect to login, got %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+
+	// The login form itself is public
+	if rr := serve(handler, httptest.NewRequest("GET", "/dashboard/login", nil)); rr.Code != http.StatusOK {
+		t.Fatalf("expected login page, got %d", rr.Code)
+	}
+}
+
+func TestDashboardRequiresViewerRole(t *testing.T) {
+	handler, audited := newTestDashboard(t)
+
+	// Gateway admins are not automatically dashboard viewers
+	req := httptest.NewRequest("GET", "/api/dashboard/stats", nil)
+	req.Header.Set("X-API-Key", userKey)
+	if rr := serve(handler, req); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without %s, got %d", DefaultRole, rr.Code)
+	}
+
+	if login(t, handler, userKey) != nil {
+		t.Fatal("login must not issue a session without the dashboard role")
+	}
+	last := (*audited)[len(*audited)-1]
+	if last.decision != "DENY" || !strings.Contains(last.reason, "role required") {
+		t.Fatalf("expected audited login denial, got %+v", last)
+	}
+
+	req = httptest.NewRequest("GET", "/api/dashboard/stats", nil)
+	req.Header.Set("X-API-Key", viewerKey)
+	if rr := serve(handler, req); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for viewer API key, got %d", rr.Code)
+	}
+}
+
+func TestDashboardSessionLogin(t *testing.T) {
+	handler, audited := newTestDashboard(t)
+
+	if login(t, handler, "wrong") != nil {
+		t.Fatal("invalid key must not create a session")
+	}
+
+	cookie := login(t, handler, viewerKey)
+	if cookie == nil {
+		t.Fatal("expected session cookie")
+	}
+	last := (*audited)[len(*audited)-1]
+	if last.decision != "ALLOW" || last.reason != "dashboard: login api_key:ops" {
+		t.Fatalf("expected audited login, got %+v", last)
+	}
+
+	req := httptest.NewRequest("GET", "/dashboard/", nil)
+	req.AddCookie(cookie)
+	if rr := serve(handler, req); rr.Code != http.StatusOK {
+		t.Fatalf("expected dashboard page with session, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/dashboard/stats", nil)
+	req.AddCookie(cookie)
+	rr := serve(handler, req)
+	if rr.Code != http.StatusOK || rr.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("expected uncached 200 with session, got %d", rr.Code)
+	}
+
+	// A forged cookie is rejected outright
+	req = httptest.NewRequest("GET", "/api/dashboard/stats", nil)
+	req.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: "forged"})
+	if rr := serve(handler, req); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for forged session, got %d", rr.Code)
+	}
+}
+
+func TestDashboardCSRF(t *testing.T) {
+	handler, _ := newTestDashboard(t)
+	cookie := login(t, handler, viewerKey)
+
+	req := httptest.NewRequest("GET", "/api/dashboard/session", nil)
+	req.AddCookie(cookie)
+	var session struct {
+		CSRFToken string `json:"csrf_token"`
+	}
+	if err := json.NewDecoder(serve(handler, req).Body).Decode(&session); err != nil || session.CSRFToken == "" {
+		t.Fatalf("expected CSRF token, got %v", err)
+	}
+
+	// Cookie-authenticated mutation without the token
+	req = httptest.NewRequest("POST", "/api/dashboard/stats", nil)
+	req.AddCookie(cookie)
+	if rr := serve(handler, req); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without CSRF token, got %d", rr.Code)
+	}
+
+	// Right token, foreign origin
+	req = httptest.NewRequest("POST", "/api/dashboard/stats", nil)
+	req.AddCookie(cookie)
+	req.Header.Set(CSRFHeader, session.CSRFToken)
+	req.Header.Set("Origin", "https://evil.example")
+	if rr := serve(handler, req); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for cross-origin request, got %d", rr.Code)
+	}
+
+	// Right token, same origin: passes CSRF (the endpoint itself is GET-only)
+	req = httptest.NewRequest("POST", "/api/dashboard/stats", nil)
+	req.AddCookie(cookie)
+	req.Header.Set(CSRFHeader, session.CSRFToken)
+	if rr := serve(handler, req); rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected request to pass CSRF check, got %d", rr.Code)
+	}
+
+	// Logout needs the token too
+	form := url.Values{"csrf_token": {"wrong"}}
+	req = httptest.NewRequest("POST", "/dashboard/logout", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	if rr := serve(handler, req); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for logout without CSRF token, got %d", rr.Code)
+	}
+
+	form = url.Values{"csrf_token": {session.CSRFToken}}
+	req = httptest.NewRequest("POST", "/dashboard/logout", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	if rr := serve(handler, req); rr.Code != http.StatusSeeOther {
+		t.Fatalf("expected logout redirect, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/dashboard/stats", nil)
+	req.AddCookie(cookie)
+	if rr := serve(handler, req); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("session must be gone after logout, got %d", rr.Code)
+	}
+}
+
+func TestDashboardCrossOriginLoginRejected(t *testing.T) {
+	handler, _ := newTestDashboard(t)
+
+	form := url.Values{"api_key": {viewerKey}}
+	req := httptest.NewRequest("POST", "/dashboard/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "https://evil.example")
+
+	rr := serve(handler, req)
+	if rr.Code != http.StatusForbidden || len(rr.Result().Cookies()) != 0 {
+		t.Fatalf("expected cross-origin login to be rejected, got %d", rr.Code)
+	}
+}
+
+func TestDashboardSessionEndsWhenKeyRevoked(t *testing.T) {
+	store := auth.MapStore{viewerKey: {ID: "ops", Key: viewerKey, Roles: []string{DefaultRole}}}
+	keys := auth.APIKeyAuthenticator{Store: store}
+	sessions := auth.NewSessionStore(0)
+	sessions.Revalidate = keys.Recheck
+
+	h := &Handlers{Stats: NewStatsCollector(), PolicyEngine: policy.NewEngine()}
+	handler := NewHandler(h, testFiles, Access{
+		Authenticators: []auth.Authenticator{keys},
+		Sessions:       sessions,
+	})
+
+	cookie := login(t, handler, viewerKey)
+	if cookie == nil {
+		t.Fatal("expected session cookie")
+	}
+	stats := func() int {
+		req := httptest.NewRequest("GET", "/api/dashboard/stats", nil)
+		req.AddCookie(cookie)
+		return serve(handler, req).Code
+	}
+	if code := stats(); code != http.StatusOK {
+		t.Fatalf("expected 200 with a live session, got %d", code)
+	}
+
+	// Losing the dashboard role applies to the next request
+	store[viewerKey].Roles = []string{"user"}
+	if code := stats(); code != http.StatusForbidden {
+		t.Fatalf("expected 403 after the role was removed, got %d", code)
+	}
+
+	store[viewerKey].Roles = []string{DefaultRole}
+	store[viewerKey].Revoked = true
+	if code := stats(); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 after revocation, got %d", code)
+	}
+
+	// The session is gone for good, even if the key came back
+	store[viewerKey].Revoked = false
+	if code := stats(); code != http.StatusUnauthorized {
+		t.Fatalf("expected the revoked session to stay ended, got %d", code)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"Zero-TrustAPIGateWayServer/internal/audit"
 	"Zero-TrustAPIGateWayServer/internal/policy"
+	"Zero-TrustAPIGateWayServer/internal/proxy"
 )
 
 // LimiterStats is the interface for rate limit statistics.
@@ -14,12 +15,19 @@ type LimiterStats interface {
 	Stats() (ipBuckets, userBuckets int)
 }
 
+// UpstreamHealth is the interface for upstream instance health.
+type UpstreamHealth interface {
+	Health() []proxy.UpstreamStatus
+}
+
 // Handlers holds dependencies for dashboard API endpoints.
 type Handlers struct {
-	Stats       *StatsCollector
-	AuditPath   string
+	Stats        *StatsCollector
+	AuditPath    string
 	PolicyEngine *policy.Engine
-	Limiter     LimiterStats
+	Shadow       *policy.Engine // candidate policies; nil = shadow mode off
+	Limiter      LimiterStats
+	Upstreams    UpstreamHealth
 }
 
 // ServeAPI routes dashboard API requests to the appropriate handler.
@@ -38,6 +46,8 @@ func (h *Handlers) ServeAPI(w http.ResponseWriter, r *http.Request) {
 		h.servePolicies(w)
 	case "/api/dashboard/status":
 		h.serveStatus(w)
+	case "/api/dashboard/upstreams":
+		h.serveUpstreams(w)
 	default:
 		http.NotFound(w, r)
 	}
@@ -50,9 +60,24 @@ func (h *Handlers) serveStats(w http.ResponseWriter) {
 		"allow":          allow,
 		"deny":           deny,
 		"uptime_seconds": int64(uptime.Seconds()),
+		"shadow": map[string]interface{}{
+			"status":      h.shadowStatus(),
+			"divergences": h.Stats.Divergences(),
+		},
 	})
 }
 
+// shadowStatus is "off", "loaded", or "invalid" (candidate not compared).
+func (h *Handlers) shadowStatus() string {
+	switch {
+	case h.Shadow == nil:
+		return "off"
+	case h.Shadow.Loaded():
+		return "loaded"
+	}
+	return "invalid"
+}
+
 func (h *Handlers) serveAudit(w http.ResponseWriter, r *http.Request) {
 	limit := 50
 	if s := r.URL.Query().Get("limit"); s != "" {
@@ -93,13 +118,14 @@ func (h *Handlers) serveAudit(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) servePolicies(w http.ResponseWriter) {
 	rules := h.PolicyEngine.GetPolicies()
 	type policyDTO struct {
-		Method string   `json:"method"`
-		Path   string   `json:"path"`
-		Roles  []string `json:"roles"`
+		Method    string   `json:"method"`
+		Path      string   `json:"path"`
+		Roles     []string `json:"roles"`
+		AuthTypes []string `json:"auth_types,omitempty"`
 	}
 	dtos := make([]policyDTO, len(rules))
 	for i, r := range rules {
-		dtos[i] = policyDTO{Method: r.Method, Path: r.Path, Roles: r.Roles}
+		dtos[i] = policyDTO{Method: r.Method, Path: r.Path, Roles: r.Roles, AuthTypes: r.AuthTypes}
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"policies": dtos})
@@ -118,3 +144,12 @@ func (h *Handlers) serveStatus(w http.ResponseWriter) {
 		},
 	})
 }
+
+func (h *Handlers) serveUpstreams(w http.ResponseWriter) {
+	upstreams := []proxy.UpstreamStatus{}
+	if h.Upstreams != nil {
+		upstreams = h.Upstreams.Health()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"upstreams": upstreams})
+}
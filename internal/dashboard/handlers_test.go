@@ -0,0 +1,42 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"Zero-TrustAPIGateWayServer/internal/policy"
+)
+
+func TestStatsReportShadowDivergences(t *testing.T) {
+	stats := NewStatsCollector()
+	stats.IncrementDivergence()
+	stats.IncrementDivergence()
+
+	tests := []struct {
+		name   string
+		shadow *policy.Engine
+		want   string
+	}{
+		{"off", nil, "off"},
+		{"invalid", policy.NewEngine(), "invalid"},
+	}
+	for _, tt := range tests {
+		h := &Handlers{Stats: stats, Shadow: tt.shadow}
+		rr := httptest.NewRecorder()
+		h.ServeAPI(rr, httptest.NewRequest("GET", "/api/dashboard/stats", nil))
+
+		var body struct {
+			Shadow struct {
+				Status      string `json:"status"`
+				Divergences int64  `json:"divergences"`
+			} `json:"shadow"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if body.Shadow.Status != tt.want || body.Shadow.Divergences != 2 {
+			t.Errorf("%s: got %+v", tt.name, body.Shadow)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 type StatsCollector struct {
 	allowCount atomic.Int64
 	denyCount  atomic.Int64
+	divergence atomic.Int64 // shadow policy disagreements
 	startedAt  time.Time
 }
 
@@ -23,6 +24,16 @@ func (s *StatsCollector) IncrementDeny() {
 	s.denyCount.Add(1)
 }
 
+// IncrementDivergence counts a request the shadow policy set decided
+// differently from the enforced one.
+func (s *StatsCollector) IncrementDivergence() {
+	s.divergence.Add(1)
+}
+
+func (s *StatsCollector) Divergences() int64 {
+	return s.divergence.Load()
+}
+
 func (s *StatsCollector) Snapshot() (allow, deny int64, uptime time.Duration) {
 	return s.allowCount.Load(), s.denyCount.Load(), time.Since(s.startedAt)
 }
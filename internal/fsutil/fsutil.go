@@ -0,0 +1,51 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+/*
+ATOMIC FILE WRITES
+
+Files the gateway rewrites at runtime (API keys, key usage, policies)
+are also read at runtime, often by a watcher. WriteFileAtomic writes a
+temporary file in the same directory, syncs it and renames it over the
+target, so a reader sees either the old file or the new one, never a
+partial write, and a crash cannot leave a truncated file behind.
+*/
+
+// WriteFileAtomic replaces path with data, created with perm.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Perm returns the permissions of the existing file at path, or def
+// when there is none, so a rewrite keeps what the operator set.
+func Perm(path string, def os.FileMode) os.FileMode {
+	if info, err := os.Stat(path); err == nil {
+		return info.Mode().Perm()
+	}
+	return def
+}
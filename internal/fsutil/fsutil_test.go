@@ -0,0 +1,94 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keys.yaml")
+
+	if err := WriteFileAtomic(path, []byte("one"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte("two"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "two" {
+		t.Fatalf("unexpected content %q (%v)", data, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected mode %v (%v)", info.Mode().Perm(), err)
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected only the target file, got %v (%v)", entries, err)
+	}
+}
+
+func TestPermKeepsExistingMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	if got := Perm(path, 0644); got != 0644 {
+		t.Fatalf("missing file: expected default 0644, got %v", got)
+	}
+	if err := os.WriteFile(path, nil, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if got := Perm(path, 0644); got != 0640 {
+		t.Fatalf("existing file: expected 0640, got %v", got)
+	}
+}
+
+func TestWatchReloadsAndReportsMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	if err := os.WriteFile(path, []byte("one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loads := make(chan string, 100)
+	gone := make(chan struct{}, 100)
+	stop := Watch(path, 5*time.Millisecond, func(p string) error {
+		data, err := os.ReadFile(p)
+		loads <- string(data)
+		return err
+	}, func() { gone <- struct{}{} })
+	defer stop()
+
+	expect := func(ch <-chan string, want string) {
+		t.Helper()
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Fatalf("loaded %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no load of %q", want)
+		}
+	}
+
+	expect(loads, "one")
+
+	os.Remove(path)
+	select {
+	case <-gone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("missing file not reported")
+	}
+
+	// Back with the same content: still reloaded
+	if err := os.WriteFile(path, []byte("one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expect(loads, "one")
+}
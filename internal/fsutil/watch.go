@@ -0,0 +1,44 @@
+package fsutil
+
+import (
+	"os"
+	"time"
+)
+
+// Watch polls path every interval and calls load whenever its
+// modification time changes, starting at once. While the file is
+// missing, gone is called instead; the file is loaded again as soon as
+// it comes back. A load that fails is retried on the next tick, so
+// load must leave its owner in a fail-closed state on error.
+// The returned function stops the watcher.
+func Watch(path string, interval time.Duration, load func(path string) error, gone func()) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var lastMod time.Time
+
+		for {
+			info, err := os.Stat(path)
+			if err != nil {
+				gone()
+				// Force a reload once the file comes back
+				lastMod = time.Time{}
+			} else if !info.ModTime().Equal(lastMod) {
+				if err := load(path); err == nil {
+					lastMod = info.ModTime()
+				}
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
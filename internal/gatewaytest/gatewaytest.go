@@ -0,0 +1,42 @@
+// Package gatewaytest holds test helpers shared across the gateway's
+// packages.
+package gatewaytest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+// WaitFor polls cond until it holds, failing the test after two seconds.
+func WaitFor(t testing.TB, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("condition not met before deadline")
+}
+
+// ServeAs sends a request to h as if authentication had produced id
+// (nil = anonymous) and returns the response.
+func ServeAs(h http.Handler, id *auth.Identity, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if id != nil {
+		req = req.WithContext(auth.WithIdentity(req.Context(), id))
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+// Roles is an identity holding roles.
+func Roles(roles ...string) *auth.Identity {
+	return &auth.Identity{Roles: roles}
+}
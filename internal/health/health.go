@@ -0,0 +1,67 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+/*
+GATEWAY HEALTH ENDPOINT
+
+Answered by the gateway itself, never proxied, and ahead of
+authentication: orchestrators probe it anonymously, and it has to keep
+answering in deny-all mode, when no policy file (and so no public
+route) is loaded.
+
+  200 "ok"        policies loaded; the gateway can make decisions
+  503 "deny_all"  no valid policy file: every request is denied
+
+The response is the status and nothing else. Upstream names and
+availability are topology, shown only on the authenticated dashboard.
+A dead upstream would not fail the check anyway: it is the upstream's
+outage, not the gateway's.
+*/
+
+// Path is where the gateway serves its health endpoint.
+const Path = "/health"
+
+// PolicyStatus reports whether a valid policy set is active.
+type PolicyStatus interface {
+	Loaded() bool
+}
+
+// Handler serves the health endpoint.
+type Handler struct {
+	Policies PolicyStatus
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, code := "ok", http.StatusOK
+	if h.Policies == nil || !h.Policies.Loaded() {
+		status, code = "deny_all", http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
+
+// Middleware answers Path itself and passes everything else to next.
+// It goes after request validation (so it sees the canonical path) and
+// before authentication.
+func (h *Handler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == Path {
+			h.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
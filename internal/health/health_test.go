@@ -0,0 +1,65 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakePolicies bool
+
+func (f fakePolicies) Loaded() bool { return bool(f) }
+
+func TestHealthReportsPolicyState(t *testing.T) {
+	for loaded, want := range map[bool]int{true: http.StatusOK, false: http.StatusServiceUnavailable} {
+		h := &Handler{Policies: fakePolicies(loaded)}
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", Path, nil))
+
+		if rr.Code != want {
+			t.Fatalf("loaded=%v: expected %d, got %d", loaded, want, rr.Code)
+		}
+
+		// Status only: no upstream topology for anonymous callers
+		var body map[string]interface{}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body) != 1 || body["status"] == nil {
+			t.Fatalf("unexpected body: %v", body)
+		}
+	}
+}
+
+func TestHealthRejectsOtherMethods(t *testing.T) {
+	h := &Handler{Policies: fakePolicies(true)}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("POST", Path, nil))
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rr.Code)
+	}
+}
+
+func TestMiddlewareAnswersAheadOfAuth(t *testing.T) {
+	h := &Handler{Policies: fakePolicies(false)}
+	denyAll := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+	handler := h.Middleware(denyAll)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", Path, nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("deny-all health: expected 503, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("other paths: expected the chain to answer, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+PATH CANONICALIZATION
+
+Authorization and the upstream must see the SAME path. Anything whose
+meaning depends on who decodes or normalizes it is either normalized
+here, once, or rejected:
+
+  //api//admin           -> /api/admin         (slashes collapsed)
+  /api/public/../admin   -> /api/admin         (dot segments resolved)
+  /api/%61dmin           -> /api/admin         (harmless escapes decoded)
+  /api/a%2Fb             -> rejected           (encoded '/', '\', '.', '%',
+                                                control characters)
+  /api\admin             -> rejected           (backslash)
+  /../etc                -> rejected           (climbs above the root)
+
+Case is kept unless LowercasePaths is set (for case-insensitive
+upstreams); RBAC matching is always case-sensitive.
+
+The canonical path replaces r.URL.Path, so RBAC, routing and the
+upstream request all use it.
+*/
+
+// CanonicalRequest is the path step of request validation: it returns
+// r with its path in canonical form (a shallow copy when it changes),
+// or the reason the path is rejected. Dry-run evaluation calls it too,
+// so an explanation sees exactly the path RBAC would.
+func CanonicalRequest(r *http.Request, lowercase bool) (*http.Request, error) {
+	canonical, err := CanonicalPath(r.URL.EscapedPath(), lowercase)
+	if err != nil {
+		return nil, err
+	}
+	if canonical != r.URL.Path || r.URL.RawPath != "" {
+		u := *r.URL
+		u.Path = canonical
+		u.RawPath = "" // re-escaped from Path when forwarded
+		r = r.WithContext(r.Context())
+		r.URL = &u
+	}
+	return r, nil
+}
+
+// CanonicalPath turns an escaped request path (URL.EscapedPath) into
+// its canonical, decoded form, or explains why it is ambiguous.
+func CanonicalPath(escaped string, lowercase bool) (string, error) {
+	if !strings.HasPrefix(escaped, "/") {
+		return "", errors.New("path must start with '/'")
+	}
+
+	decoded, err := decodePath(escaped)
+	if err != nil {
+		return "", err
+	}
+
+	var out []string
+	segments := strings.Split(decoded[1:], "/")
+	for _, seg := range segments {
+		switch seg {
+		case "", ".":
+			// Collapsed
+		case "..":
+			if len(out) == 0 {
+				return "", errors.New("path climbs above the root")
+			}
+			out = out[:len(out)-1]
+		default:
+			out = append(out, seg)
+		}
+	}
+
+	canonical := "/" + strings.Join(out, "/")
+	// A trailing slash is significant to many upstreams; keep one
+	if len(out) > 0 && strings.HasSuffix(decoded, "/") {
+		canonical += "/"
+	}
+
+	if lowercase {
+		canonical = strings.ToLower(canonical)
+	}
+	return canonical, nil
+}
+
+// decodePath decodes percent escapes, refusing every escape or byte
+// that could change the path's structure after we have checked it.
+func decodePath(escaped string) (string, error) {
+	var b strings.Builder
+	b.Grow(len(escaped))
+
+	for i := 0; i < len(escaped); i++ {
+		c := escaped[i]
+
+		if c == '%' {
+			if i+2 >= len(escaped) || !isHex(escaped[i+1]) || !isHex(escaped[i+2]) {
+				return "", errors.New("malformed percent encoding")
+			}
+			c = unhex(escaped[i+1])<<4 | unhex(escaped[i+2])
+			i += 2
+
+			switch c {
+			case '/', '\\', '.', '%':
+				return "", errors.New("encoded '" + string(c) + "' is ambiguous")
+			}
+		}
+
+		if c == '\\' {
+			return "", errors.New("backslash in path")
+		}
+		if c < 0x20 || c == 0x7f {
+			return "", errors.New("control character in path")
+		}
+		b.WriteByte(c)
+	}
+
+	out := b.String()
+	if !utf8.ValidString(out) {
+		return "", errors.New("path is not valid UTF-8")
+	}
+	return out, nil
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Zero-TrustAPIGateWayServer/internal/audit"
+)
+
+func TestCanonicalPath(t *testing.T) {
+	tests := map[string]string{
+		"/":                     "/",
+		"/api/admin":            "/api/admin",
+		"//api//admin":          "/api/admin",
+		"/api/public/../admin":  "/api/admin",
+		"/api/./admin":          "/api/admin",
+		"/api/admin/":           "/api/admin/",
+		"/api/admin//":          "/api/admin/",
+		"/api/%61dmin":          "/api/admin",
+		"/api/caf%C3%A9":        "/api/café",
+		"/api/a%20b":            "/api/a b",
+		"/api/Admin":            "/api/Admin",
+		"/api/..":               "/",
+		"/api/users/../../etc/": "/etc/",
+	}
+	for in, want := range tests {
+		got, err := CanonicalPath(in, false)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCanonicalPathLowercase(t *testing.T) {
+	got, err := CanonicalPath("/API/%41dmin", true)
+	if err != nil || got != "/api/admin" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+}
+
+func TestAmbiguousPathsRejected(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"api/admin",
+		"/api%2Fadmin",
+		"/api%2fadmin",
+		"/api%5Cadmin",
+		"/api\\admin",
+		"/api/%2E%2E/admin",
+		"/api/%2e/admin",
+		"/api/%252F",
+		"/api/%00",
+		"/api/%0A",
+		"/api/%7F",
+		"/api/%",
+		"/api/%4",
+		"/api/%zz",
+		"/api/%FF",
+		"/..",
+		"/api/../../etc",
+	} {
+		if got, err := CanonicalPath(in, false); err == nil {
+			t.Errorf("%q: accepted as %q", in, got)
+		}
+	}
+}
+
+func TestCanonicalPathForwarded(t *testing.T) {
+	var gotPath, gotEscaped string
+	handler := ValidateRequestMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotEscaped = r.URL.Path, r.URL.EscapedPath()
+	}))
+
+	req := httptest.NewRequest("GET", "//api/public/..//%61dmin%3F", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if gotPath != "/api/admin?" || gotEscaped != "/api/admin%3F" {
+		t.Fatalf("downstream saw %q (%q)", gotPath, gotEscaped)
+	}
+}
+
+func TestRejectedPathAudited(t *testing.T) {
+	handler := newTestHandler()
+
+	req := httptest.NewRequest("GET", "/api%2Fadmin", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req = req.WithContext(audit.WithReason(req.Context()))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	reason, ok := audit.Reason(req.Context())
+	if !ok || !strings.HasPrefix(reason, "invalid path: ") {
+		t.Fatalf("reason = %q, %v", reason, ok)
+	}
+}
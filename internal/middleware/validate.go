@@ -5,6 +5,8 @@ import (
 	"io"
 	"net/http"
 	"strings"
+
+	"Zero-TrustAPIGateWayServer/internal/audit"
 )
 
 /*
@@ -23,14 +25,19 @@ WHY IT IS SIMPLE:
 
 FAIL-CLOSED PRINCIPLE:
 - If something looks wrong or missing → reject with 400
+- A path that could mean two things is wrong (see path.go)
 */
 
 /*
-Configuration (constants only, per requirements)
+Configuration
+
+The package-level values are the defaults used by
+ValidateRequestMiddleware. NewValidateRequestMiddleware takes an
+explicit Config built from the gateway config file.
 */
 
 const (
-	// Maximum allowed request body size in bytes.
+	// Default maximum allowed request body size in bytes.
 	// This is a hard cap, not per-route.
 	MaxRequestBodyBytes = 1 << 20 // 1 MiB
 )
@@ -47,19 +54,64 @@ var RequiredHeaders = []string{
 	"User-Agent",
 }
 
+// Config controls the request guardrails.
+type Config struct {
+	MaxBodyBytes        int64
+	AllowedContentTypes []string
+	RequiredHeaders     []string
+
+	// LowercasePaths lowercases the canonical path, for upstreams that
+	// treat paths case-insensitively.
+	LowercasePaths bool
+}
+
+// DefaultConfig returns the built-in guardrails.
+func DefaultConfig() Config {
+	return Config{
+		MaxBodyBytes:        MaxRequestBodyBytes,
+		AllowedContentTypes: AllowedContentTypes,
+		RequiredHeaders:     RequiredHeaders,
+	}
+}
+
 /*
 Middleware
 */
 
+// ValidateRequestMiddleware applies the default guardrails.
 func ValidateRequestMiddleware(next http.Handler) http.Handler {
+	return NewValidateRequestMiddleware(DefaultConfig())(next)
+}
+
+// NewValidateRequestMiddleware applies the given guardrails.
+func NewValidateRequestMiddleware(cfg Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return validate(cfg, next)
+	}
+}
+
+func validate(cfg Config, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
+		/*
+			0. Canonicalize the path
+			Everything after us (RBAC, routing, the upstream) sees the
+			same canonical path; ambiguous paths never get that far.
+		*/
+		canonical, err := CanonicalRequest(r, cfg.LowercasePaths)
+		if err != nil {
+			audit.SetReason(r.Context(), "invalid path: "+err.Error())
+			http.Error(w, "invalid path", http.StatusBadRequest)
+			return
+		}
+		r = canonical
+
 		/*
 			1. Enforce required headers
 			We do this first because it's cheap and avoids work.
 		*/
 
-		for _, h := range RequiredHeaders {
+		for _, h := range cfg.RequiredHeaders {
 			if strings.TrimSpace(r.Header.Get(h)) == "" {
 				http.Error(w, "missing required header: "+h, http.StatusBadRequest)
 				return
@@ -72,7 +124,7 @@ func ValidateRequestMiddleware(next http.Handler) http.Handler {
 		*/
 		if r.ContentLength > 0 {
 			ct := r.Header.Get("Content-Type")
-			if !isAllowedContentType(ct) {
+			if !isAllowedContentType(ct, cfg.AllowedContentTypes) {
 				http.Error(w, "invalid Content-Type", http.StatusBadRequest)
 				return
 			}
@@ -83,7 +135,7 @@ func ValidateRequestMiddleware(next http.Handler) http.Handler {
 			http.MaxBytesReader ensures we do not read more than allowed.
 			We buffer the body so the proxy receives the full request body.
 		*/
-		r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodyBytes)
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxBodyBytes)
 
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -101,13 +153,13 @@ func ValidateRequestMiddleware(next http.Handler) http.Handler {
 Helpers
 */
 
-func isAllowedContentType(ct string) bool {
+func isAllowedContentType(ct string, allowedTypes []string) bool {
 	if ct == "" {
 		return false
 	}
 
 	// Allow prefix matches to support charset parameters
-	for _, allowed := range AllowedContentTypes {
+	for _, allowed := range allowedTypes {
 		if strings.HasPrefix(ct, allowed) {
 			return true
 		}
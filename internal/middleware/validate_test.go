@@ -113,3 +113,40 @@ func TestBodyPreservedForDownstream(t *testing.T) {
 		t.Fatalf("expected body %q, got %q", payload, rr.Body.Bytes())
 	}
 }
+
+func TestConfiguredBodyLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxBodyBytes = 8
+
+	handler := NewValidateRequestMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := baseRequest([]byte(`{"too":"long"}`))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest && rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected rejection, got %d", rr.Code)
+	}
+}
+
+func TestConfiguredContentTypes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AllowedContentTypes = []string{"application/xml"}
+
+	handler := NewValidateRequestMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := baseRequest([]byte(`<ok/>`))
+	req.Header.Set("Content-Type", "application/xml")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,150 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+	_ "time/tzdata" // time zones must not depend on the host's tzdata
+
+	"Zero-TrustAPIGateWayServer/internal/rbac"
+)
+
+/*
+Rule conditions (the "when:" block)
+
+Static data only, compiled once at load time (see rbac/conditions.go
+for the semantics). Anything that does not compile rejects the whole
+file, like every other policy error.
+*/
+
+// Conditions narrow a rule beyond method, path and roles.
+type Conditions struct {
+	SourceCIDRs    []string          `yaml:"source_cidrs"`
+	Time           []TimeWindow      `yaml:"time"`
+	Headers        []string          `yaml:"headers"`
+	Claims         map[string]string `yaml:"claims"`
+	ParamIsSubject string            `yaml:"param_is_subject"`
+}
+
+// TimeWindow is a daily window, e.g. 09:00-17:00 Mon-Fri.
+type TimeWindow struct {
+	Days     []string `yaml:"days"`     // mon..sun; empty = every day
+	Start    string   `yaml:"start"`    // HH:MM
+	End      string   `yaml:"end"`      // HH:MM, before start = wraps midnight
+	Timezone string   `yaml:"timezone"` // IANA name; default UTC
+}
+
+// IsEmpty reports whether no condition is set.
+func (c *Conditions) IsEmpty() bool {
+	return c == nil || len(c.SourceCIDRs) == 0 && len(c.Time) == 0 &&
+		len(c.Headers) == 0 && len(c.Claims) == 0 && c.ParamIsSubject == ""
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// compileConditions validates and compiles a rule's when: block.
+// matcher is the rule's compiled path (param_is_subject needs a
+// template parameter). It returns nil when there are no conditions.
+func compileConditions(c *Conditions, matcher *rbac.PathMatcher) (*rbac.Conditions, error) {
+	if c.IsEmpty() {
+		return nil, nil
+	}
+
+	out := &rbac.Conditions{ParamIsSubject: c.ParamIsSubject}
+
+	for _, cidr := range c.SourceCIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("when.source_cidrs: %q is not a CIDR", cidr)
+		}
+		if prefix != prefix.Masked() {
+			return nil, fmt.Errorf("when.source_cidrs: %q has host bits set (did you mean %s?)", cidr, prefix.Masked())
+		}
+		out.SourceCIDRs = append(out.SourceCIDRs, prefix)
+	}
+
+	for i, w := range c.Time {
+		window, err := compileWindow(w)
+		if err != nil {
+			return nil, fmt.Errorf("when.time[%d]: %w", i, err)
+		}
+		out.TimeWindows = append(out.TimeWindows, window)
+	}
+
+	for _, h := range c.Headers {
+		if strings.TrimSpace(h) == "" || strings.ContainsAny(h, " :\t") {
+			return nil, fmt.Errorf("when.headers: invalid header name %q", h)
+		}
+		out.Headers = append(out.Headers, http.CanonicalHeaderKey(h))
+	}
+
+	if len(c.Claims) > 0 {
+		out.Claims = make(map[string]string, len(c.Claims))
+		for path, value := range c.Claims {
+			if path == "" || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") || strings.Contains(path, "..") {
+				return nil, fmt.Errorf("when.claims: invalid claim path %q", path)
+			}
+			out.Claims[path] = value
+		}
+	}
+
+	if c.ParamIsSubject != "" {
+		if matcher.Type != rbac.MatchTemplate || !strings.Contains(matcher.Pattern, "{"+c.ParamIsSubject+"}") {
+			return nil, fmt.Errorf("when.param_is_subject: path has no template parameter {%s}", c.ParamIsSubject)
+		}
+	}
+
+	return out, nil
+}
+
+func compileWindow(w TimeWindow) (rbac.TimeWindow, error) {
+	var out rbac.TimeWindow
+
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return out, fmt.Errorf("start: %w", err)
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return out, fmt.Errorf("end: %w", err)
+	}
+	if start == end {
+		return out, errors.New("start and end must differ")
+	}
+	out.Start, out.End = start, end
+
+	for _, d := range w.Days {
+		day, ok := weekdays[strings.ToLower(d)]
+		if !ok {
+			return out, fmt.Errorf("unknown day %q (want mon..sun)", d)
+		}
+		out.Days = append(out.Days, day)
+	}
+
+	if w.Timezone != "" {
+		loc, err := time.LoadLocation(w.Timezone)
+		if err != nil {
+			return out, fmt.Errorf("unknown timezone %q", w.Timezone)
+		}
+		out.Location = loc
+	}
+	return out, nil
+}
+
+// parseClock parses "HH:MM" (00:00 to 24:00) as time since midnight.
+func parseClock(s string) (time.Duration, error) {
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+	"Zero-TrustAPIGateWayServer/internal/middleware"
+	"Zero-TrustAPIGateWayServer/internal/rbac"
+)
+
+/*
+DRY-RUN EVALUATION
+
+A hypothetical request is built as an *http.Request and goes through
+the gateway's own steps: path canonicalization from request validation
+(with the configured lowercase_paths), rbac.RequestAttributes, and
+rbac.PolicySet.Evaluate, the same function the RBAC middleware uses.
+The method is taken as given, exactly as RBAC sees it. Nothing is sent
+anywhere.
+*/
+
+// Options mirror the gateway settings that shape evaluation.
+type Options struct {
+	Now            time.Time // used unless the request sets Time
+	LowercasePaths bool      // validation.lowercase_paths
+}
+
+// Hypothetical describes a request to evaluate without sending it.
+type Hypothetical struct {
+	Method   string            `json:"method" yaml:"method"`
+	Path     string            `json:"path" yaml:"path"`
+	SourceIP string            `json:"source_ip,omitempty" yaml:"source_ip"`
+	Headers  map[string]string `json:"headers,omitempty" yaml:"headers"`
+	Time     time.Time         `json:"time,omitempty" yaml:"time"` // zero = now
+
+	// Identity is the authenticated caller; nil = anonymous
+	Identity *HypotheticalIdentity `json:"identity,omitempty" yaml:"-"`
+}
+
+// HypotheticalIdentity is the caller of a Hypothetical request.
+type HypotheticalIdentity struct {
+	AuthType string                 `json:"auth_type" yaml:"auth_type"`
+	Subject  string                 `json:"subject" yaml:"subject"`
+	Roles    []string               `json:"roles" yaml:"roles"`
+	Claims   map[string]interface{} `json:"claims,omitempty" yaml:"claims"`
+}
+
+// Compile validates a parsed policy file and compiles it for evaluation.
+func Compile(pf PolicyFile) (rbac.PolicySet, error) {
+	if err := validatePolicyFile(pf); err != nil {
+		return rbac.PolicySet{}, err
+	}
+	return compile(pf)
+}
+
+// Explain evaluates h against set as the gateway would.
+func Explain(set rbac.PolicySet, h Hypothetical, opts Options) (rbac.Decision, error) {
+	r, err := h.request()
+	if err != nil {
+		return rbac.Decision{}, err
+	}
+	if r, err = middleware.CanonicalRequest(r, opts.LowercasePaths); err != nil {
+		return rbac.Decision{}, fmt.Errorf("path rejected before authorization (400): %w", err)
+	}
+
+	at := opts.Now
+	if !h.Time.IsZero() {
+		at = h.Time
+	}
+
+	var identity *auth.Identity
+	if h.Identity != nil {
+		if h.Identity.AuthType != "" && !isKnownAuthType(h.Identity.AuthType) {
+			return rbac.Decision{}, fmt.Errorf("unknown auth type %q", h.Identity.AuthType)
+		}
+		identity = &auth.Identity{
+			Type:    auth.AuthType(h.Identity.AuthType),
+			Subject: h.Identity.Subject,
+			Roles:   h.Identity.Roles,
+		}
+		if identity.Type == auth.AuthJWT {
+			identity.Claims = h.Identity.Claims
+		}
+	}
+
+	return set.Evaluate(rbac.RequestAttributes(r, at), identity), nil
+}
+
+// request builds the request the gateway would receive.
+func (h Hypothetical) request() (*http.Request, error) {
+	if h.Method == "" {
+		return nil, errors.New("method is required")
+	}
+	if !strings.HasPrefix(h.Path, "/") {
+		return nil, errors.New("path must start with '/'")
+	}
+	u, err := url.ParseRequestURI(h.Path)
+	if err != nil {
+		return nil, fmt.Errorf("path rejected before authorization (400): %w", err)
+	}
+
+	r := &http.Request{Method: h.Method, URL: u, RequestURI: h.Path, Header: http.Header{}}
+	if h.SourceIP != "" {
+		ip, err := netip.ParseAddr(h.SourceIP)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source IP %q", h.SourceIP)
+		}
+		r.RemoteAddr = netip.AddrPortFrom(ip, 0).String()
+	}
+	for name, value := range h.Headers {
+		r.Header.Set(name, value)
+	}
+	return r.WithContext(context.Background()), nil
+}
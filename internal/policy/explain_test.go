@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+	"Zero-TrustAPIGateWayServer/internal/middleware"
+	"Zero-TrustAPIGateWayServer/internal/rbac"
+)
+
+type staticSource rbac.PolicySet
+
+func (s staticSource) Current() rbac.PolicySet { return rbac.PolicySet(s) }
+
+// Explain must agree with the middleware chain it stands in for:
+// request validation, then RBAC.
+func TestExplainMatchesEnforcement(t *testing.T) {
+	pf, err := Parse([]byte(`
+policies:
+  - method: GET
+    path: /api/users
+    roles: [user]
+  - method: GET
+    path: /api/admin
+    match: prefix
+    roles: [admin]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := Compile(pf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		method, path string
+		lowercase    bool
+	}{
+		{"GET", "/api/users", false},
+		{"get", "/api/users", false},
+		{"GET", "/API/Users", false},
+		{"GET", "/API/Users", true},
+		{"GET", "/api/admin/../users", false},
+		{"GET", "/api/./users/", false},
+		{"GET", "/api%2Fusers", false},
+		{"GET", "/api/../../etc", false},
+	}
+
+	for _, tc := range cases {
+		identity := &auth.Identity{Type: auth.AuthAPIKey, Subject: "alice", Roles: []string{"user"}}
+
+		chain := middleware.NewValidateRequestMiddleware(middleware.Config{
+			MaxBodyBytes:   1 << 10,
+			LowercasePaths: tc.lowercase,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(auth.WithIdentity(r.Context(), identity))
+			rbac.RBACMiddleware(staticSource(set))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(w, r)
+		}))
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Method = tc.method
+		rec := httptest.NewRecorder()
+		chain.ServeHTTP(rec, req)
+
+		d, err := Explain(set, Hypothetical{
+			Method: tc.method,
+			Path:   tc.path,
+			Identity: &HypotheticalIdentity{
+				AuthType: string(identity.Type),
+				Subject:  identity.Subject,
+				Roles:    identity.Roles,
+			},
+		}, Options{Now: now, LowercasePaths: tc.lowercase})
+
+		var explained int
+		switch {
+		case err != nil:
+			explained = http.StatusBadRequest
+		case d.Allowed:
+			explained = http.StatusOK
+		default:
+			explained = http.StatusForbidden
+		}
+		if rec.Code != explained {
+			t.Errorf("%s %s (lowercase %v): enforced %d, explained %d (%v, %v)",
+				tc.method, tc.path, tc.lowercase, rec.Code, explained, d, err)
+		}
+	}
+}
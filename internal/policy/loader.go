@@ -1,11 +1,18 @@
 package policy
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"sync"
+	"sync/atomic"
 	"time"
 
+	"Zero-TrustAPIGateWayServer/internal/auth"
+	"Zero-TrustAPIGateWayServer/internal/fsutil"
+	"Zero-TrustAPIGateWayServer/internal/rbac"
+
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,24 +23,61 @@ SECURITY DESIGN:
 - No expressions, no templates, no interpolation
 - Validation happens BEFORE policies are accepted
 - Any error results in DENY-ALL behavior
+- Rules are compiled into an immutable snapshot and swapped atomically,
+  so a reader never observes a half-updated rule set
 */
 
 type Rule struct {
-	Method string   `yaml:"method"`
-	Path   string   `yaml:"path"`
-	Roles  []string `yaml:"roles"`
+	Method    string   `yaml:"method"`
+	Path      string   `yaml:"path"`
+	Match     string   `yaml:"match"` // exact, prefix (default), glob or template
+	Roles     []string `yaml:"roles"`
+	AuthTypes []string `yaml:"auth_types"` // optional: restrict accepted auth schemes
+	Effect    string   `yaml:"effect"`     // allow (default) or deny
+
+	When *Conditions `yaml:"when"` // optional, see conditions.go
+}
+
+// PublicRoute needs no authentication for the listed methods.
+type PublicRoute struct {
+	Path    string   `yaml:"path"`
+	Match   string   `yaml:"match"`  // exact, prefix (default), glob or template
+	Methods []string `yaml:"method"` // explicit list, e.g. [GET]
+}
+
+// Role declares the roles a role inherits: a caller holding it also
+// holds every inherited role (transitively).
+type Role struct {
+	Inherits []string `yaml:"inherits"`
 }
 
 type PolicyFile struct {
-	Policies []Rule `yaml:"policies"`
+	Policies []Rule          `yaml:"policies"`
+	Public   []PublicRoute   `yaml:"public"`
+	Roles    map[string]Role `yaml:"roles"` // optional role hierarchy
+}
+
+// inheritance is the direct role -> inherited roles map.
+func (pf PolicyFile) inheritance() map[string][]string {
+	inherits := make(map[string][]string, len(pf.Roles))
+	for name, r := range pf.Roles {
+		inherits[name] = r.Inherits
+	}
+	return inherits
+}
+
+// snapshot is an immutable view of one successfully loaded policy file.
+// It is never modified after being published.
+type snapshot struct {
+	rules    []Rule
+	compiled rbac.PolicySet
 }
 
 // Engine holds the active policy set.
-// Access is guarded by RWMutex for hot reloads.
+// The current snapshot is swapped atomically on hot reload.
+// A nil snapshot means deny all.
 type Engine struct {
-	mu       sync.RWMutex
-	policies []Rule
-	loaded   bool
+	current atomic.Pointer[snapshot]
 }
 
 // NewEngine creates an empty policy engine.
@@ -45,14 +89,29 @@ func NewEngine() *Engine {
 // GetPolicies returns a snapshot of current policies.
 // If not loaded or invalid, returns empty slice (deny all).
 func (e *Engine) GetPolicies() []Rule {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
-
-	if !e.loaded {
+	s := e.current.Load()
+	if s == nil {
 		return nil
 	}
 
-	return e.policies
+	return s.rules
+}
+
+// Loaded reports whether a valid policy file is active (false = deny all).
+func (e *Engine) Loaded() bool {
+	return e.current.Load() != nil
+}
+
+// Current returns the compiled policy set for RBAC evaluation.
+// It implements rbac.PolicySource, so the RBAC middleware always
+// evaluates against the latest successfully loaded file.
+func (e *Engine) Current() rbac.PolicySet {
+	s := e.current.Load()
+	if s == nil {
+		return rbac.PolicySet{}
+	}
+
+	return s.compiled
 }
 
 // LoadFromFile loads and validates policies from disk.
@@ -63,56 +122,120 @@ func (e *Engine) LoadFromFile(path string) error {
 		return fmt.Errorf("failed to read policy file: %w", err)
 	}
 
-	var pf PolicyFile
-	if err := yaml.Unmarshal(data, &pf); err != nil {
+	pf, err := Parse(data)
+	if err != nil {
 		e.invalidate()
-		return fmt.Errorf("invalid YAML: %w", err)
+		return err
 	}
 
-	if err := validatePolicyFile(pf); err != nil {
+	compiled, err := compile(pf)
+	if err != nil {
 		e.invalidate()
 		return err
 	}
 
-	e.mu.Lock()
-	defer e.mu.Unlock()
-
-	e.policies = pf.Policies
-	e.loaded = true
+	e.current.Store(&snapshot{
+		rules:    pf.Policies,
+		compiled: compiled,
+	})
 	return nil
 }
 
-// Watch watches the policy file for changes and reloads it.
+// Parse decodes and validates a policy file without loading it.
+// Unknown keys are rejected: a misspelt section must not be ignored.
+func Parse(data []byte) (PolicyFile, error) {
+	var pf PolicyFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&pf); err != nil && !errors.Is(err, io.EOF) {
+		return PolicyFile{}, fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	if err := validatePolicyFile(pf); err != nil {
+		return PolicyFile{}, err
+	}
+	return pf, nil
+}
+
+// Replace validates data, atomically replaces the policy file at path
+// and loads it. An invalid policy set is rejected before anything is
+// written, so the running policies are never disturbed by a bad upload.
+func (e *Engine) Replace(path string, data []byte) error {
+	if _, err := Parse(data); err != nil {
+		return err
+	}
+
+	// Atomic, so the watcher never loads a partial file; keeps the
+	// existing file's permissions
+	if err := fsutil.WriteFileAtomic(path, data, fsutil.Perm(path, 0644)); err != nil {
+		return fmt.Errorf("failed to write policy file: %w", err)
+	}
+
+	return e.LoadFromFile(path)
+}
+
+// Watch polls the policy file for changes and reloads it.
 // On ANY error → policies are invalidated (deny all).
-func (e *Engine) Watch(path string, interval time.Duration) {
-	go func() {
-		var lastMod time.Time
-
-		for {
-			info, err := os.Stat(path)
-			if err != nil {
-				e.invalidate()
-				time.Sleep(interval)
-				continue
-			}
-
-			if info.ModTime().After(lastMod) {
-				if err := e.LoadFromFile(path); err != nil {
-					// Error already invalidated policies
-				} else {
-					lastMod = info.ModTime()
-				}
-			}
-
-			time.Sleep(interval)
-		}
-	}()
+// The returned function stops the watcher.
+func (e *Engine) Watch(path string, interval time.Duration) (stop func()) {
+	// On error LoadFromFile has already invalidated the policies
+	return fsutil.Watch(path, interval, e.LoadFromFile, e.invalidate)
 }
 
 func (e *Engine) invalidate() {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+	e.current.Store(nil)
+}
+
+// compile converts validated rules into the RBAC representation,
+// compiling each path pattern once.
+func compile(pf PolicyFile) (rbac.PolicySet, error) {
+	policies := make([]rbac.Policy, len(pf.Policies))
+	for i, rule := range pf.Policies {
+		matcher, err := rbac.CompilePath(rbac.MatchType(rule.Match), rule.Path)
+		if err != nil {
+			return rbac.PolicySet{}, policyError(i, err.Error())
+		}
+
+		var authTypes []auth.AuthType
+		for _, t := range rule.AuthTypes {
+			authTypes = append(authTypes, auth.AuthType(t))
+		}
+
+		conditions, err := compileConditions(rule.When, matcher)
+		if err != nil {
+			return rbac.PolicySet{}, policyError(i, err.Error())
+		}
+
+		effect := rbac.Effect(rule.Effect)
+		if effect == "" {
+			effect = rbac.Allow
+		}
+
+		policies[i] = rbac.Policy{
+			Method:    rule.Method,
+			Path:      rule.Path,
+			Matcher:   matcher,
+			Roles:     rule.Roles,
+			AuthTypes: authTypes,
+			Effect:    effect,
+
+			Conditions: conditions,
+		}
+	}
+
+	public := make([]rbac.PublicRoute, len(pf.Public))
+	for i, p := range pf.Public {
+		matcher, err := rbac.CompilePath(rbac.MatchType(p.Match), p.Path)
+		if err != nil {
+			return rbac.PolicySet{}, publicError(i, err.Error())
+		}
+		public[i] = rbac.PublicRoute{Methods: p.Methods, Matcher: matcher}
+	}
+
+	roles, err := rbac.ResolveRoles(pf.inheritance())
+	if err != nil {
+		return rbac.PolicySet{}, rolesError(err.Error())
+	}
 
-	e.policies = nil
-	e.loaded = false
+	return rbac.PolicySet{Policies: policies, Public: public, Roles: roles}, nil
 }
@@ -1,8 +1,18 @@
 package policy
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+	"Zero-TrustAPIGateWayServer/internal/gatewaytest"
+	"Zero-TrustAPIGateWayServer/internal/rbac"
 )
 
 func TestValidPolicyLoads(t *testing.T) {
@@ -60,3 +70,627 @@ policies:
 		t.Fatal("expected deny-all after invalid policy")
 	}
 }
+
+func writePolicy(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadChangesRBACDecisions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, `
+policies:
+  - method: GET
+    path: /api/reports
+    roles: [user]
+`)
+
+	engine := NewEngine()
+	if err := engine.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := rbac.RBACMiddleware(engine)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	if code := gatewaytest.ServeAs(handler, gatewaytest.Roles("user"), "GET", "/api/reports").Code; code != http.StatusOK {
+		t.Fatalf("expected 200 before edit, got %d", code)
+	}
+
+	writePolicy(t, path, `
+policies:
+  - method: GET
+    path: /api/reports
+    roles: [admin]
+`)
+	if err := engine.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := gatewaytest.ServeAs(handler, gatewaytest.Roles("user"), "GET", "/api/reports").Code; code != http.StatusForbidden {
+		t.Fatalf("expected 403 after edit, got %d", code)
+	}
+	if code := gatewaytest.ServeAs(handler, gatewaytest.Roles("admin"), "GET", "/api/reports").Code; code != http.StatusOK {
+		t.Fatalf("expected 200 for admin after edit, got %d", code)
+	}
+}
+
+func TestWatchPicksUpFileEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, `
+policies:
+  - method: GET
+    path: /api/reports
+    roles: [admin]
+`)
+
+	engine := NewEngine()
+	stop := engine.Watch(path, 10*time.Millisecond)
+	defer stop()
+
+	handler := rbac.RBACMiddleware(engine)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	gatewaytest.WaitFor(t, func() bool {
+		return gatewaytest.ServeAs(handler, gatewaytest.Roles("admin"), "GET", "/api/reports").Code == http.StatusOK
+	})
+
+	if code := gatewaytest.ServeAs(handler, gatewaytest.Roles("user"), "GET", "/api/reports").Code; code != http.StatusForbidden {
+		t.Fatalf("expected 403 before edit, got %d", code)
+	}
+
+	writePolicy(t, path, `
+policies:
+  - method: GET
+    path: /api/reports
+    roles: [user]
+`)
+	// Some filesystems have coarse mtime resolution
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	gatewaytest.WaitFor(t, func() bool {
+		return gatewaytest.ServeAs(handler, gatewaytest.Roles("user"), "GET", "/api/reports").Code == http.StatusOK
+	})
+}
+
+func TestWatchInvalidatesOnBrokenEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, `
+policies:
+  - method: GET
+    path: /api/reports
+    roles: [user]
+`)
+
+	engine := NewEngine()
+	stop := engine.Watch(path, 10*time.Millisecond)
+	defer stop()
+
+	handler := rbac.RBACMiddleware(engine)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	gatewaytest.WaitFor(t, func() bool {
+		return gatewaytest.ServeAs(handler, gatewaytest.Roles("user"), "GET", "/api/reports").Code == http.StatusOK
+	})
+
+	writePolicy(t, path, "policies: [not valid")
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	gatewaytest.WaitFor(t, func() bool {
+		return gatewaytest.ServeAs(handler, gatewaytest.Roles("user"), "GET", "/api/reports").Code == http.StatusForbidden
+	})
+
+	if len(engine.GetPolicies()) != 0 {
+		t.Fatal("expected deny-all after broken edit")
+	}
+}
+
+func TestConcurrentReloadIsAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, `
+policies:
+  - method: GET
+    path: /a
+    roles: [user]
+  - method: GET
+    path: /b
+    roles: [user]
+`)
+
+	engine := NewEngine()
+	if err := engine.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			_ = engine.LoadFromFile(path)
+		}
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-done:
+			wg.Wait()
+			return
+		default:
+		}
+		set := engine.Current()
+		if len(set.Policies) != 2 {
+			t.Fatalf("observed partial rule set: %d policies", len(set.Policies))
+		}
+	}
+}
+
+func TestUnknownAuthTypeRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, `
+policies:
+  - method: POST
+    path: /api/admin
+    roles: [admin]
+    auth_types: [basic]
+`)
+
+	engine := NewEngine()
+	if err := engine.LoadFromFile(path); err == nil {
+		t.Fatal("expected unknown auth type to be rejected")
+	}
+}
+
+func TestMatchTypesCompiledAtLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, `
+policies:
+  - method: GET
+    path: /api/admin
+    match: exact
+    roles: [admin]
+  - method: GET
+    path: /api/users/{id}
+    match: template
+    roles: [user]
+  - method: GET
+    path: /api/*/items/**
+    match: glob
+    roles: [user]
+  - method: GET
+    path: /api/public
+    roles: [user]
+`)
+
+	engine := NewEngine()
+	if err := engine.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := rbac.RBACMiddleware(engine)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		role, path string
+		want       int
+	}{
+		{"admin", "/api/admin", http.StatusOK},
+		{"admin", "/api/admin/users", http.StatusForbidden},
+		{"user", "/api/users/42", http.StatusOK},
+		{"user", "/api/users/42/secrets", http.StatusForbidden},
+		{"user", "/api/shop/items/1", http.StatusOK},
+		{"user", "/api/public/docs", http.StatusOK},
+		{"user", "/api/publicity", http.StatusForbidden},
+		{"user", "/api/public/../admin", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if code := gatewaytest.ServeAs(handler, gatewaytest.Roles(tt.role), "GET", tt.path).Code; code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.role, tt.path, tt.want, code)
+		}
+	}
+}
+
+func TestInvalidMatchRejected(t *testing.T) {
+	for name, rule := range map[string]string{
+		"unknown type":     "    path: /api\n    match: regex\n",
+		"partial wildcard": "    path: /api/item-*\n    match: glob\n",
+		"bad parameter":    "    path: /api/{id\n    match: template\n",
+		"dot segment":      "    path: /api/../admin\n",
+	} {
+		path := filepath.Join(t.TempDir(), "policies.yaml")
+		writePolicy(t, path, "policies:\n  - method: GET\n    roles: [user]\n"+rule)
+
+		engine := NewEngine()
+		if err := engine.LoadFromFile(path); err == nil {
+			t.Errorf("%s: expected policy to be rejected", name)
+		}
+	}
+}
+
+func TestDenyRuleCarvesException(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, `
+policies:
+  - method: "*"
+    path: /api
+    roles: [admin]
+  - method: DELETE
+    path: /api/billing
+    roles: [admin]
+    effect: deny
+`)
+
+	engine := NewEngine()
+	if err := engine.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := rbac.RBACMiddleware(engine)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/api/billing", http.StatusOK},
+		{"DELETE", "/api/users", http.StatusOK},
+		{"DELETE", "/api/billing", http.StatusForbidden},
+		{"DELETE", "/api/billing/42", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if code := gatewaytest.ServeAs(handler, gatewaytest.Roles("admin"), tt.method, tt.path).Code; code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, code)
+		}
+	}
+}
+
+func TestInvalidRuleSetsRejected(t *testing.T) {
+	for name, rules := range map[string]string{
+		"unknown effect": `
+  - method: GET
+    path: /api
+    roles: [user]
+    effect: audit
+`,
+		"unreachable allow": `
+  - method: GET
+    path: /api/billing
+    roles: [user]
+  - method: "*"
+    path: /api
+    roles: [user, admin]
+    effect: deny
+`,
+		"unreachable exact under glob deny": `
+  - method: GET
+    path: /api/users/me
+    match: exact
+    roles: [user]
+  - method: GET
+    path: /api/users/*
+    match: glob
+    roles: [user]
+    effect: deny
+`,
+		"conflicting": `
+  - method: GET
+    path: /api/reports
+    roles: [user, admin]
+  - method: GET
+    path: /api/reports
+    roles: [user]
+    effect: deny
+`,
+		"duplicate": `
+  - method: GET
+    path: /api/reports
+    roles: [user, admin]
+  - method: GET
+    path: /api/reports/
+    roles: [admin]
+`,
+		"duplicate earlier": `
+  - method: GET
+    path: /api/reports
+    roles: [admin]
+  - method: GET
+    path: /api/reports
+    roles: [user, admin]
+`,
+	} {
+		path := filepath.Join(t.TempDir(), "policies.yaml")
+		writePolicy(t, path, "policies:"+rules)
+
+		engine := NewEngine()
+		if err := engine.LoadFromFile(path); err == nil {
+			t.Errorf("%s: expected policy to be rejected", name)
+		}
+	}
+}
+
+func TestDenyLimitedByAuthTypeDoesNotShadow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, `
+policies:
+  - method: POST
+    path: /api/admin
+    roles: [admin]
+  - method: POST
+    path: /api/admin
+    roles: [admin]
+    auth_types: [api_key]
+    effect: deny
+`)
+
+	engine := NewEngine()
+	if err := engine.LoadFromFile(path); err != nil {
+		t.Fatalf("expected rule set to load: %v", err)
+	}
+}
+
+func TestPublicRoutesLoaded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, `
+policies:
+  - method: GET
+    path: /api
+    roles: [user]
+public:
+  - path: /health
+    match: exact
+    method: [GET, HEAD]
+  - path: /docs
+    method: [GET]
+`)
+
+	engine := NewEngine()
+	if err := engine.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	set := engine.Current()
+	tests := []struct {
+		method, path string
+		want         bool
+	}{
+		{"GET", "/health", true},
+		{"HEAD", "/health", true},
+		{"POST", "/health", false},
+		{"GET", "/health/x", false},
+		{"GET", "/docs/intro", true},
+		{"GET", "/api", false},
+	}
+	for _, tt := range tests {
+		if got := set.IsPublic(tt.method, tt.path); got != tt.want {
+			t.Errorf("%s %s: public = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	// Deny all also means nothing is public
+	writePolicy(t, path, "policies: []\n")
+	engine.LoadFromFile(path)
+	if engine.Loaded() || engine.Current().IsPublic("GET", "/health") {
+		t.Fatal("invalid policy file must not leave public routes behind")
+	}
+}
+
+func TestInvalidPublicRoutesRejected(t *testing.T) {
+	for name, public := range map[string]string{
+		"no path":       "  - method: [GET]\n",
+		"no methods":    "  - path: /health\n",
+		"any method":    "  - path: /health\n    method: [\"*\"]\n",
+		"bad match":     "  - path: /health\n    match: regex\n    method: [GET]\n",
+		"relative path": "  - path: health\n    method: [GET]\n",
+		"unknown key":   "  - path: /health\n    methods: [GET]\n",
+	} {
+		path := filepath.Join(t.TempDir(), "policies.yaml")
+		writePolicy(t, path, "policies:\n  - method: GET\n    path: /api\n    roles: [user]\npublic:\n"+public)
+
+		engine := NewEngine()
+		if err := engine.LoadFromFile(path); err == nil {
+			t.Errorf("%s: expected policy to be rejected", name)
+		}
+	}
+}
+
+func TestUnknownSectionRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, "policies:\n  - method: GET\n    path: /api\n    roles: [user]\npublik:\n  - path: /health\n")
+
+	engine := NewEngine()
+	if err := engine.LoadFromFile(path); err == nil {
+		t.Fatal("expected misspelt section to be rejected")
+	}
+}
+
+func TestConditionsCompiledAtLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, `
+policies:
+  - method: GET
+    path: /api/users/{id}
+    match: template
+    roles: [user]
+    when:
+      param_is_subject: id
+      source_cidrs: [192.0.2.0/24]
+      headers: [x-request-id]
+      time:
+        - days: [mon, tue, wed, thu, fri, sat, sun]
+          start: "00:00"
+          end: "24:00"
+          timezone: Europe/Berlin
+`)
+
+	engine := NewEngine()
+	if err := engine.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := rbac.RBACMiddleware(engine)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path, remote string, header bool) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remote
+		if header {
+			req.Header.Set("X-Request-ID", "1")
+		}
+		req = req.WithContext(auth.WithIdentity(req.Context(), &auth.Identity{Subject: "alice", Roles: []string{"user"}}))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := serve("/api/users/alice", "192.0.2.10:1234", true); code != http.StatusOK {
+		t.Fatalf("all conditions met: expected 200, got %d", code)
+	}
+	if code := serve("/api/users/bob", "192.0.2.10:1234", true); code != http.StatusForbidden {
+		t.Fatalf("other user: expected 403, got %d", code)
+	}
+	if code := serve("/api/users/alice", "198.51.100.1:1234", true); code != http.StatusForbidden {
+		t.Fatalf("other network: expected 403, got %d", code)
+	}
+	if code := serve("/api/users/alice", "192.0.2.10:1234", false); code != http.StatusForbidden {
+		t.Fatalf("missing header: expected 403, got %d", code)
+	}
+}
+
+func TestInvalidConditionsRejected(t *testing.T) {
+	for name, when := range map[string]string{
+		"bad cidr":          "      source_cidrs: [10.0.0.0/33]\n",
+		"host bits":         "      source_cidrs: [10.0.0.1/8]\n",
+		"bad clock":         "      time: [{start: \"9am\", end: \"17:00\"}]\n",
+		"empty window":      "      time: [{start: \"09:00\", end: \"09:00\"}]\n",
+		"bad day":           "      time: [{days: [funday], start: \"09:00\", end: \"17:00\"}]\n",
+		"bad timezone":      "      time: [{start: \"09:00\", end: \"17:00\", timezone: Mars/Base}]\n",
+		"bad header":        "      headers: [\"X Bad\"]\n",
+		"bad claim path":    "      claims: {\"a..b\": x}\n",
+		"param not in path": "      param_is_subject: id\n",
+		"unknown condition": "      weather: sunny\n",
+	} {
+		path := filepath.Join(t.TempDir(), "policies.yaml")
+		writePolicy(t, path, "policies:\n  - method: GET\n    path: /api/users\n    roles: [user]\n    when:\n"+when)
+
+		engine := NewEngine()
+		if err := engine.LoadFromFile(path); err == nil {
+			t.Errorf("%s: expected policy to be rejected", name)
+		}
+	}
+}
+
+func TestConditionalDenyDoesNotShadow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, `
+policies:
+  - method: GET
+    path: /api/reports
+    roles: [user]
+  - method: GET
+    path: /api/reports
+    roles: [user]
+    effect: deny
+    when:
+      time: [{start: "18:00", end: "08:00"}]
+`)
+
+	engine := NewEngine()
+	if err := engine.LoadFromFile(path); err != nil {
+		t.Fatalf("expected rule set to load: %v", err)
+	}
+}
+
+func TestRoleHierarchyExpandsCallerRoles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writePolicy(t, path, `
+roles:
+  admin:
+    inherits: [editor]
+  editor:
+    inherits: [user]
+policies:
+  - method: GET
+    path: /api/public
+    roles: [user]
+  - method: PUT
+    path: /api/articles
+    roles: [editor]
+  - method: PUT
+    path: /api/articles/locked
+    roles: [admin]
+    effect: deny
+`)
+
+	engine := NewEngine()
+	if err := engine.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := rbac.RBACMiddleware(engine)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method, path, role string
+		want               int
+	}{
+		{"GET", "/api/public", "user", http.StatusOK},
+		{"GET", "/api/public", "admin", http.StatusOK},
+		{"PUT", "/api/articles", "user", http.StatusForbidden},
+		{"PUT", "/api/articles", "editor", http.StatusOK},
+		{"PUT", "/api/articles", "admin", http.StatusOK},
+		{"PUT", "/api/articles/locked", "editor", http.StatusOK},
+		{"PUT", "/api/articles/locked", "admin", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if code := gatewaytest.ServeAs(handler, gatewaytest.Roles(tt.role), tt.method, tt.path).Code; code != tt.want {
+			t.Errorf("%s %s as %s: expected %d, got %d", tt.method, tt.path, tt.role, tt.want, code)
+		}
+	}
+}
+
+func TestInvalidRoleHierarchyRejected(t *testing.T) {
+	rule := "policies:\n  - method: GET\n    path: /api\n    roles: [user]\n"
+	tests := map[string]struct{ data, errHas string }{
+		"cycle": {
+			"roles:\n  a: {inherits: [b]}\n  b: {inherits: [c]}\n  c: {inherits: [a]}\n" + rule,
+			"roles: inheritance cycle a -> b -> c -> a",
+		},
+		"self":           {"roles:\n  admin: {inherits: [admin]}\n" + rule, "inheritance cycle admin -> admin"},
+		"empty inherits": {"roles:\n  admin: {inherits: [\"\"]}\n" + rule, "must not be empty"},
+		"unknown key":    {"roles:\n  admin: {extends: [user]}\n" + rule, "extends"},
+		"inherited duplicate": {
+			"roles:\n  admin: {inherits: [user]}\n" + rule + "  - method: GET\n    path: /api\n    roles: [admin]\n",
+			"policy[1]: duplicates policy[0]",
+		},
+		"inherited unreachable": {
+			"roles:\n  admin: {inherits: [user]}\npolicies:\n" +
+				"  - method: GET\n    path: /api/admin\n    roles: [admin]\n" +
+				"  - method: GET\n    path: /api\n    roles: [user]\n    effect: deny\n",
+			"policy[0]: unreachable",
+		},
+	}
+	for name, tt := range tests {
+		_, err := Parse([]byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.errHas) {
+			t.Errorf("%s: expected error containing %q, got %v", name, tt.errHas, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"Zero-TrustAPIGateWayServer/internal/rbac"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+POLICY TEST SUITES
+
+A suite (policies_test.yaml, next to policies.yaml) lists hypothetical
+requests and the outcome each must get. Every case is evaluated with
+Explain, i.e. exactly as the gateway would decide it, so a policy change
+can be gated in review like code:
+
+  tests:
+    - name: users cannot delete billing
+      request: {method: DELETE, path: /api/billing}
+      identity: {auth_type: jwt, roles: [user]}   # omit = anonymous
+      expect: deny
+      rule: 3                                     # optional deciding rule
+
+Cases without a request time are evaluated at the time of the run; use
+request.time for rules with time windows.
+*/
+
+// TestSuite is a parsed policies_test.yaml.
+type TestSuite struct {
+	Tests []TestCase `yaml:"tests"`
+}
+
+// TestCase is one fixture and its expected outcome.
+type TestCase struct {
+	Name     string                `yaml:"name"`
+	Request  Hypothetical          `yaml:"request"`
+	Identity *HypotheticalIdentity `yaml:"identity"`
+	Expect   string                `yaml:"expect"` // allow or deny
+	Rule     *int                  `yaml:"rule"`   // optional; -1 = no rule
+}
+
+// TestResult is the outcome of one case.
+type TestResult struct {
+	Case     TestCase
+	Decision rbac.Decision
+	Passed   bool
+	Failure  string // why it failed
+}
+
+// ParseTestSuite decodes and validates a test suite. Unknown keys are
+// rejected, like in policy files.
+func ParseTestSuite(data []byte) (TestSuite, error) {
+	var suite TestSuite
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&suite); err != nil && !errors.Is(err, io.EOF) {
+		return TestSuite{}, fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	if len(suite.Tests) == 0 {
+		return TestSuite{}, errors.New("test suite contains no tests")
+	}
+
+	names := make(map[string]bool)
+	for i, tc := range suite.Tests {
+		if strings.TrimSpace(tc.Name) == "" {
+			return TestSuite{}, testError(i, "name is required")
+		}
+		if names[tc.Name] {
+			return TestSuite{}, testError(i, "duplicate name "+tc.Name)
+		}
+		names[tc.Name] = true
+
+		if tc.Request.Method == "" || tc.Request.Path == "" {
+			return TestSuite{}, testError(i, "request.method and request.path are required")
+		}
+		switch strings.ToLower(tc.Expect) {
+		case "allow", "deny":
+		default:
+			return TestSuite{}, testError(i, "expect must be allow or deny")
+		}
+	}
+	return suite, nil
+}
+
+// RunTests evaluates every case against set. A case whose request is
+// invalid fails; it does not stop the run.
+func RunTests(set rbac.PolicySet, suite TestSuite, opts Options) []TestResult {
+	results := make([]TestResult, len(suite.Tests))
+	for i, tc := range suite.Tests {
+		results[i] = runTest(set, tc, opts)
+	}
+	return results
+}
+
+func runTest(set rbac.PolicySet, tc TestCase, opts Options) TestResult {
+	result := TestResult{Case: tc}
+
+	h := tc.Request
+	h.Identity = tc.Identity
+	d, err := Explain(set, h, opts)
+	if err != nil {
+		result.Failure = err.Error()
+		return result
+	}
+	result.Decision = d
+
+	got := "deny"
+	if d.Allowed {
+		got = "allow"
+	}
+	switch {
+	case got != strings.ToLower(tc.Expect):
+		result.Failure = fmt.Sprintf("expected %s, got %s (%s)", strings.ToUpper(tc.Expect), strings.ToUpper(got), d.Reason)
+	case tc.Rule != nil && *tc.Rule != d.Rule:
+		result.Failure = fmt.Sprintf("expected rule %d, got %d (%s)", *tc.Rule, d.Rule, d.Reason)
+	default:
+		result.Passed = true
+	}
+	return result
+}
+
+func testError(index int, msg string) error {
+	return errors.New("tests[" + itoa(index) + "]: " + msg)
+}
@@ -3,7 +3,11 @@ package policy
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+	"Zero-TrustAPIGateWayServer/internal/rbac"
 )
 
 /*
@@ -29,6 +33,15 @@ func validatePolicyFile(pf PolicyFile) error {
 			return policyError(i, "path must start with '/'")
 		}
 
+		matcher, err := rbac.CompilePath(rbac.MatchType(p.Match), p.Path)
+		if err != nil {
+			return policyError(i, err.Error())
+		}
+
+		if _, err := compileConditions(p.When, matcher); err != nil {
+			return policyError(i, err.Error())
+		}
+
 		if len(p.Roles) == 0 {
 			return policyError(i, "roles must not be empty")
 		}
@@ -38,15 +51,194 @@ func validatePolicyFile(pf PolicyFile) error {
 				return policyError(i, "role names must not be empty")
 			}
 		}
+
+		for _, t := range p.AuthTypes {
+			if !isKnownAuthType(t) {
+				return policyError(i, "unknown auth type: "+t)
+			}
+		}
+
+		switch rbac.Effect(p.Effect) {
+		case "", rbac.Allow, rbac.Deny:
+		default:
+			return policyError(i, "effect must be allow or deny")
+		}
 	}
 
+	for i, p := range pf.Public {
+		if err := validatePublic(p); err != nil {
+			return publicError(i, err.Error())
+		}
+	}
+
+	roles, err := rbac.ResolveRoles(pf.inheritance())
+	if err != nil {
+		return rolesError(err.Error())
+	}
+
+	return validateRuleSet(pf.Policies, roles)
+}
+
+// validatePublic checks one public route. Methods are listed
+// explicitly: making every method of a path anonymous is never implied.
+func validatePublic(p PublicRoute) error {
+	if strings.TrimSpace(p.Path) == "" {
+		return errors.New("path is required")
+	}
+	if _, err := rbac.CompilePath(rbac.MatchType(p.Match), p.Path); err != nil {
+		return err
+	}
+	if len(p.Methods) == 0 {
+		return errors.New("method must list at least one method")
+	}
+	for _, m := range p.Methods {
+		if strings.TrimSpace(m) == "" || m == rbac.MethodAny {
+			return errors.New("methods must be named explicitly")
+		}
+	}
 	return nil
 }
 
+/*
+Rule set checks
+
+Precedence (deny overrides, then most specific; see rbac/precedence.go)
+makes some rule sets meaningless. They are rejected rather than loaded:
+
+- Unreachable: an allow rule whose every request is also matched by an
+  unconditional deny rule (same or broader method, path, auth types
+  and roles).
+- Conflicting: an allow and a deny rule for the same method, path,
+  auth types and conditions sharing a role.
+- Duplicate: a rule repeating another with the same effect and no
+  roles of its own (for identical rules, the later one is reported).
+
+"Broader roles" follows the role hierarchy: a rule for [user] covers
+one for [admin] when admin inherits user. Conflicts compare the listed
+roles only, so a deny for [admin] can carve admins out of an allow for
+[user].
+*/
+
+func validateRuleSet(rules []Rule, roles rbac.RoleHierarchy) error {
+	matchers := make([]*rbac.PathMatcher, len(rules))
+	for i, r := range rules {
+		// Already validated above
+		matchers[i], _ = rbac.CompilePath(rbac.MatchType(r.Match), r.Path)
+	}
+
+	for j, b := range rules {
+		for i, a := range rules {
+			if i == j {
+				continue
+			}
+			same := a.Method == b.Method && sameAuthTypes(a.AuthTypes, b.AuthTypes) &&
+				matchers[i].Type == matchers[j].Type && matchers[i].Pattern == matchers[j].Pattern &&
+				sameConditions(a.When, b.When)
+
+			switch {
+			case isDeny(a) && !isDeny(b) && shadows(a, matchers[i], b, matchers[j], roles):
+				return policyError(j, "unreachable: every request it allows is denied by policy["+itoa(i)+"]")
+			case isDeny(a) != isDeny(b) && same && overlaps(a.Roles, b.Roles):
+				return policyError(j, "conflicts with policy["+itoa(i)+"] (same method, path and auth types, opposite effect)")
+			case isDeny(a) == isDeny(b) && same && coversRoles(a.Roles, b.Roles, roles) &&
+				(i < j || !coversRoles(b.Roles, a.Roles, roles)):
+				return policyError(j, "duplicates policy["+itoa(i)+"]")
+			}
+		}
+	}
+	return nil
+}
+
+// shadows reports whether deny rule a matches every request allow rule
+// b matches.
+func shadows(a Rule, am *rbac.PathMatcher, b Rule, bm *rbac.PathMatcher, roles rbac.RoleHierarchy) bool {
+	// A conditional deny only applies some of the time
+	if !a.When.IsEmpty() {
+		return false
+	}
+	if a.Method != rbac.MethodAny && a.Method != b.Method {
+		return false
+	}
+	if len(a.AuthTypes) > 0 && (len(b.AuthTypes) == 0 || !subset(b.AuthTypes, a.AuthTypes)) {
+		return false
+	}
+	return am.Covers(bm) && coversRoles(a.Roles, b.Roles, roles)
+}
+
+// coversRoles reports whether every caller holding one of b also holds
+// one of a, directly or by inheritance.
+func coversRoles(a, b []string, roles rbac.RoleHierarchy) bool {
+	for _, r := range b {
+		if !overlaps(roles.Expand([]string{r}), a) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameConditions(a, b *Conditions) bool {
+	if a.IsEmpty() || b.IsEmpty() {
+		return a.IsEmpty() && b.IsEmpty()
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func isDeny(r Rule) bool {
+	return rbac.Effect(r.Effect) == rbac.Deny
+}
+
+func sameAuthTypes(a, b []string) bool {
+	return subset(a, b) && subset(b, a)
+}
+
+// subset reports whether every entry of a is in b.
+func subset(a, b []string) bool {
+	for _, x := range a {
+		if !contains(b, x) {
+			return false
+		}
+	}
+	return true
+}
+
+func overlaps(a, b []string) bool {
+	for _, x := range a {
+		if contains(b, x) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, want string) bool {
+	for _, s := range list {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+func isKnownAuthType(t string) bool {
+	switch auth.AuthType(t) {
+	case auth.AuthJWT, auth.AuthAPIKey, auth.AuthMTLS:
+		return true
+	}
+	return false
+}
+
 func policyError(index int, msg string) error {
 	return errors.New("policy[" + itoa(index) + "]: " + msg)
 }
 
+func publicError(index int, msg string) error {
+	return errors.New("public[" + itoa(index) + "]: " + msg)
+}
+
+func rolesError(msg string) error {
+	return errors.New("roles: " + msg)
+}
+
 // tiny helper to avoid strconv import
 func itoa(i int) string {
 	return fmt.Sprintf("%d", i)
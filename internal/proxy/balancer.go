@@ -0,0 +1,308 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+/*
+LOAD BALANCING DESIGN
+
+- An upstream is a pool of one or more instances
+- An instance receives traffic only while it is healthy (active checks)
+  AND not ejected (passive checks)
+- Fail closed: if no instance is available the request is rejected
+  with 503; we never "try anyway" against a known-bad instance
+
+Strategies:
+- round_robin:        rotate through available instances
+- least_connections:  fewest in-flight requests wins (ties: first)
+- consistent_hash:    hash of the caller identity (subject, falling back
+                      to client IP) so a caller sticks to one instance
+*/
+
+type Strategy string
+
+const (
+	RoundRobin       Strategy = "round_robin"
+	LeastConnections Strategy = "least_connections"
+	ConsistentHash   Strategy = "consistent_hash"
+)
+
+// Defaults applied to zero values.
+const (
+	DefaultHealthInterval = 10 * time.Second
+	DefaultHealthTimeout  = 2 * time.Second
+	DefaultEjectionTime   = 30 * time.Second
+
+	// Virtual nodes per instance on the consistent hash ring
+	hashReplicas = 64
+)
+
+// HealthCheck configures active probing. An empty Path disables it.
+type HealthCheck struct {
+	Path     string
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// PassiveCheck configures ejection after consecutive failures observed
+// on live traffic (5xx responses or connection errors).
+// MaxFailures == 0 disables passive ejection.
+type PassiveCheck struct {
+	MaxFailures  int
+	EjectionTime time.Duration
+}
+
+// Instance is one backend address inside a pool.
+type Instance struct {
+	URL *url.URL
+
+	healthy      atomic.Bool
+	active       atomic.Int64
+	failures     atomic.Int64
+	ejectedUntil atomic.Int64 // unix nanos, 0 = not ejected
+	lastError    atomic.Pointer[string]
+}
+
+func (i *Instance) available(now time.Time) bool {
+	return i.healthy.Load() && now.UnixNano() >= i.ejectedUntil.Load()
+}
+
+// Pool balances requests across the instances of one upstream.
+type Pool struct {
+	name      string
+	strategy  Strategy
+	instances []*Instance
+	health    HealthCheck
+	passive   PassiveCheck
+	transport http.RoundTripper // shared by proxies and health probes
+
+	next atomic.Uint64 // round robin cursor
+	ring []ringNode    // consistent hash ring, sorted by hash
+
+	now func() time.Time
+}
+
+type ringNode struct {
+	hash     uint32
+	instance *Instance
+}
+
+// ErrNoHealthyInstance is returned when every instance is down or ejected.
+var ErrNoHealthyInstance = errors.New("no healthy upstream instance")
+
+func newPool(u Upstream) (*Pool, error) {
+	if len(u.Targets) == 0 {
+		return nil, fmt.Errorf("upstream %q has no targets", u.Name)
+	}
+
+	strategy := u.Strategy
+	if strategy == "" {
+		strategy = RoundRobin
+	}
+	switch strategy {
+	case RoundRobin, LeastConnections, ConsistentHash:
+	default:
+		return nil, fmt.Errorf("upstream %q: unknown strategy %q", u.Name, strategy)
+	}
+
+	health := u.HealthCheck
+	if health.Interval <= 0 {
+		health.Interval = DefaultHealthInterval
+	}
+	if health.Timeout <= 0 {
+		health.Timeout = DefaultHealthTimeout
+	}
+
+	passive := u.Passive
+	if passive.MaxFailures > 0 && passive.EjectionTime <= 0 {
+		passive.EjectionTime = DefaultEjectionTime
+	}
+
+	p := &Pool{
+		name:      u.Name,
+		strategy:  strategy,
+		health:    health,
+		passive:   passive,
+		transport: newTransport(u.TLS),
+		now:       time.Now,
+	}
+
+	for _, target := range u.Targets {
+		if target == nil {
+			return nil, fmt.Errorf("upstream %q has a nil target", u.Name)
+		}
+		inst := &Instance{URL: target}
+		// Healthy until proven otherwise, so traffic flows before the first probe
+		inst.healthy.Store(true)
+		p.instances = append(p.instances, inst)
+	}
+
+	if strategy == ConsistentHash {
+		p.ring = buildRing(p.instances)
+	}
+
+	return p, nil
+}
+
+// newTransport gives each upstream its own connection pool and TLS
+// settings, so one upstream's client certificate is never offered to
+// another.
+func newTransport(tlsConfig *tls.Config) http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		t.TLSClientConfig = tlsConfig.Clone()
+	}
+	return t
+}
+
+// pick selects an available instance for the request.
+func (p *Pool) pick(r *http.Request) (*Instance, error) {
+	now := p.now()
+
+	switch p.strategy {
+	case LeastConnections:
+		var best *Instance
+		for _, inst := range p.instances {
+			if !inst.available(now) {
+				continue
+			}
+			if best == nil || inst.active.Load() < best.active.Load() {
+				best = inst
+			}
+		}
+		if best != nil {
+			return best, nil
+		}
+
+	case ConsistentHash:
+		h := hashKey(balanceKey(r))
+		start := sort.Search(len(p.ring), func(i int) bool { return p.ring[i].hash >= h })
+		for n := 0; n < len(p.ring); n++ {
+			node := p.ring[(start+n)%len(p.ring)]
+			if node.instance.available(now) {
+				return node.instance, nil
+			}
+		}
+
+	default: // RoundRobin
+		count := uint64(len(p.instances))
+		start := p.next.Add(1) - 1
+		for n := uint64(0); n < count; n++ {
+			inst := p.instances[(start+n)%count]
+			if inst.available(now) {
+				return inst, nil
+			}
+		}
+	}
+
+	return nil, ErrNoHealthyInstance
+}
+
+// reportSuccess clears the passive failure streak.
+func (p *Pool) reportSuccess(inst *Instance) {
+	inst.failures.Store(0)
+}
+
+// reportFailure records a 5xx or connection error and ejects the
+// instance once the consecutive failure limit is reached.
+func (p *Pool) reportFailure(inst *Instance, reason string) {
+	inst.lastError.Store(&reason)
+
+	if p.passive.MaxFailures <= 0 {
+		return
+	}
+
+	if inst.failures.Add(1) >= int64(p.passive.MaxFailures) {
+		inst.ejectedUntil.Store(p.now().Add(p.passive.EjectionTime).UnixNano())
+		inst.failures.Store(0)
+	}
+}
+
+/*
+Status (read-only, for the dashboard)
+*/
+
+// InstanceStatus is a point-in-time view of one instance.
+type InstanceStatus struct {
+	URL         string `json:"url"`
+	Healthy     bool   `json:"healthy"`
+	Ejected     bool   `json:"ejected"`
+	ActiveConns int64  `json:"active_connections"`
+	Failures    int64  `json:"consecutive_failures"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
+// UpstreamStatus is a point-in-time view of one pool.
+type UpstreamStatus struct {
+	Name      string           `json:"name"`
+	Strategy  Strategy         `json:"strategy"`
+	Instances []InstanceStatus `json:"instances"`
+}
+
+func (p *Pool) status() UpstreamStatus {
+	now := p.now()
+	st := UpstreamStatus{Name: p.name, Strategy: p.strategy}
+
+	for _, inst := range p.instances {
+		s := InstanceStatus{
+			URL:         inst.URL.String(),
+			Healthy:     inst.healthy.Load(),
+			Ejected:     now.UnixNano() < inst.ejectedUntil.Load(),
+			ActiveConns: inst.active.Load(),
+			Failures:    inst.failures.Load(),
+		}
+		if e := inst.lastError.Load(); e != nil {
+			s.LastError = *e
+		}
+		st.Instances = append(st.Instances, s)
+	}
+	return st
+}
+
+/*
+Helpers
+*/
+
+func buildRing(instances []*Instance) []ringNode {
+	ring := make([]ringNode, 0, len(instances)*hashReplicas)
+	for _, inst := range instances {
+		for v := 0; v < hashReplicas; v++ {
+			ring = append(ring, ringNode{
+				hash:     hashKey(inst.URL.String() + "#" + strconv.Itoa(v)),
+				instance: inst,
+			})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+	return ring
+}
+
+// balanceKey identifies the caller for consistent hashing.
+func balanceKey(r *http.Request) string {
+	if id, ok := auth.FromContext(r.Context()); ok && id.Subject != "" {
+		return string(id.Type) + ":" + id.Subject
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func hashKey(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
@@ -0,0 +1,189 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+	"Zero-TrustAPIGateWayServer/internal/gatewaytest"
+)
+
+// instanceServer stands in for one `cmd/upstream -addr ...` process.
+type instanceServer struct {
+	name    string
+	srv     *httptest.Server
+	failing atomic.Bool // when set, every response is 500
+	hits    atomic.Int64
+}
+
+func newInstance(t *testing.T, name string) *instanceServer {
+	t.Helper()
+
+	is := &instanceServer{name: name}
+	is.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if is.failing.Load() {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Path != "/health" {
+			is.hits.Add(1)
+		}
+		fmt.Fprint(w, name)
+	}))
+	t.Cleanup(is.srv.Close)
+	return is
+}
+
+func (is *instanceServer) url(t *testing.T) *url.URL {
+	u, err := url.Parse(is.srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func newPoolRouter(t *testing.T, u Upstream) *Router {
+	t.Helper()
+	rt, err := NewRouter([]Upstream{u}, []Route{{Name: "all", PathPrefix: "/", Upstream: u.Name}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rt
+}
+
+func TestRoundRobinSpreadsLoad(t *testing.T) {
+	a, b := newInstance(t, "a"), newInstance(t, "b")
+	rt := newPoolRouter(t, Upstream{Name: "svc", Targets: []*url.URL{a.url(t), b.url(t)}})
+
+	for i := 0; i < 10; i++ {
+		gatewaytest.ServeAs(rt, nil, "GET", "/")
+	}
+
+	if a.hits.Load() != 5 || b.hits.Load() != 5 {
+		t.Fatalf("expected 5/5 split, got a=%d b=%d", a.hits.Load(), b.hits.Load())
+	}
+}
+
+func TestLeastConnectionsPrefersIdle(t *testing.T) {
+	a, b := newInstance(t, "a"), newInstance(t, "b")
+	rt := newPoolRouter(t, Upstream{Name: "svc", Strategy: LeastConnections, Targets: []*url.URL{a.url(t), b.url(t)}})
+
+	// Pretend instance a is busy
+	rt.pools[0].instances[0].active.Add(3)
+
+	for i := 0; i < 4; i++ {
+		if rr := gatewaytest.ServeAs(rt, nil, "GET", "/"); rr.Body.String() != "b" {
+			t.Fatalf("expected idle instance b, got %q", rr.Body.String())
+		}
+	}
+}
+
+func TestConsistentHashIsSticky(t *testing.T) {
+	a, b, c := newInstance(t, "a"), newInstance(t, "b"), newInstance(t, "c")
+	rt := newPoolRouter(t, Upstream{Name: "svc", Strategy: ConsistentHash, Targets: []*url.URL{a.url(t), b.url(t), c.url(t)}})
+
+	seen := map[string]bool{}
+	for _, subject := range []string{"alice", "bob", "carol", "dave", "erin", "frank"} {
+		first := gatewaytest.ServeAs(rt, &auth.Identity{Type: auth.AuthAPIKey, Subject: subject}, "GET", "/").Body.String()
+		for i := 0; i < 5; i++ {
+			if got := gatewaytest.ServeAs(rt, &auth.Identity{Type: auth.AuthAPIKey, Subject: subject}, "GET", "/").Body.String(); got != first {
+				t.Fatalf("%s moved from %s to %s", subject, first, got)
+			}
+		}
+		seen[first] = true
+	}
+
+	if len(seen) < 2 {
+		t.Fatalf("expected identities to spread over instances, got %v", seen)
+	}
+}
+
+func TestPassiveEjectionAfterConsecutive5xx(t *testing.T) {
+	a, b := newInstance(t, "a"), newInstance(t, "b")
+	a.failing.Store(true)
+
+	rt := newPoolRouter(t, Upstream{
+		Name:    "svc",
+		Targets: []*url.URL{a.url(t), b.url(t)},
+		Passive: PassiveCheck{MaxFailures: 2, EjectionTime: time.Minute},
+	})
+
+	for i := 0; i < 4; i++ {
+		gatewaytest.ServeAs(rt, nil, "GET", "/")
+	}
+
+	st := rt.Health()[0].Instances
+	if !st[0].Ejected || st[1].Ejected {
+		t.Fatalf("expected only a ejected, got %+v", st)
+	}
+
+	for i := 0; i < 4; i++ {
+		if rr := gatewaytest.ServeAs(rt, nil, "GET", "/"); rr.Body.String() != "b" {
+			t.Fatalf("expected traffic on b only, got %d %q", rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestPassiveEjectionOnConnectionError(t *testing.T) {
+	dead := newInstance(t, "dead")
+	deadURL := dead.url(t)
+	dead.srv.Close()
+
+	rt := newPoolRouter(t, Upstream{
+		Name:    "svc",
+		Targets: []*url.URL{deadURL},
+		Passive: PassiveCheck{MaxFailures: 1, EjectionTime: time.Minute},
+	})
+
+	if rr := gatewaytest.ServeAs(rt, nil, "GET", "/"); rr.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d", rr.Code)
+	}
+	if rr := gatewaytest.ServeAs(rt, nil, "GET", "/"); rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 once ejected, got %d", rr.Code)
+	}
+}
+
+func TestActiveHealthCheckMarksInstanceDown(t *testing.T) {
+	a, b := newInstance(t, "a"), newInstance(t, "b")
+	rt := newPoolRouter(t, Upstream{
+		Name:        "svc",
+		Targets:     []*url.URL{a.url(t), b.url(t)},
+		HealthCheck: HealthCheck{Path: "/health", Interval: 10 * time.Millisecond, Timeout: time.Second},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rt.StartHealthChecks(ctx)
+
+	a.failing.Store(true)
+	gatewaytest.WaitFor(t, func() bool { return !rt.Health()[0].Instances[0].Healthy })
+
+	for i := 0; i < 4; i++ {
+		if rr := gatewaytest.ServeAs(rt, nil, "GET", "/"); rr.Body.String() != "b" {
+			t.Fatalf("expected traffic on b only, got %q", rr.Body.String())
+		}
+	}
+
+	a.failing.Store(false)
+	gatewaytest.WaitFor(t, func() bool { return rt.Health()[0].Instances[0].Healthy })
+}
+
+func TestAllInstancesDownFailsClosed(t *testing.T) {
+	a := newInstance(t, "a")
+	rt := newPoolRouter(t, Upstream{Name: "svc", Targets: []*url.URL{a.url(t)}})
+
+	rt.pools[0].instances[0].healthy.Store(false)
+
+	if rr := gatewaytest.ServeAs(rt, nil, "GET", "/"); rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rr.Code)
+	}
+	if a.hits.Load() != 0 {
+		t.Fatal("unhealthy instance must not receive traffic")
+	}
+}
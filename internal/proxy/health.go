@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+ACTIVE HEALTH CHECKS
+
+- One goroutine per pool, probing every instance each interval
+- Probe = GET <instance><path>; any 2xx/3xx is healthy
+- Anything else (error, timeout, 4xx, 5xx) marks the instance unhealthy
+- Probes use their own client; they never go through the gateway chain
+*/
+
+// startHealthChecks probes the pool until ctx is cancelled.
+// No-op if active checks are disabled for this pool.
+func (p *Pool) startHealthChecks(ctx context.Context, client *http.Client) {
+	if p.health.Path == "" {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(p.health.Interval)
+		defer ticker.Stop()
+
+		for {
+			for _, inst := range p.instances {
+				p.probe(ctx, client, inst)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (p *Pool) probe(ctx context.Context, client *http.Client, inst *Instance) {
+	ctx, cancel := context.WithTimeout(ctx, p.health.Timeout)
+	defer cancel()
+
+	target := strings.TrimSuffix(inst.URL.String(), "/") + p.health.Path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		p.markUnhealthy(inst, err.Error())
+		return
+	}
+	req.Header.Set("User-Agent", "zero-trust-gateway-healthcheck")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		p.markUnhealthy(inst, "health check: "+err.Error())
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		p.markUnhealthy(inst, fmt.Sprintf("health check: status %d", resp.StatusCode))
+		return
+	}
+
+	inst.healthy.Store(true)
+}
+
+func (p *Pool) markUnhealthy(inst *Instance, reason string) {
+	inst.lastError.Store(&reason)
+	inst.healthy.Store(false)
+}
@@ -0,0 +1,292 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/audit"
+	"Zero-TrustAPIGateWayServer/internal/certs"
+)
+
+/*
+ROUTING DESIGN
+
+- Routes are static data declared in the gateway config
+- Routes are evaluated in declaration order; first match wins
+- A route matches on path prefix (segment aware), and optionally
+  on host and method
+- Default deny: a request that matches no route is rejected and
+  never reaches any upstream
+- Each route owns its reverse proxy, path rewrite and timeout
+- Each route forwards to an upstream pool (see balancer.go), which
+  picks the instance per request
+*/
+
+// DefaultTimeout applies to routes that do not set their own.
+const DefaultTimeout = 10 * time.Second
+
+// Upstream is a named backend service with one or more instances.
+type Upstream struct {
+	Name        string
+	Targets     []*url.URL
+	Strategy    Strategy // empty = RoundRobin
+	HealthCheck HealthCheck
+	Passive     PassiveCheck
+	TLS         *tls.Config // for https targets; nil = system roots, no client cert
+}
+
+// Route maps matching requests to a named upstream.
+type Route struct {
+	Name       string
+	PathPrefix string   // segment-aware: /api matches /api and /api/x, not /apix
+	Hosts      []string // empty = any host
+	Methods    []string // empty = any method
+	Upstream   string
+
+	// Path rewrite (at most one)
+	StripPrefix   bool   // /api/users -> /users
+	ReplacePrefix string // /api/users -> <ReplacePrefix>/users
+
+	Timeout time.Duration // zero = DefaultTimeout
+}
+
+type compiledRoute struct {
+	Route
+	pool  *Pool
+	proxy *httputil.ReverseProxy
+}
+
+// Router dispatches requests to per-route reverse proxies.
+type Router struct {
+	routes []*compiledRoute
+	pools  []*Pool
+}
+
+// Context key carrying the instance chosen for this request
+// from Router.ServeHTTP to the proxy's Rewrite hook.
+type instanceKey struct{}
+
+// NewRouter validates the routing table and builds one proxy per route.
+// Any unknown upstream or malformed route is an error.
+func NewRouter(upstreams []Upstream, routes []Route) (*Router, error) {
+	rt := &Router{}
+
+	byName := make(map[string]*Pool, len(upstreams))
+	for _, u := range upstreams {
+		if u.Name == "" {
+			return nil, errors.New("upstream name is required")
+		}
+		if _, dup := byName[u.Name]; dup {
+			return nil, fmt.Errorf("duplicate upstream %q", u.Name)
+		}
+		pool, err := newPool(u)
+		if err != nil {
+			return nil, err
+		}
+		byName[u.Name] = pool
+		rt.pools = append(rt.pools, pool)
+	}
+
+	for i, route := range routes {
+		pool, ok := byName[route.Upstream]
+		if !ok {
+			return nil, fmt.Errorf("route[%d]: unknown upstream %q", i, route.Upstream)
+		}
+		if !strings.HasPrefix(route.PathPrefix, "/") {
+			return nil, fmt.Errorf("route[%d]: path prefix must start with '/'", i)
+		}
+		if route.StripPrefix && route.ReplacePrefix != "" {
+			return nil, fmt.Errorf("route[%d]: strip_prefix and replace_prefix are mutually exclusive", i)
+		}
+		if route.Timeout <= 0 {
+			route.Timeout = DefaultTimeout
+		}
+
+		rt.routes = append(rt.routes, &compiledRoute{
+			Route: route,
+			pool:  pool,
+			proxy: newReverseProxy(route, pool),
+		})
+	}
+
+	return rt, nil
+}
+
+// ServeHTTP forwards the request through the first matching route.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := rt.match(r)
+	if route == nil {
+		// Default deny: unrouted traffic never leaves the gateway
+		http.Error(w, "no matching route", http.StatusForbidden)
+		return
+	}
+
+	inst, err := route.pool.pick(r)
+	if err != nil {
+		// Fail closed: never forward to a known-bad instance
+		http.Error(w, "no healthy upstream", http.StatusServiceUnavailable)
+		return
+	}
+
+	inst.active.Add(1)
+	defer inst.active.Add(-1)
+
+	ctx, cancel := context.WithTimeout(r.Context(), route.Timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, instanceKey{}, inst)
+
+	route.proxy.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// StartHealthChecks runs active health checks for every pool that has
+// them configured, until ctx is cancelled.
+func (rt *Router) StartHealthChecks(ctx context.Context) {
+	for _, pool := range rt.pools {
+		// Probes use the upstream's own TLS settings
+		client := &http.Client{
+			Transport: pool.transport,
+			// Never follow redirects to somewhere other than the instance
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		pool.startHealthChecks(ctx, client)
+	}
+}
+
+// Health returns the current state of every upstream instance.
+func (rt *Router) Health() []UpstreamStatus {
+	out := make([]UpstreamStatus, len(rt.pools))
+	for i, pool := range rt.pools {
+		out[i] = pool.status()
+	}
+	return out
+}
+
+func (rt *Router) match(r *http.Request) *compiledRoute {
+	for _, route := range rt.routes {
+		if route.matches(r) {
+			return route
+		}
+	}
+	return nil
+}
+
+func (c *compiledRoute) matches(r *http.Request) bool {
+	if len(c.Methods) > 0 && !containsFold(c.Methods, r.Method) {
+		return false
+	}
+
+	if len(c.Hosts) > 0 && !containsFold(c.Hosts, hostOnly(r.Host)) {
+		return false
+	}
+
+	return hasPathPrefix(r.URL.Path, c.PathPrefix)
+}
+
+func newReverseProxy(route Route, pool *Pool) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Transport: pool.transport,
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			inst := pr.In.Context().Value(instanceKey{}).(*Instance)
+
+			pr.Out.URL.Path = rewritePath(route, pr.In.URL.Path)
+			pr.Out.URL.RawPath = ""
+			pr.SetURL(inst.URL)
+			pr.SetXForwarded()
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			inst := resp.Request.Context().Value(instanceKey{}).(*Instance)
+
+			if resp.StatusCode >= 500 {
+				pool.reportFailure(inst, fmt.Sprintf("status %d", resp.StatusCode))
+			} else {
+				pool.reportSuccess(inst)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("route %q: upstream error: %v", route.Name, err)
+
+			// A failed handshake or certificate check is never retried
+			// in the clear; the client gets a plain 502
+			if certs.IsTLSError(err) {
+				audit.SetReason(r.Context(), "upstream "+pool.name+": TLS verification failed: "+err.Error())
+			}
+
+			// A client that went away says nothing about the instance
+			if !errors.Is(err, context.Canceled) {
+				inst := r.Context().Value(instanceKey{}).(*Instance)
+				pool.reportFailure(inst, err.Error())
+			}
+
+			if errors.Is(err, context.DeadlineExceeded) {
+				http.Error(w, "upstream timeout", http.StatusGatewayTimeout)
+				return
+			}
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		},
+	}
+}
+
+/*
+Helpers
+*/
+
+// hasPathPrefix reports whether path is prefix itself or lies below it.
+func hasPathPrefix(path, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func rewritePath(route Route, path string) string {
+	if !route.StripPrefix && route.ReplacePrefix == "" {
+		return path
+	}
+
+	rest := strings.TrimPrefix(path, strings.TrimSuffix(route.PathPrefix, "/"))
+
+	if route.ReplacePrefix != "" {
+		return singleJoin(route.ReplacePrefix, rest)
+	}
+
+	if rest == "" {
+		return "/"
+	}
+	return rest
+}
+
+func singleJoin(a, b string) string {
+	if b == "" {
+		return a
+	}
+	return strings.TrimSuffix(a, "/") + b
+}
+
+func hostOnly(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return hostport
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/audit"
+	"Zero-TrustAPIGateWayServer/internal/certs"
+	"Zero-TrustAPIGateWayServer/internal/certs/certstest"
+)
+
+// newBackend returns an upstream that echoes its name and the path it received.
+func newBackend(t *testing.T, name string) *Upstream {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", name, r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Upstream{Name: name, Targets: []*url.URL{u}}
+}
+
+func serve(rt http.Handler, method, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRoutesToNamedUpstream(t *testing.T) {
+	users := newBackend(t, "users")
+	billing := newBackend(t, "billing")
+
+	rt, err := NewRouter([]Upstream{*users, *billing}, []Route{
+		{Name: "users", PathPrefix: "/api/users", Upstream: "users"},
+		{Name: "billing", PathPrefix: "/api/billing", Upstream: "billing"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rr := serve(rt, "GET", "/api/users/42"); rr.Body.String() != "users /api/users/42" {
+		t.Fatalf("unexpected users response: %d %q", rr.Code, rr.Body.String())
+	}
+	if rr := serve(rt, "GET", "/api/billing"); rr.Body.String() != "billing /api/billing" {
+		t.Fatalf("unexpected billing response: %d %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestUnmatchedRouteDenied(t *testing.T) {
+	users := newBackend(t, "users")
+
+	rt, err := NewRouter([]Upstream{*users}, []Route{
+		{Name: "users", PathPrefix: "/api/users", Upstream: "users"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/", "/api", "/api/usersevil", "/other"} {
+		if rr := serve(rt, "GET", path); rr.Code != http.StatusForbidden {
+			t.Fatalf("%s: expected 403, got %d", path, rr.Code)
+		}
+	}
+}
+
+func TestMethodAndHostMatching(t *testing.T) {
+	users := newBackend(t, "users")
+
+	rt, err := NewRouter([]Upstream{*users}, []Route{
+		{Name: "users", PathPrefix: "/", Hosts: []string{"api.example.com"}, Methods: []string{"GET"}, Upstream: "users"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "http://api.example.com:8080/x", nil)
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for matching host, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "http://other.example.com/x", nil)
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for other host, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest("POST", "http://api.example.com/x", nil)
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for other method, got %d", rr.Code)
+	}
+}
+
+func TestPathRewrite(t *testing.T) {
+	svc := newBackend(t, "svc")
+
+	rt, err := NewRouter([]Upstream{*svc}, []Route{
+		{Name: "strip", PathPrefix: "/strip", Upstream: "svc", StripPrefix: true},
+		{Name: "replace", PathPrefix: "/old", Upstream: "svc", ReplacePrefix: "/v2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"/strip/a/b": "svc /a/b",
+		"/strip":     "svc /",
+		"/old/items": "svc /v2/items",
+		"/old":       "svc /v2",
+	}
+	for path, want := range cases {
+		if rr := serve(rt, "GET", path); rr.Body.String() != want {
+			t.Fatalf("%s: expected %q, got %q", path, want, rr.Body.String())
+		}
+	}
+}
+
+func TestRouteTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	rt, err := NewRouter([]Upstream{{Name: "slow", Targets: []*url.URL{u}}}, []Route{
+		{Name: "slow", PathPrefix: "/", Upstream: "slow", Timeout: 20 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rr := serve(rt, "GET", "/"); rr.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", rr.Code)
+	}
+}
+
+func TestUnknownUpstreamRejected(t *testing.T) {
+	_, err := NewRouter(nil, []Route{{Name: "r", PathPrefix: "/", Upstream: "missing"}})
+	if err == nil {
+		t.Fatal("expected unknown upstream error")
+	}
+}
+
+func TestUpstreamTLS(t *testing.T) {
+	dir := t.TempDir()
+	upstreamCA := certstest.NewCA(t, "upstream-ca")
+	gatewayCA := certstest.NewCA(t, "gateway-ca")
+	otherCA := certstest.NewCA(t, "other-ca")
+
+	// The upstream presents a SPIFFE ID and requires a gateway client cert
+	serverTmpl := certstest.Server("users.internal")
+	spiffeID, _ := url.Parse("spiffe://example.org/users")
+	serverTmpl.URIs = []*url.URL{spiffeID}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello %s", r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{upstreamCA.KeyPair(t, serverTmpl)},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    gatewayCA.Pool(),
+	}
+	srv.StartTLS()
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+
+	caFile := filepath.Join(dir, "upstream-ca.pem")
+	certstest.WriteFile(t, caFile, upstreamCA.PEM())
+	otherCAFile := filepath.Join(dir, "other-ca.pem")
+	certstest.WriteFile(t, otherCAFile, otherCA.PEM())
+	certFile, keyFile := gatewayCA.WriteFiles(t, dir, "gateway", certstest.Client("gateway", ""))
+
+	valid := certs.UpstreamOptions{
+		CAFile:     caFile,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ServerName: "users.internal",
+		SPIFFEIDs:  []string{"spiffe://example.org/users"},
+	}
+
+	tests := []struct {
+		name   string
+		modify func(o *certs.UpstreamOptions)
+		reason string // "" = request must succeed
+	}{
+		{"valid", func(o *certs.UpstreamOptions) {}, ""},
+		{"untrusted CA", func(o *certs.UpstreamOptions) { o.CAFile = otherCAFile }, "certificate signed by unknown authority"},
+		{"wrong server name", func(o *certs.UpstreamOptions) { o.ServerName = "billing.internal" }, "billing.internal"},
+		{"trust domain case ignored", func(o *certs.UpstreamOptions) { o.SPIFFEIDs = []string{"spiffe://Example.ORG/users"} }, ""},
+		{"SPIFFE ID not pinned", func(o *certs.UpstreamOptions) { o.SPIFFEIDs = []string{"spiffe://example.org/billing"} }, "is not pinned"},
+		{"no client certificate", func(o *certs.UpstreamOptions) { o.CertFile, o.KeyFile = "", "" }, "certificate required"},
+	}
+
+	for _, tt := range tests {
+		opts := valid
+		tt.modify(&opts)
+		tlsConfig, err := certs.UpstreamTLSConfig(opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		rt, err := NewRouter([]Upstream{{Name: "users", Targets: []*url.URL{target}, TLS: tlsConfig}}, []Route{
+			{Name: "users", PathPrefix: "/", Upstream: "users"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest("GET", "/api", nil)
+		ctx := audit.WithReason(req.Context())
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req.WithContext(ctx))
+		reason, _ := audit.Reason(ctx)
+
+		if tt.reason == "" {
+			if rr.Code != http.StatusOK || rr.Body.String() != "hello gateway" {
+				t.Errorf("%s: expected 200 via mTLS, got %d %q (%s)", tt.name, rr.Code, rr.Body.String(), reason)
+			}
+			continue
+		}
+		if rr.Code != http.StatusBadGateway {
+			t.Errorf("%s: expected 502, got %d", tt.name, rr.Code)
+		}
+		if !strings.HasPrefix(reason, "upstream users: TLS verification failed") || !strings.Contains(reason, tt.reason) {
+			t.Errorf("%s: unexpected audit reason %q", tt.name, reason)
+		}
+	}
+}
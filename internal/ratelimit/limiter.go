@@ -30,12 +30,15 @@ Fail-closed behavior:
 
 /*
 
-Configuration (constants only, per requirements)
+Configuration
+
+Primary limits are configurable (see Config); the constants are the
+defaults. The fallback limit is deliberately NOT configurable.
 
 */
 
 const (
-	// Primary limits
+	// Primary limits (defaults)
 	IPBucketCapacity    = 20
 	IPRefillPerSecond   = 5
 	UserBucketCapacity  = 40
@@ -46,6 +49,24 @@ const (
 	FallbackRefillPS = 1
 )
 
+// Config holds the primary bucket sizes and refill rates.
+type Config struct {
+	IPCapacity   int
+	IPRefillPS   int
+	UserCapacity int
+	UserRefillPS int
+}
+
+// DefaultConfig returns the built-in limits.
+func DefaultConfig() Config {
+	return Config{
+		IPCapacity:   IPBucketCapacity,
+		IPRefillPS:   IPRefillPerSecond,
+		UserCapacity: UserBucketCapacity,
+		UserRefillPS: UserRefillPerSecond,
+	}
+}
+
 /*
 
 Clock abstraction (for testability)
@@ -109,6 +130,7 @@ Limiter
 type Limiter struct {
 	mu    sync.Mutex
 	clock Clock
+	cfg   Config
 
 	ipBuckets   map[string]*bucket
 	userBuckets map[string]*bucket
@@ -120,9 +142,17 @@ type userIDKeyType struct{}
 
 var UserIDKey = userIDKeyType{}
 
+// NewLimiter returns a limiter using the default limits.
 func NewLimiter() *Limiter {
+	return NewLimiterWithConfig(DefaultConfig())
+}
+
+// NewLimiterWithConfig returns a limiter using the given limits.
+// The caller is responsible for validating cfg (all values positive).
+func NewLimiterWithConfig(cfg Config) *Limiter {
 	return &Limiter{
 		clock:       realClock{},
+		cfg:         cfg,
 		ipBuckets:   make(map[string]*bucket),
 		userBuckets: make(map[string]*bucket),
 	}
@@ -180,7 +210,7 @@ func (l *Limiter) safeAllow(ctx context.Context, remoteAddr string) (allowed boo
 	// IP bucket (always enforced)
 	ipBucket := l.ipBuckets[ip]
 	if ipBucket == nil {
-		ipBucket = newBucket(IPBucketCapacity, IPRefillPerSecond, now)
+		ipBucket = newBucket(l.cfg.IPCapacity, l.cfg.IPRefillPS, now)
 		l.ipBuckets[ip] = ipBucket
 	}
 
@@ -192,7 +222,7 @@ func (l *Limiter) safeAllow(ctx context.Context, remoteAddr string) (allowed boo
 	if uid, ok := ctx.Value(UserIDKey).(string); ok && uid != "" {
 		userBucket := l.userBuckets[uid]
 		if userBucket == nil {
-			userBucket = newBucket(UserBucketCapacity, UserRefillPerSecond, now)
+			userBucket = newBucket(l.cfg.UserCapacity, l.cfg.UserRefillPS, now)
 			l.userBuckets[uid] = userBucket
 		}
 
@@ -142,3 +142,35 @@ func TestUserIDLimitApplied(t *testing.T) {
 		t.Fatalf("expected user-level 429")
 	}
 }
+
+func TestConfiguredCapacityApplied(t *testing.T) {
+	fc := &fakeClock{now: time.Unix(0, 0)}
+	limiter := NewLimiterWithConfig(Config{
+		IPCapacity:   3,
+		IPRefillPS:   1,
+		UserCapacity: 10,
+		UserRefillPS: 1,
+	})
+	limiter.SetClock(fc)
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "7.7.7.7:1"
+
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 after configured capacity, got %d", rr.Code)
+	}
+}
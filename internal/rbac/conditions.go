@@ -0,0 +1,200 @@
+package rbac
+
+import (
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+/*
+ATTRIBUTE CONDITIONS
+
+A rule may narrow itself with conditions. They are static data compiled
+at load time: no scripting and no expressions. They are evaluated only
+after method, path, auth types and roles have matched. A rule whose
+conditions are not met does not apply, whatever its effect.
+
+  source_cidrs      client IP in ANY of the prefixes
+  time              request time inside ANY of the windows
+  headers           ALL listed headers present and non-empty
+  claims            ALL listed JWT claims equal the given value
+                    (array claims: contains it); non-JWT callers never match
+  param_is_subject  template parameter == authenticated subject
+                    ("users may only access /api/users/{id} where id == sub")
+
+All conditions present must hold. The client IP is the TCP peer:
+X-Forwarded-For is client-controlled and never trusted here.
+*/
+
+// Conditions narrow a rule; the zero value has none.
+type Conditions struct {
+	SourceCIDRs    []netip.Prefix
+	TimeWindows    []TimeWindow
+	Headers        []string          // canonical header names
+	Claims         map[string]string // dotted claim path -> value
+	ParamIsSubject string            // template parameter name
+}
+
+// TimeWindow is a daily window in a time zone. Start after End wraps
+// past midnight (22:00-06:00).
+type TimeWindow struct {
+	Days     []time.Weekday // empty = every day
+	Start    time.Duration  // since local midnight
+	End      time.Duration
+	Location *time.Location // nil = UTC
+}
+
+// Attributes are the request properties rules are evaluated against.
+type Attributes struct {
+	Method   string
+	Path     string
+	SourceIP netip.Addr // invalid when unknown: source_cidrs never match
+	Header   http.Header
+	Time     time.Time
+}
+
+// RequestAttributes extracts the evaluation attributes of r.
+func RequestAttributes(r *http.Request, now time.Time) Attributes {
+	var ip netip.Addr
+	if ap, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		ip = ap.Addr().Unmap()
+	}
+
+	return Attributes{
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		SourceIP: ip,
+		Header:   r.Header,
+		Time:     now,
+	}
+}
+
+// unmet returns the first condition the request does not meet, or ""
+// when all are met.
+func (c *Conditions) unmet(attrs Attributes, identity *auth.Identity, params Params) string {
+	if c == nil {
+		return ""
+	}
+
+	if len(c.SourceCIDRs) > 0 && !inAnyPrefix(attrs.SourceIP, c.SourceCIDRs) {
+		return "source IP not in source_cidrs"
+	}
+
+	if len(c.TimeWindows) > 0 && !inAnyWindow(attrs.Time, c.TimeWindows) {
+		return "outside time windows"
+	}
+
+	for _, h := range c.Headers {
+		if strings.TrimSpace(attrs.Header.Get(h)) == "" {
+			return "missing header " + h
+		}
+	}
+
+	// In name order, so the reason is the same on every evaluation
+	paths := make([]string, 0, len(c.Claims))
+	for path := range c.Claims {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		got, ok := identity.Claim(path)
+		if identity.Type != auth.AuthJWT || !ok || !claimEquals(got, c.Claims[path]) {
+			return "claim " + path + " does not match"
+		}
+	}
+
+	if c.ParamIsSubject != "" {
+		if identity.Subject == "" || params[c.ParamIsSubject] != identity.Subject {
+			return "path parameter " + c.ParamIsSubject + " is not the subject"
+		}
+	}
+
+	return ""
+}
+
+func inAnyPrefix(ip netip.Addr, prefixes []netip.Prefix) bool {
+	if !ip.IsValid() {
+		return false
+	}
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func inAnyWindow(t time.Time, windows []TimeWindow) bool {
+	for _, w := range windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w TimeWindow) contains(t time.Time) bool {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+
+	// Wall-clock time of day, not time elapsed since midnight: on a DST
+	// transition day the two differ by an hour
+	since := time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second +
+		time.Duration(t.Nanosecond())
+	day := t.Weekday()
+
+	if w.Start <= w.End {
+		return since >= w.Start && since < w.End && w.onDay(day)
+	}
+
+	// Wraps past midnight: the early part belongs to the previous day's window
+	if since >= w.Start {
+		return w.onDay(day)
+	}
+	if since < w.End {
+		return w.onDay((day + 6) % 7)
+	}
+	return false
+}
+
+func (w TimeWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// claimEquals compares a claim with the configured value: scalars by
+// their string form, arrays by membership.
+func claimEquals(claim interface{}, want string) bool {
+	switch v := claim.(type) {
+	case string:
+		return v == want
+	case bool:
+		return strconv.FormatBool(v) == want
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64) == want
+	case []interface{}:
+		for _, e := range v {
+			if s, ok := e.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
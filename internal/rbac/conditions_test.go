@@ -0,0 +1,148 @@
+package rbac
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+func TestConditions(t *testing.T) {
+	// Wednesday 2026-06-03 10:30 UTC
+	wed := time.Date(2026, 6, 3, 10, 30, 0, 0, time.UTC)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+
+	jwtUser := &auth.Identity{
+		Type:    auth.AuthJWT,
+		Subject: "alice",
+		Claims: map[string]interface{}{
+			"tenant":   "acme",
+			"verified": true,
+			"level":    float64(3),
+			"org":      map[string]interface{}{"groups": []interface{}{"eng", "ops"}},
+		},
+	}
+	keyUser := &auth.Identity{Type: auth.AuthAPIKey, Subject: "alice"}
+
+	base := Attributes{
+		SourceIP: netip.MustParseAddr("10.1.2.3"),
+		Header:   http.Header{"X-Request-Id": {"abc"}},
+		Time:     wed,
+	}
+
+	tests := []struct {
+		name     string
+		cond     Conditions
+		identity *auth.Identity
+		attrs    func(Attributes) Attributes
+		params   Params
+		met      bool
+	}{
+		{"cidr match", Conditions{SourceCIDRs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}, jwtUser, nil, nil, true},
+		{"cidr miss", Conditions{SourceCIDRs: []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")}}, jwtUser, nil, nil, false},
+		{"unknown ip", Conditions{SourceCIDRs: []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")}}, jwtUser,
+			func(a Attributes) Attributes { a.SourceIP = netip.Addr{}; return a }, nil, false},
+
+		{"in window", Conditions{TimeWindows: []TimeWindow{{Start: 9 * time.Hour, End: 17 * time.Hour}}}, jwtUser, nil, nil, true},
+		{"outside window", Conditions{TimeWindows: []TimeWindow{{Start: 11 * time.Hour, End: 17 * time.Hour}}}, jwtUser, nil, nil, false},
+		{"weekday miss", Conditions{TimeWindows: []TimeWindow{{Days: []time.Weekday{time.Saturday}, Start: 0, End: 24 * time.Hour}}}, jwtUser, nil, nil, false},
+		{"window time zone", Conditions{TimeWindows: []TimeWindow{{Start: 12 * time.Hour, End: 13 * time.Hour, Location: berlin}}}, jwtUser, nil, nil, true},
+		// 2026-03-29 09:30 in Berlin, the day clocks go forward
+		{"dst day, in window", Conditions{TimeWindows: []TimeWindow{{Start: 9 * time.Hour, End: 17 * time.Hour, Location: berlin}}}, jwtUser,
+			func(a Attributes) Attributes { a.Time = time.Date(2026, 3, 29, 9, 30, 0, 0, berlin); return a }, nil, true},
+		{"dst day, before window", Conditions{TimeWindows: []TimeWindow{{Start: 9 * time.Hour, End: 17 * time.Hour, Location: berlin}}}, jwtUser,
+			func(a Attributes) Attributes { a.Time = time.Date(2026, 3, 29, 8, 30, 0, 0, berlin); return a }, nil, false},
+		{"overnight, late part", Conditions{TimeWindows: []TimeWindow{{Days: []time.Weekday{time.Wednesday}, Start: 22 * time.Hour, End: 6 * time.Hour}}}, jwtUser,
+			func(a Attributes) Attributes { a.Time = wed.Add(12 * time.Hour); return a }, nil, true},
+		{"overnight, early part is previous day", Conditions{TimeWindows: []TimeWindow{{Days: []time.Weekday{time.Tuesday}, Start: 22 * time.Hour, End: 6 * time.Hour}}}, jwtUser,
+			func(a Attributes) Attributes { a.Time = wed.Add(-8 * time.Hour); return a }, nil, true},
+		{"overnight, daytime", Conditions{TimeWindows: []TimeWindow{{Start: 22 * time.Hour, End: 6 * time.Hour}}}, jwtUser, nil, nil, false},
+
+		{"header present", Conditions{Headers: []string{"X-Request-Id"}}, jwtUser, nil, nil, true},
+		{"header missing", Conditions{Headers: []string{"X-Tenant"}}, jwtUser, nil, nil, false},
+
+		{"claim string", Conditions{Claims: map[string]string{"tenant": "acme"}}, jwtUser, nil, nil, true},
+		{"claim bool", Conditions{Claims: map[string]string{"verified": "true"}}, jwtUser, nil, nil, true},
+		{"claim number", Conditions{Claims: map[string]string{"level": "3"}}, jwtUser, nil, nil, true},
+		{"claim array", Conditions{Claims: map[string]string{"org.groups": "ops"}}, jwtUser, nil, nil, true},
+		{"claim mismatch", Conditions{Claims: map[string]string{"tenant": "globex"}}, jwtUser, nil, nil, false},
+		{"claim absent", Conditions{Claims: map[string]string{"region": "eu"}}, jwtUser, nil, nil, false},
+		{"claim needs jwt", Conditions{Claims: map[string]string{"tenant": "acme"}}, keyUser, nil, nil, false},
+
+		{"param is subject", Conditions{ParamIsSubject: "id"}, jwtUser, nil, Params{"id": "alice"}, true},
+		{"param is not subject", Conditions{ParamIsSubject: "id"}, jwtUser, nil, Params{"id": "bob"}, false},
+		{"empty subject", Conditions{ParamIsSubject: "id"}, &auth.Identity{}, nil, Params{"id": ""}, false},
+	}
+	for _, tt := range tests {
+		attrs := base
+		if tt.attrs != nil {
+			attrs = tt.attrs(base)
+		}
+		unmet := tt.cond.unmet(attrs, tt.identity, tt.params)
+		if (unmet == "") != tt.met {
+			t.Errorf("%s: met = %v (unmet %q), want %v", tt.name, unmet == "", unmet, tt.met)
+		}
+	}
+}
+
+func TestClaimReasonIsStable(t *testing.T) {
+	cond := Conditions{Claims: map[string]string{"tenant": "x", "region": "x", "org.groups": "x", "level": "x"}}
+	identity := &auth.Identity{Type: auth.AuthJWT, Claims: map[string]interface{}{}}
+
+	for i := 0; i < 20; i++ {
+		if got := cond.unmet(Attributes{}, identity, nil); got != "claim level does not match" {
+			t.Fatalf("run %d: reason %q", i, got)
+		}
+	}
+}
+
+func TestConditionsEvaluatedAfterRoles(t *testing.T) {
+	policies := PolicySet{Policies: []Policy{{
+		Method:     "GET",
+		Path:       "/api/users/{id}",
+		Matcher:    mustCompile(t, MatchTemplate, "/api/users/{id}"),
+		Roles:      []string{"user"},
+		Conditions: &Conditions{ParamIsSubject: "id"},
+	}}}
+
+	handler := RBACMiddleware(policies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		roles []string
+		path  string
+		want  int
+	}{
+		{[]string{"user"}, "/api/users/alice", http.StatusOK},
+		{[]string{"user"}, "/api/users/bob", http.StatusForbidden},
+		{[]string{"guest"}, "/api/users/alice", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req = req.WithContext(auth.WithIdentity(req.Context(), &auth.Identity{Subject: "alice", Roles: tt.roles}))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.want {
+			t.Errorf("%v %s: expected %d, got %d", tt.roles, tt.path, tt.want, rr.Code)
+		}
+	}
+}
+
+func TestRequestAttributesUseTCPPeer(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.RemoteAddr = "[::ffff:10.0.0.7]:4242"
+	req.Header.Set("X-Forwarded-For", "192.168.1.1")
+
+	attrs := RequestAttributes(req, time.Now())
+	if attrs.SourceIP != netip.MustParseAddr("10.0.0.7") {
+		t.Fatalf("source IP = %v", attrs.SourceIP)
+	}
+}
@@ -0,0 +1,92 @@
+package rbac
+
+import (
+	"fmt"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+/*
+DECISIONS
+
+Evaluate is the single, pure evaluation path: RBACMiddleware, the admin
+explain endpoint and "gatewayctl policy explain" all call it. Explain
+also builds its request through request validation's path
+canonicalization and RequestAttributes, so an explanation sees the
+same method, path and attributes RBAC would.
+
+The reason names the deciding rule. When no rule applies it names the
+rule that got furthest (method, then path, auth type, roles,
+conditions) and what stopped it. That is the rule the operator most
+likely meant.
+*/
+
+// Decision is the outcome of evaluating one request.
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Public  bool   `json:"public,omitempty"` // allowed by a public route
+	Rule    int    `json:"rule"`             // deciding policy index, -1 = none
+	Effect  Effect `json:"effect"`           // effect of the decision
+	Reason  string `json:"reason"`
+
+	// Closest is the rule nearest to applying when none applied (-1 = no rules)
+	Closest int `json:"closest"`
+}
+
+// publicDecision is the decision for a request to a public route.
+var publicDecision = Decision{Allowed: true, Public: true, Rule: -1, Effect: Allow, Closest: -1, Reason: "public route"}
+
+// Evaluate decides a request. It has no side effects. identity may be
+// nil for an anonymous request, which only a public route allows.
+func (s PolicySet) Evaluate(attrs Attributes, identity *auth.Identity) Decision {
+	if s.IsPublic(attrs.Method, attrs.Path) {
+		return publicDecision
+	}
+	if identity == nil {
+		return Decision{Rule: -1, Effect: Deny, Closest: -1, Reason: "no identity"}
+	}
+
+	// Rules see the caller's inherited roles too
+	if len(s.Roles) > 0 {
+		expanded := *identity
+		expanded.Roles = s.Roles.Expand(identity.Roles)
+		identity = &expanded
+	}
+
+	if i := decide(s.Policies, attrs, identity); i >= 0 {
+		p := s.Policies[i]
+		if p.Effect == Deny {
+			return Decision{Rule: i, Effect: Deny, Closest: -1, Reason: "denied by " + describePolicy(i, p)}
+		}
+		return Decision{Allowed: true, Rule: i, Effect: Allow, Closest: -1, Reason: "allowed by " + describePolicy(i, p)}
+	}
+
+	d := Decision{Rule: -1, Effect: Deny, Closest: -1, Reason: "no rule applies"}
+	if len(s.Policies) == 0 {
+		d.Reason = "no policies loaded (deny all)"
+		return d
+	}
+
+	best, why := -1, ""
+	for i, p := range s.Policies {
+		stage, reason := p.check(attrs, identity)
+		if stage > best {
+			best, why, d.Closest = stage, reason, i
+		}
+	}
+	d.Reason += "; closest " + describePolicy(d.Closest, s.Policies[d.Closest]) + ": " + why
+	return d
+}
+
+func describePolicy(i int, p Policy) string {
+	return fmt.Sprintf("policy[%d] (%s %s)", i, p.Method, p.Path)
+}
+
+// describe renders the pattern for explanations.
+func (m *PathMatcher) describe() string {
+	pattern := m.Pattern
+	if pattern == "" {
+		pattern = "/"
+	}
+	return string(m.Type) + " " + pattern
+}
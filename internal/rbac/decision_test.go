@@ -0,0 +1,79 @@
+package rbac
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Zero-TrustAPIGateWayServer/internal/audit"
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+func explainSet(t *testing.T) PolicySet {
+	return PolicySet{
+		Policies: []Policy{
+			{Method: "GET", Path: "/api/public", Roles: []string{"user", "admin"}},
+			{Method: "POST", Path: "/api/admin", Roles: []string{"admin"}},
+			{Method: MethodAny, Path: "/api", Roles: []string{"admin"}},
+			{Method: "DELETE", Path: "/api/billing", Roles: []string{"admin"}, Effect: Deny},
+		},
+		Public: []PublicRoute{{Methods: []string{"GET"}, Matcher: mustCompile(t, MatchExact, "/health")}},
+	}
+}
+
+func TestEvaluateExplainsDecisions(t *testing.T) {
+	set := explainSet(t)
+	user := &auth.Identity{Type: auth.AuthAPIKey, Roles: []string{"user"}}
+	admin := &auth.Identity{Type: auth.AuthAPIKey, Roles: []string{"admin"}}
+
+	tests := []struct {
+		name      string
+		attrs     Attributes
+		identity  *auth.Identity
+		allowed   bool
+		rule      int
+		closest   int
+		reasonHas string
+	}{
+		{"allowed", attrs("GET", "/api/public"), user, true, 0, -1, "allowed by policy[0] (GET /api/public)"},
+		{"denied by rule", attrs("DELETE", "/api/billing"), admin, false, 3, -1, "denied by policy[3]"},
+		{"wrong role", attrs("POST", "/api/admin"), user, false, -1, 1, "closest policy[1] (POST /api/admin): caller has none of the roles admin"},
+		{"wrong method", attrs("PUT", "/api/public"), user, false, -1, 2, "closest policy[2]"},
+		{"public", attrs("GET", "/health"), nil, true, -1, -1, "public route"},
+		{"anonymous", attrs("GET", "/api/public"), nil, false, -1, -1, "no identity"},
+	}
+	for _, tt := range tests {
+		d := set.Evaluate(tt.attrs, tt.identity)
+		if d.Allowed != tt.allowed || d.Rule != tt.rule || d.Closest != tt.closest {
+			t.Errorf("%s: got %+v", tt.name, d)
+		}
+		if !strings.Contains(d.Reason, tt.reasonHas) {
+			t.Errorf("%s: reason %q does not contain %q", tt.name, d.Reason, tt.reasonHas)
+		}
+	}
+
+	if d := (PolicySet{}).Evaluate(attrs("GET", "/api"), user); d.Allowed || !strings.Contains(d.Reason, "deny all") {
+		t.Errorf("empty set: got %+v", d)
+	}
+}
+
+func TestRBACDenialReasonAudited(t *testing.T) {
+	handler := RBACMiddleware(explainSet(t))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("should not reach handler")
+	}))
+
+	req := httptest.NewRequest("POST", "/api/admin", nil)
+	ctx := audit.WithReason(auth.WithIdentity(req.Context(), &auth.Identity{Roles: []string{"user"}}))
+	req = req.WithContext(ctx)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden || strings.Contains(rr.Body.String(), "policy[") {
+		t.Fatalf("expected a bare 403, got %d %q", rr.Code, rr.Body.String())
+	}
+	reason, _ := audit.Reason(req.Context())
+	if !strings.HasPrefix(reason, "rbac: no rule applies; closest policy[1]") {
+		t.Fatalf("audit reason = %q", reason)
+	}
+}
@@ -3,7 +3,9 @@ package rbac
 import (
 	"net/http"
 	"strings"
+	"time"
 
+	"Zero-TrustAPIGateWayServer/internal/audit"
 	"Zero-TrustAPIGateWayServer/internal/auth"
 )
 
@@ -14,62 +16,156 @@ RBAC DESIGN PRINCIPLES:
 - Auth middleware only proves *who* the caller is
 - RBAC decides *what* they are allowed to do
 - Default deny: no matching rule => reject
-- No dynamic logic, no conditions, no expressions
+- Deny rules override allow rules (see precedence.go)
+- No dynamic logic, no expressions; conditions are static data
+  (see conditions.go)
 */
 
+// Effect is what a rule does to the requests it applies to.
+type Effect string
+
+const (
+	Allow Effect = "allow"
+	Deny  Effect = "deny"
+)
+
+// MethodAny in Policy.Method matches every HTTP method.
+const MethodAny = "*"
+
 type Policy struct {
-	Method string   // HTTP method: GET, POST, etc.
-	Path   string   // Path prefix match (e.g. /api/admin)
-	Roles  []string // Allowed roles
+	Method    string          // HTTP method: GET, POST, etc., or MethodAny
+	Path      string          // Path pattern (e.g. /api/admin), see match.go
+	Matcher   *PathMatcher    // Compiled Path; nil = segment-aware prefix
+	Roles     []string        // Roles the rule applies to
+	AuthTypes []auth.AuthType // Accepted auth schemes; empty = any
+	Effect    Effect          // Allow or Deny; empty = Allow
+
+	Conditions *Conditions // nil = none, see conditions.go
+}
+
+// matcher returns the compiled pattern, falling back to a prefix match.
+func (p Policy) matcher() *PathMatcher {
+	if p.Matcher != nil {
+		return p.Matcher
+	}
+	return &PathMatcher{Type: MatchPrefix, Pattern: strings.TrimSuffix(p.Path, "/")}
+}
+
+// matchPath matches the request path against the compiled pattern.
+func (p Policy) matchPath(path string) (Params, bool) {
+	return p.matcher().Match(path)
+}
+
+// applies reports whether the rule covers this request and identity.
+func (p Policy) applies(attrs Attributes, identity *auth.Identity) bool {
+	stage, _ := p.check(attrs, identity)
+	return stage == stageApplies
+}
+
+// Evaluation stages, in order. check reports how far a rule got, which
+// is how Evaluate finds the rule closest to matching.
+const (
+	stageMethod = iota
+	stagePath
+	stageAuthType
+	stageRoles
+	stageConditions
+	stageApplies
+)
+
+// check returns the stage at which the rule stopped matching, and why.
+func (p Policy) check(attrs Attributes, identity *auth.Identity) (int, string) {
+	// Method must match exactly (or be MethodAny)
+	if p.Method != MethodAny && attrs.Method != p.Method {
+		return stageMethod, "method " + attrs.Method + " does not match " + p.Method
+	}
+
+	// Path must match the rule's pattern
+	params, ok := p.matchPath(attrs.Path)
+	if !ok {
+		return stagePath, "path " + attrs.Path + " does not match " + p.matcher().describe()
+	}
+
+	// Auth scheme restriction (e.g. admin routes JWT-only)
+	if !hasAllowedAuthType(identity.Type, p.AuthTypes) {
+		return stageAuthType, "auth type " + string(identity.Type) + " not accepted"
+	}
+
+	// Role intersection check
+	if !hasAllowedRole(identity.Roles, p.Roles) {
+		return stageRoles, "caller has none of the roles " + strings.Join(p.Roles, ", ")
+	}
+
+	// Attribute conditions, only once the roles matched
+	if unmet := p.Conditions.unmet(attrs, identity, params); unmet != "" {
+		return stageConditions, "condition not met: " + unmet
+	}
+	return stageApplies, ""
 }
 
 type PolicySet struct {
 	Policies []Policy
+	Public   []PublicRoute // see public.go
+	Roles    RoleHierarchy // see roles.go; nil = no inheritance
+}
+
+// PolicySource supplies the policy set to evaluate.
+// It is consulted on EVERY request so hot reloads take effect immediately.
+// Implementations must return an immutable snapshot.
+type PolicySource interface {
+	Current() PolicySet
+}
+
+// Current lets a fixed PolicySet act as its own (static) source.
+func (s PolicySet) Current() PolicySet {
+	return s
 }
 
 // RBACMiddleware enforces role-based authorization.
 // It assumes authentication has already happened and
 // identity is present in request context.
-func RBACMiddleware(policies PolicySet) func(http.Handler) http.Handler {
+func RBACMiddleware(source PolicySource) func(http.Handler) http.Handler {
+	return ShadowRBACMiddleware(source, nil)
+}
+
+// ShadowRBACMiddleware is RBACMiddleware that also evaluates every
+// request against a candidate policy set (see shadow.go). A nil shadow
+// evaluates the enforced set only.
+func ShadowRBACMiddleware(source PolicySource, shadow *Shadow) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := auth.FromContext(r.Context())
 
-			// Skip RBAC for health checks
-			if r.URL.Path == "/health" {
+			// Public routes carry no identity (see public.go)
+			if auth.IsPublic(r.Context()) {
+				shadow.compare(r, RequestAttributes(r, time.Now()), identity, publicDecision)
 				next.ServeHTTP(w, r)
 				return
 			}
 
 			// Identity MUST exist at this point
-			identity, ok := auth.FromContext(r.Context())
 			if !ok {
 				// Fail closed: unauthenticated access is forbidden
+				audit.SetReason(r.Context(), "rbac: no identity")
 				http.Error(w, "access denied", http.StatusForbidden)
 				return
 			}
 
-			// Evaluate policies
-			for _, p := range policies.Policies {
-
-				// Method must match exactly
-				if r.Method != p.Method {
-					continue
-				}
-
-				// Path must match prefix
-				if !strings.HasPrefix(r.URL.Path, p.Path) {
-					continue
-				}
-
-				// Role intersection check
-				if hasAllowedRole(identity.Roles, p.Roles) {
-					// Explicit allow
-					next.ServeHTTP(w, r)
-					return
-				}
+			// One snapshot per request: a concurrent reload cannot
+			// change the rule set halfway through evaluation
+			policies := source.Current()
+
+			// Explicit allow only; a deny or no matching rule => deny
+			attrs := RequestAttributes(r, time.Now())
+			d := policies.Evaluate(attrs, identity)
+			shadow.compare(r, attrs, identity, d)
+			if d.Allowed {
+				next.ServeHTTP(w, r)
+				return
 			}
 
-			// No policy matched => deny
+			// The explanation is for the audit log, never the caller
+			audit.SetReason(r.Context(), "rbac: "+d.Reason)
 			http.Error(w, "access denied", http.StatusForbidden)
 		})
 	}
@@ -85,3 +181,15 @@ func hasAllowedRole(userRoles, allowedRoles []string) bool {
 	}
 	return false
 }
+
+func hasAllowedAuthType(t auth.AuthType, allowed []auth.AuthType) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if t == a {
+			return true
+		}
+	}
+	return false
+}
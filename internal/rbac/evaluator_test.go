@@ -115,3 +115,37 @@ func TestRBACPathPrefixMatch(t *testing.T) {
 		t.Fatalf("expected 200 (path prefix match), got %d", rr.Code)
 	}
 }
+
+func TestRBACRestrictsAuthType(t *testing.T) {
+	policies := PolicySet{
+		Policies: []Policy{
+			{
+				Method:    "POST",
+				Path:      "/api/admin",
+				Roles:     []string{"admin"},
+				AuthTypes: []auth.AuthType{auth.AuthJWT},
+			},
+		},
+	}
+
+	handler := RBACMiddleware(policies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for authType, want := range map[auth.AuthType]int{
+		auth.AuthJWT:    http.StatusOK,
+		auth.AuthAPIKey: http.StatusForbidden,
+	} {
+		id := &auth.Identity{Type: authType, Roles: []string{"admin"}}
+
+		req := httptest.NewRequest("POST", "/api/admin", nil)
+		req = req.WithContext(auth.WithIdentity(context.Background(), id))
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != want {
+			t.Fatalf("%s: expected %d, got %d", authType, want, rr.Code)
+		}
+	}
+}
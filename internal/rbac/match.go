@@ -0,0 +1,323 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+POLICY PATH MATCHING
+
+Every rule declares how its path is matched; patterns are compiled once
+when the policy file is loaded, never per request.
+
+  exact     /api/admin           only /api/admin
+  prefix    /api/admin           /api/admin and /api/admin/..., never
+                                 /api/adminevil (segment aware; default)
+  glob      /api/*  /files/**    * = exactly one segment,
+                                 ** = any number of segments (at most one **)
+  template  /api/users/{id}      {name} = exactly one segment, captured
+
+Wildcards and parameters always stand for whole segments: "item-*" or
+"v{n}" are rejected at load time rather than guessed at.
+
+Path confusion:
+ A path that is not in canonical form (empty "//" segments, "." or ".."
+ segments, backslashes) matches NOTHING. A rule for /api/public must
+ never allow /api/public/../admin, whatever the upstream makes of it.
+*/
+
+// MatchType selects how a policy path is compared to the request path.
+type MatchType string
+
+const (
+	MatchExact    MatchType = "exact"
+	MatchPrefix   MatchType = "prefix"
+	MatchGlob     MatchType = "glob"
+	MatchTemplate MatchType = "template"
+)
+
+// Params holds the values captured by a template match.
+type Params map[string]string
+
+// PathMatcher is a compiled policy path.
+type PathMatcher struct {
+	Type    MatchType
+	Pattern string
+
+	segments []string // glob and template patterns, split on "/"
+}
+
+// CompilePath validates pattern for the match type. An empty type
+// means prefix.
+func CompilePath(t MatchType, pattern string) (*PathMatcher, error) {
+	if t == "" {
+		t = MatchPrefix
+	}
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, errors.New("path must start with '/'")
+	}
+	if !isCanonical(pattern) {
+		return nil, errors.New("path must not contain empty, '.' or '..' segments or backslashes")
+	}
+
+	m := &PathMatcher{Type: t, Pattern: pattern}
+
+	switch t {
+	case MatchExact:
+		if strings.ContainsAny(pattern, "*{}") {
+			return nil, errors.New("exact path must not contain wildcards or parameters")
+		}
+	case MatchPrefix:
+		if strings.ContainsAny(pattern, "*{}") {
+			return nil, errors.New("prefix path must not contain wildcards or parameters (use glob or template)")
+		}
+		m.Pattern = strings.TrimSuffix(pattern, "/")
+	case MatchGlob:
+		m.segments = splitPath(pattern)
+		if err := checkGlob(m.segments); err != nil {
+			return nil, err
+		}
+	case MatchTemplate:
+		m.segments = splitPath(pattern)
+		if err := checkTemplate(m.segments); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown match type %q (want exact, prefix, glob or template)", t)
+	}
+
+	return m, nil
+}
+
+// Match reports whether path matches. For templates it also returns
+// the captured parameters.
+func (m *PathMatcher) Match(path string) (Params, bool) {
+	if !strings.HasPrefix(path, "/") || !isCanonical(path) {
+		return nil, false
+	}
+
+	switch m.Type {
+	case MatchExact:
+		return nil, path == m.Pattern
+	case MatchPrefix:
+		return nil, hasPathPrefix(path, m.Pattern)
+	case MatchGlob:
+		return nil, matchGlob(m.segments, splitPath(path))
+	case MatchTemplate:
+		return matchTemplate(m.segments, splitPath(path))
+	}
+	return nil, false
+}
+
+// Covers reports whether every path o matches is also matched by m.
+// It is conservative: false can also mean "not provable", which is
+// the safe answer for the policy validator.
+func (m *PathMatcher) Covers(o *PathMatcher) bool {
+	switch m.Type {
+	case MatchPrefix:
+		return hasPathPrefix(o.stem(), m.Pattern)
+	case MatchExact:
+		return o.Type == MatchExact && o.Pattern == m.Pattern
+	}
+
+	// glob or template
+	if o.Type == MatchExact {
+		_, ok := m.Match(o.Pattern)
+		return ok
+	}
+	return o.Type == m.Type && sameShape(m.segments, o.segments)
+}
+
+// stem is the literal path every match lies at or below.
+func (m *PathMatcher) stem() string {
+	if m.Type == MatchExact || m.Type == MatchPrefix {
+		return m.Pattern
+	}
+
+	var literal []string
+	for _, seg := range m.segments {
+		if _, ok := paramName(seg); ok || seg == "*" || seg == "**" || seg == "" {
+			break
+		}
+		literal = append(literal, seg)
+	}
+	if len(literal) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(literal, "/")
+}
+
+// literalSegments counts the segments that must match literally.
+func (m *PathMatcher) literalSegments() int {
+	segments := m.segments
+	if segments == nil {
+		segments = splitPath(m.Pattern + "/")
+	}
+
+	n := 0
+	for _, seg := range segments {
+		if _, ok := paramName(seg); ok || seg == "*" || seg == "**" || seg == "" {
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+// sameShape reports whether two glob or template patterns match the
+// same paths (parameter names aside).
+func sameShape(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		_, pa := paramName(a[i])
+		_, pb := paramName(b[i])
+		if pa != pb || !pa && a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPathPrefix reports whether path is prefix itself or lies below it.
+// prefix has no trailing slash ("" stands for "/").
+func hasPathPrefix(path, prefix string) bool {
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func matchGlob(pattern, path []string) bool {
+	for i, seg := range pattern {
+		if seg == "**" {
+			// At most one **: the rest of the pattern anchors to the end
+			rest := pattern[i+1:]
+			if len(path) < len(rest) {
+				return false
+			}
+			return matchGlob(rest, path[len(path)-len(rest):])
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if seg == "*" {
+			if path[0] == "" {
+				return false
+			}
+		} else if seg != path[0] {
+			return false
+		}
+		path = path[1:]
+	}
+	return len(path) == 0
+}
+
+func matchTemplate(pattern, path []string) (Params, bool) {
+	if len(pattern) != len(path) {
+		return nil, false
+	}
+
+	var params Params
+	for i, seg := range pattern {
+		if name, ok := paramName(seg); ok {
+			if path[i] == "" {
+				return nil, false
+			}
+			if params == nil {
+				params = make(Params)
+			}
+			params[name] = path[i]
+			continue
+		}
+		if seg != path[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func checkGlob(segments []string) error {
+	doubles := 0
+	for _, seg := range segments {
+		switch {
+		case seg == "**":
+			doubles++
+		case seg == "*":
+		case strings.Contains(seg, "*"):
+			return errors.New("glob wildcards must be whole segments ('*' or '**')")
+		case strings.ContainsAny(seg, "{}"):
+			return errors.New("glob path must not contain parameters (use template)")
+		}
+	}
+	if doubles > 1 {
+		return errors.New("glob path may contain '**' at most once")
+	}
+	return nil
+}
+
+func checkTemplate(segments []string) error {
+	seen := make(map[string]bool)
+	for _, seg := range segments {
+		if strings.Contains(seg, "*") {
+			return errors.New("template path must not contain wildcards (use glob)")
+		}
+		name, ok := paramName(seg)
+		if !ok {
+			if strings.ContainsAny(seg, "{}") {
+				return fmt.Errorf("parameter %q must be a whole segment like {name}", seg)
+			}
+			continue
+		}
+		if !isIdentifier(name) {
+			return fmt.Errorf("parameter name %q must be a letter or '_' followed by letters, digits or '_'", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("parameter %q is duplicated", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+func paramName(seg string) (string, bool) {
+	if len(seg) > 2 && seg[0] == '{' && seg[len(seg)-1] == '}' {
+		return seg[1 : len(seg)-1], true
+	}
+	return "", false
+}
+
+func isIdentifier(s string) bool {
+	for i, c := range s {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && c >= '0' && c <= '9':
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+// splitPath splits "/a/b" into ["a", "b"]; "/" is [""].
+func splitPath(path string) []string {
+	return strings.Split(path[1:], "/")
+}
+
+// isCanonical rejects paths whose meaning depends on who normalizes
+// them. A single trailing slash is allowed.
+func isCanonical(path string) bool {
+	if strings.Contains(path, "\\") {
+		return false
+	}
+	segments := splitPath(path)
+	for i, seg := range segments {
+		if seg == "." || seg == ".." {
+			return false
+		}
+		if seg == "" && i != len(segments)-1 {
+			return false
+		}
+	}
+	return true
+}
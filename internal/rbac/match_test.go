@@ -0,0 +1,169 @@
+package rbac
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPathMatching(t *testing.T) {
+	tests := []struct {
+		typ     MatchType
+		pattern string
+		path    string
+		want    bool
+	}{
+		// exact
+		{MatchExact, "/api/admin", "/api/admin", true},
+		{MatchExact, "/api/admin", "/api/admin/", false},
+		{MatchExact, "/api/admin", "/api/admin/x", false},
+		{MatchExact, "/api/admin", "/API/admin", false},
+
+		// prefix (segment aware)
+		{MatchPrefix, "/api/admin", "/api/admin", true},
+		{MatchPrefix, "/api/admin", "/api/admin/", true},
+		{MatchPrefix, "/api/admin", "/api/admin/users/1", true},
+		{MatchPrefix, "/api/admin", "/api/adminevil", false},
+		{MatchPrefix, "/api/admin", "/api/admi", false},
+		{MatchPrefix, "/api/admin/", "/api/admin", true},
+		{MatchPrefix, "/", "/anything/at/all", true},
+
+		// glob
+		{MatchGlob, "/api/*/items", "/api/shop/items", true},
+		{MatchGlob, "/api/*/items", "/api/items", false},
+		{MatchGlob, "/api/*/items", "/api/a/b/items", false},
+		{MatchGlob, "/api/*/items/**", "/api/shop/items", true},
+		{MatchGlob, "/api/*/items/**", "/api/shop/items/1/reviews", true},
+		{MatchGlob, "/api/*/items/**", "/api/shop/itemsx/1", false},
+		{MatchGlob, "/files/**/meta", "/files/a/b/c/meta", true},
+		{MatchGlob, "/files/**/meta", "/files/meta", true},
+		{MatchGlob, "/files/**/meta", "/files/a/metadata", false},
+		{MatchGlob, "/api/*", "/api/", false}, // * never matches an empty segment
+
+		// template
+		{MatchTemplate, "/api/users/{id}", "/api/users/42", true},
+		{MatchTemplate, "/api/users/{id}", "/api/users", false},
+		{MatchTemplate, "/api/users/{id}", "/api/users/", false},
+		{MatchTemplate, "/api/users/{id}", "/api/users/42/keys", false},
+		{MatchTemplate, "/api/users/{id}/keys/{key}", "/api/users/42/keys/k1", true},
+	}
+
+	for _, tt := range tests {
+		m, err := CompilePath(tt.typ, tt.pattern)
+		if err != nil {
+			t.Fatalf("%s %s: unexpected compile error %v", tt.typ, tt.pattern, err)
+		}
+		if _, got := m.Match(tt.path); got != tt.want {
+			t.Errorf("%s %s vs %s: got %v, want %v", tt.typ, tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPathConfusionNeverMatches(t *testing.T) {
+	patterns := []struct {
+		typ     MatchType
+		pattern string
+	}{
+		{MatchPrefix, "/api/public"},
+		{MatchPrefix, "/"},
+		{MatchGlob, "/api/public/**"},
+		{MatchTemplate, "/api/public/{a}/{b}"},
+	}
+	paths := []string{
+		"/api/public/../admin",
+		"/api/public/./x",
+		"/api/public/x/..",
+		"/api/public//admin",
+		"//api/public/x",
+		"/api/public/..\\admin",
+		"/api/public\\..\\admin",
+		"api/public/x",
+		"",
+	}
+
+	for _, p := range patterns {
+		m, err := CompilePath(p.typ, p.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range paths {
+			if _, ok := m.Match(path); ok {
+				t.Errorf("%s %s must not match %q", p.typ, p.pattern, path)
+			}
+		}
+	}
+}
+
+func TestTemplateCapturesParams(t *testing.T) {
+	m, err := CompilePath(MatchTemplate, "/api/users/{user_id}/keys/{key}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params, ok := m.Match("/api/users/alice/keys/k1")
+	if !ok || params["user_id"] != "alice" || params["key"] != "k1" {
+		t.Fatalf("unexpected params %v (match %v)", params, ok)
+	}
+}
+
+func TestInvalidPathPatternsRejected(t *testing.T) {
+	tests := []struct {
+		typ     MatchType
+		pattern string
+	}{
+		{"regex", "/api"},
+		{MatchExact, "api"},
+		{MatchExact, "/api/*"},
+		{MatchPrefix, "/api/{id}"},
+		{MatchPrefix, "/api/../admin"},
+		{MatchPrefix, "/api//admin"},
+		{MatchGlob, "/api/item-*"},
+		{MatchGlob, "/api/**/x/**"},
+		{MatchGlob, "/api/{id}"},
+		{MatchTemplate, "/api/v{n}"},
+		{MatchTemplate, "/api/{1st}"},
+		{MatchTemplate, "/api/{id}/{id}"},
+		{MatchTemplate, "/api/*/{id}"},
+	}
+
+	for _, tt := range tests {
+		if _, err := CompilePath(tt.typ, tt.pattern); err == nil {
+			t.Errorf("%s %s: expected compile error", tt.typ, tt.pattern)
+		}
+	}
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		a, b   string // "type pattern"
+		covers bool
+	}{
+		{"prefix /api", "exact /api/billing", true},
+		{"prefix /api", "prefix /api/billing", true},
+		{"prefix /api", "glob /api/*/items", true},
+		{"prefix /api", "template /api/users/{id}", true},
+		{"prefix /", "glob /*", true},
+		{"prefix /api/billing", "prefix /api", false},
+		{"prefix /api/billing", "glob /api/**", false},
+		{"prefix /api", "prefix /apix", false},
+		{"exact /api", "exact /api", true},
+		{"exact /api", "prefix /api", false},
+		{"glob /api/*", "exact /api/users", true},
+		{"glob /api/*", "glob /api/*", true},
+		{"glob /api/*", "prefix /api", false},
+		{"glob /api/**", "glob /api/*", false}, // not provable, so false
+		{"template /api/{a}", "template /api/{b}", true},
+		{"template /api/{a}", "exact /api/users", true},
+		{"template /api/{a}", "exact /api/users/1", false},
+	}
+	for _, tt := range tests {
+		a, b := compileSpec(t, tt.a), compileSpec(t, tt.b)
+		if got := a.Covers(b); got != tt.covers {
+			t.Errorf("%s covers %s: got %v, want %v", tt.a, tt.b, got, tt.covers)
+		}
+	}
+}
+
+func compileSpec(t *testing.T, spec string) *PathMatcher {
+	typ, pattern, _ := strings.Cut(spec, " ")
+	return mustCompile(t, MatchType(typ), pattern)
+}
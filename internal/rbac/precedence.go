@@ -0,0 +1,91 @@
+package rbac
+
+import "Zero-TrustAPIGateWayServer/internal/auth"
+
+/*
+RULE PRECEDENCE
+
+A rule APPLIES to a request when its method, path, auth types, roles
+and conditions all match. The decision is deterministic and independent of rule order:
+
+ 1. Deny overrides: if any applicable rule denies, the request is denied.
+ 2. Most specific match: the deciding rule is the most specific
+    applicable rule with the winning effect.
+ 3. No applicable rule: denied (default deny).
+
+So "admins may do everything under /api except DELETE /api/billing" is
+
+  - method: "*"     path: /api           roles: [admin]
+  - method: DELETE  path: /api/billing   roles: [admin]   effect: deny
+
+Specificity, compared in order (first difference wins):
+
+  - more literal path segments     /api/billing  over  /api
+  - match type                     exact > template > glob > prefix
+  - a named method                 DELETE  over  *
+  - restricted auth types          [jwt]  over  any
+  - conditions                     a rule with conditions over one without
+  - earlier in the file
+*/
+
+// decide returns the index of the deciding policy, or -1 when no
+// policy applies.
+func decide(policies []Policy, attrs Attributes, identity *auth.Identity) int {
+	allow, deny := -1, -1
+	for i, p := range policies {
+		if !p.applies(attrs, identity) {
+			continue
+		}
+		best := &allow
+		if p.Effect == Deny {
+			best = &deny
+		}
+		if *best < 0 || moreSpecific(p, policies[*best]) {
+			*best = i
+		}
+	}
+
+	if deny >= 0 {
+		return deny
+	}
+	return allow
+}
+
+// moreSpecific reports whether a is strictly more specific than b.
+func moreSpecific(a, b Policy) bool {
+	ka, kb := specificity(a), specificity(b)
+	for i := range ka {
+		if ka[i] != kb[i] {
+			return ka[i] > kb[i]
+		}
+	}
+	return false
+}
+
+func specificity(p Policy) [5]int {
+	m := p.matcher()
+
+	var method, authTypes, conditions int
+	if p.Method != MethodAny {
+		method = 1
+	}
+	if len(p.AuthTypes) > 0 {
+		authTypes = 1
+	}
+	if p.Conditions != nil {
+		conditions = 1
+	}
+	return [5]int{m.literalSegments(), matchRank(m.Type), method, authTypes, conditions}
+}
+
+func matchRank(t MatchType) int {
+	switch t {
+	case MatchExact:
+		return 3
+	case MatchTemplate:
+		return 2
+	case MatchGlob:
+		return 1
+	}
+	return 0
+}
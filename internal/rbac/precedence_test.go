@@ -0,0 +1,104 @@
+package rbac
+
+import (
+	"testing"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+func mustCompile(t *testing.T, typ MatchType, pattern string) *PathMatcher {
+	t.Helper()
+	m, err := CompilePath(typ, pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func attrs(method, path string) Attributes {
+	return Attributes{Method: method, Path: path}
+}
+
+func TestDenyOverridesAllow(t *testing.T) {
+	policies := []Policy{
+		{Method: MethodAny, Path: "/api", Roles: []string{"admin"}},
+		{Method: "DELETE", Path: "/api/billing", Roles: []string{"admin"}, Effect: Deny},
+	}
+	admin := &auth.Identity{Roles: []string{"admin"}}
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/api/billing", 0},
+		{"DELETE", "/api/users", 0},
+		{"DELETE", "/api/billing", 1},
+		{"DELETE", "/api/billing/42", 1},
+		{"GET", "/other", -1},
+	}
+	for _, tt := range tests {
+		if got := decide(policies, attrs(tt.method, tt.path), admin); got != tt.want {
+			t.Errorf("%s %s: decided by %d, want %d", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	// Rule order never changes the outcome
+	reversed := []Policy{policies[1], policies[0]}
+	if got := decide(reversed, attrs("DELETE", "/api/billing"), admin); got != 0 {
+		t.Fatalf("reversed: decided by %d, want the deny rule", got)
+	}
+}
+
+func TestDenyOnlyAppliesToItsRoles(t *testing.T) {
+	policies := []Policy{
+		{Method: "GET", Path: "/api", Roles: []string{"user", "contractor"}},
+		{Method: "GET", Path: "/api/payroll", Roles: []string{"contractor"}, Effect: Deny},
+	}
+
+	if got := decide(policies, attrs("GET", "/api/payroll"), &auth.Identity{Roles: []string{"user"}}); got != 0 {
+		t.Fatalf("user: decided by %d, want 0", got)
+	}
+	if got := decide(policies, attrs("GET", "/api/payroll"), &auth.Identity{Roles: []string{"user", "contractor"}}); got != 1 {
+		t.Fatalf("contractor: decided by %d, want 1", got)
+	}
+}
+
+func TestMostSpecificRuleDecides(t *testing.T) {
+	id := &auth.Identity{Type: auth.AuthJWT, Roles: []string{"user"}}
+
+	tests := []struct {
+		name     string
+		policies []Policy
+		want     int
+	}{
+		{"more literal segments", []Policy{
+			{Method: "GET", Path: "/api", Roles: []string{"user"}},
+			{Method: "GET", Path: "/api/users", Roles: []string{"user"}},
+		}, 1},
+		{"exact over template", []Policy{
+			{Method: "GET", Path: "/api/users/{id}", Matcher: mustCompile(t, MatchTemplate, "/api/users/{id}"), Roles: []string{"user"}},
+			{Method: "GET", Path: "/api/users/me", Matcher: mustCompile(t, MatchExact, "/api/users/me"), Roles: []string{"user"}},
+		}, 1},
+		{"template over glob", []Policy{
+			{Method: "GET", Path: "/api/users/*", Matcher: mustCompile(t, MatchGlob, "/api/users/*"), Roles: []string{"user"}},
+			{Method: "GET", Path: "/api/users/{id}", Matcher: mustCompile(t, MatchTemplate, "/api/users/{id}"), Roles: []string{"user"}},
+		}, 1},
+		{"named method over any", []Policy{
+			{Method: MethodAny, Path: "/api/users", Roles: []string{"user"}},
+			{Method: "GET", Path: "/api/users", Roles: []string{"user"}},
+		}, 1},
+		{"auth types over any", []Policy{
+			{Method: "GET", Path: "/api/users", Roles: []string{"user"}},
+			{Method: "GET", Path: "/api/users", Roles: []string{"user"}, AuthTypes: []auth.AuthType{auth.AuthJWT}},
+		}, 1},
+		{"earlier on a tie", []Policy{
+			{Method: "GET", Path: "/api/users", Roles: []string{"user"}},
+			{Method: "GET", Path: "/api/users", Roles: []string{"user", "admin"}},
+		}, 0},
+	}
+	for _, tt := range tests {
+		if got := decide(tt.policies, attrs("GET", "/api/users/me"), id); got != tt.want {
+			t.Errorf("%s: decided by %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
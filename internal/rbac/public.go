@@ -0,0 +1,61 @@
+package rbac
+
+import (
+	"net/http"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+/*
+PUBLIC ROUTES
+
+Public routes come from the policy file like every other rule; nothing
+proxied is public by being hard-coded. (The gateway's own /health is
+answered ahead of this chain; see package health.) PublicMiddleware decides ONCE per request,
+from the same snapshot source as RBAC, and marks the request context.
+Authentication and RBAC honour the mark instead of comparing paths
+themselves, so they can never disagree about what is public.
+
+A public route names its methods explicitly; there is no "*".
+*/
+
+// PublicRoute lets anonymous requests through for the listed methods.
+type PublicRoute struct {
+	Methods []string
+	Matcher *PathMatcher
+}
+
+// IsPublic reports whether a request needs no authentication.
+func (s PolicySet) IsPublic(method, path string) bool {
+	for _, p := range s.Public {
+		if !containsMethod(p.Methods, method) {
+			continue
+		}
+		if _, ok := p.Matcher.Match(path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PublicMiddleware marks requests to public routes. It must run before
+// authentication.
+func PublicMiddleware(source PolicySource) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if source.Current().IsPublic(r.Method, r.URL.Path) {
+				r = r.WithContext(auth.WithPublic(r.Context()))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
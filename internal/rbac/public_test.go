@@ -0,0 +1,52 @@
+package rbac
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublicRoutesSkipAuthAndRBAC(t *testing.T) {
+	policies := PolicySet{
+		Public: []PublicRoute{
+			{Methods: []string{"GET"}, Matcher: mustCompile(t, MatchExact, "/health")},
+		},
+	}
+
+	// Public marking, then RBAC with no identity at all
+	handler := PublicMiddleware(policies)(RBACMiddleware(policies)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	))
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/health", http.StatusOK},
+		{"POST", "/health", http.StatusForbidden},
+		{"GET", "/health/details", http.StatusForbidden},
+		{"GET", "/api", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+		if rr.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, rr.Code)
+		}
+	}
+}
+
+func TestRBACNoLongerSkipsHealth(t *testing.T) {
+	handler := RBACMiddleware(PolicySet{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("should not reach handler")
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,119 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+ROLE HIERARCHY
+
+A role may inherit other roles: "admin inherits editor, editor inherits
+user" lets a rule name only the minimum role it needs, and an admin
+still satisfies roles: [user].
+
+The hierarchy is resolved ONCE when the policy file is loaded: each
+role maps to the full, transitive set of roles it inherits. A cycle
+is rejected at load time like any other invalid policy. At request
+time the caller's roles are expanded once per evaluation, so the rule
+checks never walk the graph.
+
+Inheritance only adds roles to the caller. Deny rules see the
+expanded roles too: a deny for [user] also applies to admins.
+*/
+
+// RoleHierarchy maps a role to every role it inherits, directly or
+// indirectly, sorted. Roles that inherit nothing are absent.
+type RoleHierarchy map[string][]string
+
+// ResolveRoles computes the transitive closure of inherits (role ->
+// roles it inherits directly) and rejects cycles.
+func ResolveRoles(inherits map[string][]string) (RoleHierarchy, error) {
+	names := make([]string, 0, len(inherits))
+	for name := range inherits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		resolved = 2
+	)
+	state := make(map[string]int)
+	hierarchy := make(RoleHierarchy)
+	var path []string
+
+	var visit func(role string) error
+	visit = func(role string) error {
+		switch state[role] {
+		case resolved:
+			return nil
+		case visiting:
+			start := 0
+			for path[start] != role {
+				start++
+			}
+			return errors.New("inheritance cycle " + strings.Join(append(path[start:], role), " -> "))
+		}
+
+		state[role] = visiting
+		path = append(path, role)
+
+		var all []string
+		for _, parent := range inherits[role] {
+			if strings.TrimSpace(parent) == "" {
+				return fmt.Errorf("role %q: inherited role names must not be empty", role)
+			}
+			if err := visit(parent); err != nil {
+				return err
+			}
+			all = appendMissing(all, parent)
+			all = appendMissing(all, hierarchy[parent]...)
+		}
+
+		path = path[:len(path)-1]
+		state[role] = resolved
+		if len(all) > 0 {
+			sort.Strings(all)
+			hierarchy[role] = all
+		}
+		return nil
+	}
+
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			return nil, errors.New("role names must not be empty")
+		}
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return hierarchy, nil
+}
+
+// Expand returns roles plus every role they inherit.
+func (h RoleHierarchy) Expand(roles []string) []string {
+	if len(h) == 0 {
+		return roles
+	}
+	expanded := append([]string(nil), roles...)
+	for _, r := range roles {
+		expanded = appendMissing(expanded, h[r]...)
+	}
+	return expanded
+}
+
+func appendMissing(list []string, roles ...string) []string {
+next:
+	for _, r := range roles {
+		for _, have := range list {
+			if have == r {
+				continue next
+			}
+		}
+		list = append(list, r)
+	}
+	return list
+}
@@ -0,0 +1,58 @@
+package rbac
+
+import (
+	"reflect"
+	"testing"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+func TestResolveRolesIsTransitive(t *testing.T) {
+	h, err := ResolveRoles(map[string][]string{
+		"admin":   {"editor", "auditor"},
+		"editor":  {"user"},
+		"auditor": {"user"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := RoleHierarchy{
+		"admin":   {"auditor", "editor", "user"},
+		"editor":  {"user"},
+		"auditor": {"user"},
+	}
+	if !reflect.DeepEqual(h, want) {
+		t.Fatalf("got %v", h)
+	}
+
+	if got := h.Expand([]string{"editor", "billing"}); !reflect.DeepEqual(got, []string{"editor", "billing", "user"}) {
+		t.Fatalf("Expand = %v", got)
+	}
+}
+
+func TestResolveRolesRejectsCycles(t *testing.T) {
+	_, err := ResolveRoles(map[string][]string{
+		"admin":  {"editor"},
+		"editor": {"user"},
+		"user":   {"editor"},
+	})
+	if err == nil || err.Error() != "inheritance cycle editor -> user -> editor" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestEvaluateUsesInheritedRoles(t *testing.T) {
+	set := PolicySet{
+		Policies: []Policy{{Method: "GET", Path: "/api/public", Roles: []string{"user"}}},
+		Roles:    RoleHierarchy{"admin": {"user"}},
+	}
+	admin := &auth.Identity{Roles: []string{"admin"}}
+
+	if d := set.Evaluate(attrs("GET", "/api/public"), admin); !d.Allowed {
+		t.Fatalf("admin should inherit user: %+v", d)
+	}
+	if !reflect.DeepEqual(admin.Roles, []string{"admin"}) {
+		t.Fatalf("caller identity modified: %v", admin.Roles)
+	}
+}
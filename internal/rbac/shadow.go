@@ -0,0 +1,68 @@
+package rbac
+
+import (
+	"net/http"
+
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+/*
+SHADOW POLICIES
+
+A candidate policy set can run in shadow mode next to the enforced one.
+Every request RBAC sees is evaluated against both, with the same
+attributes and identity; only the enforced decision is ever acted on.
+When the two disagree on allow/deny, OnDivergence is called so the
+gateway can audit and count it. A candidate that has been quiet under
+real traffic can be promoted with confidence.
+
+ - The candidate never changes a response, a status or an audit reason.
+ - Only the verdict is compared. A different deciding rule with the same
+   outcome is not a divergence.
+ - A candidate with no rules (not loaded, or invalid) is skipped rather
+   than reported as denying everything.
+ - Requests rejected before RBAC (validation, authentication) are not
+   compared: there is no identity to authorize.
+*/
+
+// Shadow evaluates a candidate policy set alongside the enforced one.
+type Shadow struct {
+	Candidate    PolicySource
+	OnDivergence func(r *http.Request, d Divergence)
+}
+
+// Divergence is a request the enforced and candidate sets disagree on.
+type Divergence struct {
+	Enforced  Decision
+	Candidate Decision
+}
+
+// String summarises both decisions for the audit log.
+func (d Divergence) String() string {
+	return "enforced " + verdict(d.Enforced) + " (" + d.Enforced.Reason + "); candidate " +
+		verdict(d.Candidate) + " (" + d.Candidate.Reason + ")"
+}
+
+// compare evaluates the candidate set and reports a divergence from
+// the enforced decision. It is a no-op on a nil Shadow.
+func (s *Shadow) compare(r *http.Request, attrs Attributes, identity *auth.Identity, enforced Decision) {
+	if s == nil || s.Candidate == nil || s.OnDivergence == nil {
+		return
+	}
+
+	candidate := s.Candidate.Current()
+	if len(candidate.Policies) == 0 && len(candidate.Public) == 0 {
+		return
+	}
+
+	if d := candidate.Evaluate(attrs, identity); d.Allowed != enforced.Allowed {
+		s.OnDivergence(r, Divergence{Enforced: enforced, Candidate: d})
+	}
+}
+
+func verdict(d Decision) string {
+	if d.Allowed {
+		return "allow"
+	}
+	return "deny"
+}
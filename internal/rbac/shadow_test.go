@@ -0,0 +1,96 @@
+package rbac
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Zero-TrustAPIGateWayServer/internal/audit"
+	"Zero-TrustAPIGateWayServer/internal/auth"
+)
+
+func TestShadowReportsDivergencesOnly(t *testing.T) {
+	enforced := explainSet(t)
+
+	// The candidate drops the public /health route, tightens
+	// /api/public to admins and opens PUT /api/public to users
+	candidate := PolicySet{
+		Policies: []Policy{
+			{Method: "GET", Path: "/api/public", Roles: []string{"admin"}},
+			{Method: "PUT", Path: "/api/public", Roles: []string{"user"}},
+			{Method: "POST", Path: "/api/admin", Roles: []string{"admin"}},
+		},
+	}
+
+	var got []Divergence
+	handler := PublicMiddleware(enforced)(ShadowRBACMiddleware(enforced, &Shadow{
+		Candidate:    candidate,
+		OnDivergence: func(r *http.Request, d Divergence) { got = append(got, d) },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	user := &auth.Identity{Type: auth.AuthAPIKey, Roles: []string{"user"}}
+	tests := []struct {
+		method, path string
+		identity     *auth.Identity
+		want         int  // enforced status, whatever the candidate says
+		diverges     bool // candidate verdict differs
+	}{
+		{"GET", "/api/public", user, http.StatusOK, true},
+		{"PUT", "/api/public", user, http.StatusForbidden, true},
+		{"POST", "/api/admin", user, http.StatusForbidden, false},
+		{"GET", "/health", nil, http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		got = nil
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		ctx := audit.WithReason(req.Context())
+		if tt.identity != nil {
+			ctx = auth.WithIdentity(ctx, tt.identity)
+		}
+		req = req.WithContext(ctx)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, rr.Code)
+		}
+		if (len(got) == 1) != tt.diverges {
+			t.Errorf("%s %s: divergences = %+v", tt.method, tt.path, got)
+			continue
+		}
+		if tt.diverges && got[0].Enforced.Allowed == got[0].Candidate.Allowed {
+			t.Errorf("%s %s: reported agreeing decisions %+v", tt.method, tt.path, got[0])
+		}
+		if reason, _ := audit.Reason(req.Context()); strings.Contains(reason, "PUT /api/public") {
+			t.Errorf("%s %s: candidate leaked into audit reason %q", tt.method, tt.path, reason)
+		}
+	}
+}
+
+func TestShadowSkipsEmptyCandidate(t *testing.T) {
+	enforced := explainSet(t)
+	handler := ShadowRBACMiddleware(enforced, &Shadow{
+		Candidate: PolicySet{},
+		OnDivergence: func(r *http.Request, d Divergence) {
+			t.Fatalf("unexpected divergence %+v", d)
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/api/public", nil)
+	req = req.WithContext(auth.WithIdentity(req.Context(), &auth.Identity{Roles: []string{"user"}}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestDivergenceString(t *testing.T) {
+	d := Divergence{
+		Enforced:  Decision{Allowed: true, Reason: "allowed by policy[0] (GET /api)"},
+		Candidate: Decision{Reason: "no rule applies"},
+	}
+	want := "enforced allow (allowed by policy[0] (GET /api)); candidate deny (no rule applies)"
+	if d.String() != want {
+		t.Fatalf("got %q", d.String())
+	}
+}